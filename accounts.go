@@ -0,0 +1,42 @@
+package allyapi
+
+import "encoding/json"
+
+// AccountSummary is the balance and holdings of a single account
+type AccountSummary struct {
+	Account         string          `json:",omitempty"`
+	AccountBalance  json.RawMessage `json:",omitempty"`
+	AccountHoldings json.RawMessage `json:",omitempty"`
+}
+
+// AccountSummaries holds one summary per account
+type AccountSummaries []AccountSummary
+
+// UnmarshalJSON accepts either an array of summaries or a single summary
+func (as *AccountSummaries) UnmarshalJSON(data []byte) error {
+	return unmarshalArray(data, (*[]AccountSummary)(as))
+}
+
+// Accounts returns a summary of all accounts, including balances and
+// holdings
+func (ac *Client) Accounts() (*APIResponse, error) {
+	accountsURL := ac.endpoint("/accounts")
+
+	return ac.get(accountsURL)
+}
+
+// AccountIDs returns the IDs of all accounts
+func (ac *Client) AccountIDs() ([]string, error) {
+	resp, err := ac.Accounts()
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	if resp.Response.Accounts != nil {
+		for _, a := range resp.Response.Accounts.AccountSummary {
+			ids = append(ids, a.Account)
+		}
+	}
+	return ids, nil
+}
@@ -1,62 +1,200 @@
+// Package allyapi is a client for the Ally Invest API.
+//
 // https://www.ally.com/api/invest/documentation/getting-started/
-package main
+package allyapi
 
 import (
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"encoding/xml"
 	"errors"
-	"flag"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/dghubble/oauth1"
-	"github.com/keybase/go-keychain"
 )
 
-var version = "undefined"
-var showVersionFlag, streamFlag *bool
-var symbols *string
-var client = newAllyClient()
-var wg sync.WaitGroup
+// MaxQuoteSymbols is the default number of symbols sent per quotes request
+const MaxQuoteSymbols = 100
 
-type allyClient struct {
+// Client makes authenticated requests to the Ally Invest API
+type Client struct {
 	*http.Client
-	APICallsRemaining int
-	mu                sync.Mutex
-}
-
-type quoteArray []map[string]string
-
-type apiResponse struct {
-	Status   string `json:",omitempty"`
-	Response *struct {
-		ID          string `json:"@id,omitempty"`
-		ElapsedTime int    `json:",string,omitempty"`
-		Error       string `json:",omitempty"`
-		Quotes      *struct {
-			QuoteType string     `json:",omitempty"`
-			Quote     quoteArray `json:",omitempty"`
-		} `json:",omitempty"`
-	} `json:",omitempty"`
-	Trade *struct {
-		Cvol      int                    `json:",string,omitempty"`
-		DateTime  string                 `json:",omitempty"`
-		Exch      map[string]interface{} `json:",omitempty"`
-		Last      float32                `json:",string,omitempty"`
-		Symbol    string                 `json:",omitempty"`
-		Timestamp int64                  `json:",string,omitempty"`
-		Vl        int                    `json:",string,omitempty"`
-		Vwap      float32                `json:",string,omitempty"`
-	} `json:",omitempty"`
-}
-
-func (qa *quoteArray) UnmarshalJSON(data []byte) error {
+	mu sync.Mutex
+	wg sync.WaitGroup
+
+	// If not nil, GetQuotes returns cached quotes when it can
+	Cache *QuoteCache
+
+	// Maximum number of symbols per quotes request; larger requests are
+	// split up and the results merged. Defaults to MaxQuoteSymbols.
+	ChunkSize int
+
+	// Number of chunks to fetch at once; defaults to 1
+	Concurrency int
+
+	// If true, order requests are written to DryRunOutput (or stdout) and
+	// not sent, and order methods return ErrDryRun
+	DryRun       bool
+	DryRunOutput io.Writer
+
+	// If set, every order request sent is appended to this file, with its
+	// response, as a line of JSON; see ReadAuditLog. Requests aren't sent if
+	// it can't be opened.
+	AuditLog string
+
+	// If set, placing an order identical to one placed successfully this
+	// recently, according to AuditLog, returns ErrDuplicateOrder instead
+	DuplicateWindow time.Duration
+
+	// If not nil, API calls, streamed quotes, and errors are counted here;
+	// see MetricsHandler
+	Metrics *Metrics
+
+	// If set, rate limit counters are saved here so that they carry over to
+	// the next process; see LoadRateLimitState
+	StateFile string
+
+	// If set, streams that go this long without a message are considered
+	// dead and closed with ErrStreamStale. The API sends no heartbeats, so
+	// this should allow for quiet symbols.
+	StreamTimeout time.Duration
+
+	// If not nil, requests fail fast with ErrCircuitOpen after repeated
+	// failures; see CircuitBreaker
+	Breaker *CircuitBreaker
+
+	// Rate limit counters from the last response; see RateLimit
+	rateLimitUsed      int
+	rateLimitRemaining int
+	rateLimitLimit     int
+	rateLimitExpires   time.Time
+
+	env Environment
+
+	// Response format requested from the API, "json" or "xml"
+	format string
+
+	creds       *Credentials
+	credsSource string
+	credsPath   string
+	profile     string
+
+	ctx       context.Context
+	debug     io.Writer
+	logger    Logger
+	proxy     *url.URL
+	transport http.RoundTripper
+	userAgent string
+}
+
+// QuoteArray holds quotes keyed by field name. The API returns a single
+// object rather than an array when there is only one quote.
+type QuoteArray []map[string]string
+
+// Quotes is the list of quotes in a response
+type Quotes struct {
+	QuoteType string     `json:",omitempty" xml:"quotetype,omitempty"`
+	Quote     QuoteArray `json:",omitempty" xml:"quote,omitempty"`
+}
+
+// APIResponse is a decoded API response or streaming message
+type APIResponse struct {
+	Status   string        `json:",omitempty"`
+	Response *ResponseBody `json:",omitempty"`
+	Quote    *StreamQuote  `json:",omitempty"`
+	Trade    *StreamTrade  `json:",omitempty"`
+}
+
+// StreamQuote is a change in the bid or ask of a streamed symbol
+type StreamQuote struct {
+	Ask       Decimal                `json:",omitempty"`
+	Asksz     int                    `json:",string,omitempty"`
+	Bid       Decimal                `json:",omitempty"`
+	Bidsz     int                    `json:",string,omitempty"`
+	DateTime  string                 `json:",omitempty"`
+	Exch      map[string]interface{} `json:",omitempty"`
+	Qcond     string                 `json:",omitempty"`
+	Symbol    string                 `json:",omitempty"`
+	Timestamp int64                  `json:",string,omitempty"`
+}
+
+// Time returns when the quote was made in market time, or the zero time if
+// the message doesn't say
+func (q *StreamQuote) Time() time.Time {
+	return streamTime(q.Timestamp, q.DateTime)
+}
+
+// StreamTrade is a trade of a streamed symbol
+type StreamTrade struct {
+	Cvol      int                    `json:",string,omitempty"`
+	DateTime  string                 `json:",omitempty"`
+	Exch      map[string]interface{} `json:",omitempty"`
+	Last      Decimal                `json:",omitempty"`
+	Symbol    string                 `json:",omitempty"`
+	Timestamp int64                  `json:",string,omitempty"`
+	Vl        int                    `json:",string,omitempty"`
+	Vwap      Decimal                `json:",omitempty"`
+}
+
+// Time returns when the trade was made in market time, or the zero time if
+// the message doesn't say
+func (t *StreamTrade) Time() time.Time {
+	return streamTime(t.Timestamp, t.DateTime)
+}
+
+func streamTime(timestamp int64, datetime string) time.Time {
+	if timestamp != 0 {
+		return time.Unix(timestamp, 0).In(MarketTime)
+	}
+	t, _ := parseMarketTime(datetime)
+	return t
+}
+
+// ResponseBody is the body of a JSON response, or the root element of an XML
+// response
+type ResponseBody struct {
+	XMLName     xml.Name `json:"-" xml:"response"`
+	ID          string   `json:"@id,omitempty" xml:"id,attr,omitempty"`
+	ElapsedTime int      `json:",string,omitempty" xml:"elapsedtime,omitempty"`
+	Error       string   `json:",omitempty" xml:"error,omitempty"`
+	Quotes      *Quotes  `json:",omitempty" xml:"quotes,omitempty"`
+	Accounts    *struct {
+		AccountSummary AccountSummaries `json:",omitempty"`
+	} `json:",omitempty" xml:"-"`
+	Watchlists *struct {
+		Watchlist Watchlists `json:",omitempty" xml:"watchlist,omitempty"`
+	} `json:",omitempty" xml:"watchlists,omitempty"`
+	AccountBalance  *Balance `json:",omitempty" xml:"accountbalance,omitempty"`
+	UserData        *Member  `json:",omitempty" xml:"userdata,omitempty"`
+	AccountHoldings *struct {
+		Holding         Holdings `json:",omitempty" xml:"holding,omitempty"`
+		TotalSecurities string   `json:",omitempty" xml:"totalsecurities,omitempty"`
+	} `json:",omitempty" xml:"accountholdings,omitempty"`
+	Transactions *struct {
+		Transaction Transactions `json:",omitempty" xml:"transaction,omitempty"`
+	} `json:",omitempty" xml:"transactions,omitempty"`
+	Timeseries *struct {
+		Series struct {
+			Data Bars `json:",omitempty" xml:"data,omitempty"`
+		} `json:",omitempty" xml:"series,omitempty"`
+	} `json:",omitempty" xml:"timeseries,omitempty"`
+	ExpirationDates *struct {
+		Date StringArray `json:",omitempty" xml:"date,omitempty"`
+	} `json:",omitempty" xml:"expirationdates,omitempty"`
+	OrderResponse
+}
+
+// UnmarshalJSON accepts either an array of quotes or a single quote object
+func (qa *QuoteArray) UnmarshalJSON(data []byte) error {
 	if len(data) < 1 {
 		return errors.New("No input")
 	}
@@ -72,29 +210,62 @@ func (qa *quoteArray) UnmarshalJSON(data []byte) error {
 		if err := json.Unmarshal(data, &mp); err != nil {
 			return err
 		}
-		*qa = quoteArray{mp}
+		*qa = QuoteArray{mp}
 	}
 	return nil
 }
 
-func timestampToDate(str string) time.Time {
-	timestampArr := make([]int64, 2)
-	var err error
-	for i, s := range strings.Split(str, ".") {
-		if s != "" {
-			timestampArr[i], err = strconv.ParseInt(s, 10, 64)
-			if err != nil {
-				log.Println("Error converting timestamp string to int64")
+// StringArray holds one or more strings
+type StringArray []string
+
+// UnmarshalJSON accepts either an array of strings or a single string
+func (sa *StringArray) UnmarshalJSON(data []byte) error {
+	return unmarshalArray(data, (*[]string)(sa))
+}
+
+// UnmarshalXML is called once for each <quote> element, whose children become
+// the keys and values of a new map
+func (qa *QuoteArray) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	q := make(map[string]string)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			var v string
+			if err := d.DecodeElement(&v, &t); err != nil {
+				return err
 			}
+			q[t.Name.Local] = v
+		case xml.EndElement:
+			*qa = append(*qa, q)
+			return nil
 		}
 	}
-	return time.Unix(timestampArr[0], timestampArr[1])
 }
 
-func (ac *allyClient) doAPICall(endpoint string, method string, data map[string][]string) (string, error) {
+// The API returns a single item instead of an array when there is only one,
+// so wrap a lone object or string in an array before decoding
+func unmarshalArray(data []byte, v interface{}) error {
+	if len(data) > 0 && (data[0] == '{' || data[0] == '"') {
+		data = append(append([]byte{'['}, data...), ']')
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (ac *Client) doAPICall(endpoint string, method string, data map[string][]string, handle func(*APIResponse) error) error {
+	req, err := ac.newRequest(endpoint, method, data)
+	if err != nil {
+		return err
+	}
+	return ac.doRequest(req, handle)
+}
 
+func (ac *Client) newRequest(endpoint string, method string, data map[string][]string) (*http.Request, error) {
 	if strings.HasPrefix(endpoint, "/") {
-		endpoint = "https://devapi.invest.ally.com/v1" + endpoint
+		endpoint = ac.env.BaseURL + endpoint
 	}
 
 	var dataString string
@@ -111,196 +282,422 @@ func (ac *allyClient) doAPICall(endpoint string, method string, data map[string]
 		dataString = ""
 	}
 
-	req, err := http.NewRequest(method, endpoint, strings.NewReader(dataString))
+	req, err := http.NewRequestWithContext(ac.ctx, method, endpoint, strings.NewReader(dataString))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
+	req.Header.Set("User-Agent", ac.userAgent)
 	if req.Method == "POST" {
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	}
+	return req, nil
+}
 
-	resp, err := ac.Do(req)
+// Send a request and pass each decoded response to handle; streaming
+// endpoints send many responses over a single connection
+func (ac *Client) doRequest(req *http.Request, handle func(*APIResponse) error) error {
+	err := ac.sendRequest(req, handle)
 	if err != nil {
-		return "", err
+		ac.Metrics.addError()
 	}
-	defer resp.Body.Close()
+	return err
+}
 
-	decoder := json.NewDecoder(resp.Body)
-	for decoder.More() {
+func (ac *Client) sendRequest(req *http.Request, handle func(*APIResponse) error) error {
+	resp, err := ac.open(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
 
-		var m apiResponse
-		err := decoder.Decode(&m)
-		if err != nil {
-			return "", err
+	if strings.HasSuffix(req.URL.Path, ".xml") {
+		var body ResponseBody
+		if err := xml.NewDecoder(resp.Body).Decode(&body); err != nil {
+			return err
 		}
+		if err := handle(&APIResponse{Response: &body}); err != nil {
+			return err
+		}
+	} else {
+		decoder := json.NewDecoder(resp.Body)
+		for decoder.More() {
 
-		b, err := json.MarshalIndent(m, "", "  ")
-		if err != nil {
-			return "", err
+			var m APIResponse
+			err := decoder.Decode(&m)
+			if err != nil {
+				return err
+			}
+
+			if err := handle(&m); err != nil {
+				return err
+			}
 		}
-		fmt.Println(string(b))
 	}
 
-	// Interesting response headers:
-	// X-Ratelimit-Used: Number of requests sent against the current limit
-	// X-Ratelimit-Expire: When the current limit will expire (Unix timestamp)
-	// X-Ratelimit-Limit: Total number of requests allowed in the call limit
-	// X-Ratelimit-Remaining: Number of requests allowed against the current lim it
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		ac.mu.Lock()
-		defer ac.mu.Unlock()
+	ac.updateRateLimit(resp)
+	return nil
+}
 
-		ac.APICallsRemaining, err = strconv.Atoi(resp.Header["X-Ratelimit-Remaining"][0])
-		if err != nil {
-			log.Println("Unable to determine API calls remaining")
-		}
+// Send a request and return the response if it succeeded; the caller must
+// close its body
+func (ac *Client) open(req *http.Request) (*http.Response, error) {
+	if err := ac.checkRateLimit(); err != nil {
+		return nil, err
+	}
 
-		if ac.APICallsRemaining < 10 {
-			fmt.Printf("Warning: only %v API calls remaining\n", ac.APICallsRemaining)
-			expiration := timestampToDate(resp.Header["X-Ratelimit-Expire"][0])
-			fmt.Printf("Current limit set to expire at %v\n", expiration)
-		}
-	}()
+	if err := ac.Breaker.allow(); err != nil {
+		return nil, err
+	}
 
-	return "", nil
+	ac.Metrics.addAPICall()
+	resp, err := ac.roundTrip(req)
+	if ac.Breaker.record(err) {
+		ac.logger.Warn("too many failed requests; pausing requests", "error", err, "cooldown", ac.Breaker.Cooldown)
+	}
+	return resp, err
 }
 
-func (ac *allyClient) get(url string) (string, error) {
-	return ac.doAPICall(url, "GET", nil)
+func (ac *Client) roundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := ac.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := decompress(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		apiErr := newHTTPError(resp, strings.HasSuffix(req.URL.Path, ".xml"))
+		ac.updateRateLimit(resp)
+		return nil, apiErr
+	}
+	return resp, nil
 }
 
-func (ac *allyClient) post(url string, data map[string][]string) (string, error) {
-	return ac.doAPICall(url, "POST", data)
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
 }
 
-func (ac *allyClient) streamQuotes(symbols []string) (string, error) {
-	quotesEndpoint := "https://devapi-stream.invest.ally.com/v1/market/quotes.json"
+func (b *gzipBody) Close() error {
+	b.Reader.Close()
+	return b.body.Close()
+}
 
-	data := make(map[string][]string, 1)
-	data["symbols"] = []string{strings.Join(symbols, ",")}
+// Decode a gzip-compressed response body. Transports only do this themselves
+// when they added the Accept-Encoding header, and ours is set explicitly so
+// that custom transports get compressed responses too.
+func decompress(resp *http.Response) error {
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return nil
+	}
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error decompressing response: %v", err)
+	}
+	resp.Body = &gzipBody{Reader: gz, body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
 
-	body, err := ac.post(quotesEndpoint, data)
+func (ac *Client) call(endpoint, method string, data map[string][]string) (*APIResponse, error) {
+	req, err := ac.newRequest(endpoint, method, data)
+	if err != nil {
+		return nil, err
+	}
+	return ac.callRequest(req)
+}
 
+// Make a request that returns a single response, checking it for errors
+func (ac *Client) callRequest(req *http.Request) (*APIResponse, error) {
+	// Streaming responses aren't compressed so that messages arrive as soon
+	// as they are sent
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	var resp *APIResponse
+	err := ac.doRequest(req, func(m *APIResponse) error {
+		resp = m
+		return nil
+	})
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if resp == nil || resp.Response == nil {
+		return nil, errors.New("empty response")
+	}
+	if e := resp.Response.Error; e != "" && e != "Success" {
+		ac.Metrics.addError()
+		return nil, &APIError{StatusCode: http.StatusOK, Message: e, RequestID: resp.Response.ID}
 	}
-	return body, nil
+	return resp, nil
 }
 
-func (ac *allyClient) getQuotes(symbols []string) (string, error) {
-	quotesEndpoint := "/market/ext/quotes.json"
+// Add the extension for the client's response format to an endpoint
+func (ac *Client) endpoint(path string) string {
+	return path + "." + ac.format
+}
 
-	data := make(map[string][]string, 1)
-	data["symbols"] = []string{strings.Join(symbols, ",")}
+func (ac *Client) get(url string) (*APIResponse, error) {
+	return ac.call(url, "GET", nil)
+}
 
-	body, err := ac.post(quotesEndpoint, data)
-	if err != nil {
-		return "", err
-	}
-	return body, nil
+func (ac *Client) post(url string, data map[string][]string) (*APIResponse, error) {
+	return ac.call(url, "POST", data)
 }
 
-func newAllyClient() *allyClient {
-	consumerKey, err := getCredsFromKeychain("TradeKing", "consumer_key")
+// Raw sends a signed request to an endpoint, such as
+// "/market/ext/quotes.json", and returns the undecoded body of the response.
+// data is sent as the query of GET and DELETE requests and as the form body of
+// others. Responses with an error status return an *APIError. In dry run
+// mode, requests other than GETs are written to DryRunOutput instead of being
+// sent, since they may place orders.
+func (ac *Client) Raw(method, endpoint string, data url.Values) ([]byte, error) {
+	method = strings.ToUpper(method)
+	if (method == "GET" || method == "DELETE") && len(data) > 0 {
+		endpoint += "?" + data.Encode()
+		data = nil
+	}
+	req, err := ac.newRequest(endpoint, method, data)
 	if err != nil {
-		log.Fatalf("Error setting up TradeKing client: %v\n", err)
+		return nil, err
 	}
+	req.Header.Set("Accept-Encoding", "gzip")
 
-	consumerSecret, err := getCredsFromKeychain("TradeKing", "consumer_secret")
-	if err != nil {
-		log.Fatalf("Error setting up TradeKing client: %v\n", err)
+	if ac.DryRun && method != "GET" {
+		w := ac.DryRunOutput
+		if w == nil {
+			w = os.Stdout
+		}
+		fmt.Fprintf(w, "%v %v\n", req.Method, req.URL)
+		if data != nil {
+			fmt.Fprintf(w, "Content-Type: %v\n\n%v\n", req.Header.Get("Content-Type"), data.Encode())
+		}
+		return nil, ErrDryRun
 	}
 
-	accessToken, err := getCredsFromKeychain("TradeKing", "access_token")
+	resp, err := ac.open(req)
 	if err != nil {
-		log.Fatalf("Error setting up TradeKing client: %v\n", err)
+		ac.Metrics.addError()
+		return nil, err
 	}
+	defer resp.Body.Close()
+	ac.updateRateLimit(resp)
+	return io.ReadAll(resp.Body)
+}
 
-	accessSecret, err := getCredsFromKeychain("TradeKing", "access_secret")
+// StreamQuotes streams quotes and trades for symbols, passing each message to
+// handle until the connection closes or handle returns an error
+func (ac *Client) StreamQuotes(symbols []string, handle func(*APIResponse) error) error {
+	quotesEndpoint := ac.env.StreamURL + "/market/quotes.json"
+
+	data := make(map[string][]string, 1)
+	data["symbols"] = []string{strings.Join(symbols, ",")}
+
+	req, err := ac.newRequest(quotesEndpoint, "POST", data)
 	if err != nil {
-		log.Fatalf("Error setting up TradeKing client: %v\n", err)
+		return err
+	}
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+	wd := ac.newWatchdog(cancel)
+	defer wd.stop()
+
+	err = ac.doRequest(req.WithContext(ctx), ac.Metrics.countQuotes(func(m *APIResponse) error {
+		wd.reset()
+		return handle(m)
+	}))
+	if wd.fired() {
+		return ErrStreamStale
 	}
+	return err
+}
 
-	config := oauth1.NewConfig(consumerKey, consumerSecret)
-	token := oauth1.NewToken(accessToken, accessSecret)
+// GetQuotes returns quotes for symbols. If fields is not empty, only those
+// fields are returned.
+func (ac *Client) GetQuotes(symbols, fields []string) (*APIResponse, error) {
+	if ac.Cache == nil {
+		return ac.getQuotes(symbols, fields)
+	}
 
-	client := allyClient{
-		config.Client(oauth1.NoContext, token),
-		0,
-		sync.Mutex{},
+	bySymbol := make(map[string]map[string]string)
+	var missing []string
+	for _, s := range symbols {
+		if q, ok := ac.Cache.Get(s, fields); ok {
+			bySymbol[strings.ToUpper(s)] = q
+		} else {
+			missing = append(missing, s)
+		}
 	}
 
-	return &client
-}
+	resp := &APIResponse{Response: &ResponseBody{Quotes: &Quotes{}}}
+	if len(missing) > 0 {
+		var err error
+		resp, err = ac.getQuotes(missing, fields)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Response.Quotes == nil {
+			resp.Response.Quotes = &Quotes{}
+		}
+		if err := ac.Cache.Put(resp.Response.Quotes.Quote, fields); err != nil {
+			ac.logger.Warn("unable to save quote cache", "error", err)
+		}
+		for _, q := range resp.Response.Quotes.Quote {
+			bySymbol[strings.ToUpper(q["symbol"])] = q
+		}
+	}
 
-func printVersion() {
-	fmt.Println("allyapi version:", version)
-	os.Exit(0)
+	// Return quotes in the order they were requested
+	quotes := make(QuoteArray, 0, len(symbols))
+	for _, s := range symbols {
+		if q, ok := bySymbol[strings.ToUpper(s)]; ok {
+			quotes = append(quotes, q)
+		}
+	}
+	resp.Response.Quotes.Quote = quotes
+	return resp, nil
 }
 
-// Try to get credentials from keychain
-func getCredsFromKeychain(service, account string) (string, error) {
-	query := keychain.NewItem()
-	query.SetSecClass(keychain.SecClassGenericPassword)
-	query.SetService(service)
-	query.SetAccount(account)
-	query.SetMatchLimit(keychain.MatchLimitOne)
-	query.SetReturnData(true)
-	results, err := keychain.QueryItem(query)
-	if err != nil {
-		return "", err
-	} else if len(results) != 1 {
-		return "", fmt.Errorf("got %v results", len(results))
+// Fetch quotes in chunks of at most ChunkSize symbols and merge the results
+func (ac *Client) getQuotes(symbols, fields []string) (*APIResponse, error) {
+	size := ac.ChunkSize
+	if size <= 0 {
+		size = MaxQuoteSymbols
+	}
+	if len(symbols) <= size {
+		return ac.getQuotesChunk(symbols, fields)
 	}
-	password := string(results[0].Data)
-	return password, nil
-}
 
-func showAccounts() (string, error) {
-	accountsURL := "/accounts.json"
+	var chunks [][]string
+	for len(symbols) > size {
+		chunks = append(chunks, symbols[:size])
+		symbols = symbols[size:]
+	}
+	chunks = append(chunks, symbols)
 
-	accounts, err := client.get(accountsURL)
-	if err != nil {
-		return "", err
+	concurrency := ac.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	responses := make([]*APIResponse, len(chunks))
+	errs := make([]error, len(chunks))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, chunk []string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			responses[i], errs[i] = ac.getQuotesChunk(chunk, fields)
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
 	}
-	return accounts, nil
-}
 
-func init() {
-	showVersionFlag = flag.Bool("version", false, "Print version")
-	streamFlag = flag.Bool("stream", false, "Stream symbols")
-	symbols = flag.String("symbols", "", "Comma-separated list of symbols to search for quotes")
+	merged := responses[0]
+	if merged.Response.Quotes == nil {
+		merged.Response.Quotes = &Quotes{}
+	}
+	for _, resp := range responses[1:] {
+		if resp.Response.Quotes != nil {
+			merged.Response.Quotes.Quote = append(merged.Response.Quotes.Quote, resp.Response.Quotes.Quote...)
+		}
+	}
+	return merged, nil
 }
 
-func main() {
+func (ac *Client) getQuotesChunk(symbols, fields []string) (*APIResponse, error) {
+	quotesEndpoint := ac.endpoint("/market/ext/quotes")
 
-	flag.Parse()
-	if *showVersionFlag {
-		printVersion()
+	data := make(map[string][]string, 2)
+	data["symbols"] = []string{strings.Join(symbols, ",")}
+	if len(fields) > 0 {
+		data["fids"] = []string{strings.Join(fields, ",")}
 	}
 
-	switch *symbols {
-	case "":
-		flag.PrintDefaults()
+	return ac.post(quotesEndpoint, data)
+}
+
+// NewClient returns a client with the given options. Unless they include
+// WithCredentials, it loads credentials as given by WithCredentialSource,
+// which defaults to "auto" and the default config path.
+func NewClient(opts ...Option) (*Client, error) {
+	client := Client{
+		format:      "json",
+		env:         Dev,
+		ctx:         context.Background(),
+		logger:      slog.Default(),
+		credsSource: "auto",
+		credsPath:   DefaultConfigPath(),
+		userAgent:   "allyapi/" + moduleVersion(),
+	}
+	for _, opt := range opts {
+		opt(&client)
+	}
+	switch client.format {
+	case "json", "xml":
 	default:
-		symbolsSlice := strings.Split(*symbols, ",")
+		return nil, fmt.Errorf("invalid response format: %q", client.format)
+	}
 
-		if *streamFlag {
-			quotes, err := client.streamQuotes(symbolsSlice)
-			if err != nil {
-				log.Fatalf("error streaming quotes: %v", err)
-			}
-			fmt.Println(quotes)
-		} else {
-			quotes, err := client.getQuotes(symbolsSlice)
-			if err != nil {
-				log.Fatalf("error getting quotes: %v", err)
-			}
-			fmt.Println(quotes)
+	creds := client.creds
+	if creds == nil {
+		var err error
+		if creds, err = LoadProfileCredentials(client.credsSource, client.credsPath, client.profile); err != nil {
+			return nil, fmt.Errorf("error loading credentials: %w", err)
 		}
 	}
-	wg.Wait()
+	config := oauth1.NewConfig(creds.ConsumerKey, creds.ConsumerSecret)
+	token := oauth1.NewToken(creds.AccessToken, creds.AccessSecret)
+
+	if client.proxy != nil {
+		var t *http.Transport
+		switch rt := client.transport.(type) {
+		case nil:
+			t = http.DefaultTransport.(*http.Transport).Clone()
+		case *http.Transport:
+			t = rt.Clone()
+		default:
+			return nil, fmt.Errorf("can't set a proxy on a transport of type %T", rt)
+		}
+		t.Proxy = http.ProxyURL(client.proxy)
+		client.transport = t
+	}
+
+	// Debug output wraps the transport so that it shows signed requests
+	if client.debug != nil {
+		next := client.transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		client.transport = &debugTransport{next: next, w: client.debug}
+	}
+
+	// oauth1 signs requests and sends them with the client in the context
+	ctx := oauth1.NoContext
+	if client.transport != nil {
+		ctx = context.WithValue(ctx, oauth1.HTTPClient, &http.Client{Transport: client.transport})
+	}
+	client.Client = config.Client(ctx, token)
+
+	return &client, nil
+}
+
+// Wait blocks until rate limit bookkeeping from previous calls is finished
+func (ac *Client) Wait() {
+	ac.wg.Wait()
 }
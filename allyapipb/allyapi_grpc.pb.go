@@ -0,0 +1,252 @@
+// The gRPC interface served by "allyapi serve -grpc". Prices are decimal
+// strings, e.g. "150.25", so that they aren't rounded.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: allyapi.proto
+
+package allyapipb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Ally_GetQuotes_FullMethodName    = "/allyapi.v1.Ally/GetQuotes"
+	Ally_StreamQuotes_FullMethodName = "/allyapi.v1.Ally/StreamQuotes"
+	Ally_PreviewOrder_FullMethodName = "/allyapi.v1.Ally/PreviewOrder"
+	Ally_PlaceOrder_FullMethodName   = "/allyapi.v1.Ally/PlaceOrder"
+)
+
+// AllyClient is the client API for Ally service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AllyClient interface {
+	// Get quotes for one or more symbols
+	GetQuotes(ctx context.Context, in *QuotesRequest, opts ...grpc.CallOption) (*QuotesResponse, error)
+	// Stream quotes and trades for one or more symbols until the call is
+	// cancelled or the stream ends
+	StreamQuotes(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamMessage], error)
+	// Get the estimated cost and commission of an order without placing it
+	PreviewOrder(ctx context.Context, in *Order, opts ...grpc.CallOption) (*OrderResponse, error)
+	// Place an order
+	PlaceOrder(ctx context.Context, in *Order, opts ...grpc.CallOption) (*OrderResponse, error)
+}
+
+type allyClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAllyClient(cc grpc.ClientConnInterface) AllyClient {
+	return &allyClient{cc}
+}
+
+func (c *allyClient) GetQuotes(ctx context.Context, in *QuotesRequest, opts ...grpc.CallOption) (*QuotesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QuotesResponse)
+	err := c.cc.Invoke(ctx, Ally_GetQuotes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *allyClient) StreamQuotes(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamMessage], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Ally_ServiceDesc.Streams[0], Ally_StreamQuotes_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamRequest, StreamMessage]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Ally_StreamQuotesClient = grpc.ServerStreamingClient[StreamMessage]
+
+func (c *allyClient) PreviewOrder(ctx context.Context, in *Order, opts ...grpc.CallOption) (*OrderResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OrderResponse)
+	err := c.cc.Invoke(ctx, Ally_PreviewOrder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *allyClient) PlaceOrder(ctx context.Context, in *Order, opts ...grpc.CallOption) (*OrderResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OrderResponse)
+	err := c.cc.Invoke(ctx, Ally_PlaceOrder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AllyServer is the server API for Ally service.
+// All implementations must embed UnimplementedAllyServer
+// for forward compatibility.
+type AllyServer interface {
+	// Get quotes for one or more symbols
+	GetQuotes(context.Context, *QuotesRequest) (*QuotesResponse, error)
+	// Stream quotes and trades for one or more symbols until the call is
+	// cancelled or the stream ends
+	StreamQuotes(*StreamRequest, grpc.ServerStreamingServer[StreamMessage]) error
+	// Get the estimated cost and commission of an order without placing it
+	PreviewOrder(context.Context, *Order) (*OrderResponse, error)
+	// Place an order
+	PlaceOrder(context.Context, *Order) (*OrderResponse, error)
+	mustEmbedUnimplementedAllyServer()
+}
+
+// UnimplementedAllyServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAllyServer struct{}
+
+func (UnimplementedAllyServer) GetQuotes(context.Context, *QuotesRequest) (*QuotesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetQuotes not implemented")
+}
+func (UnimplementedAllyServer) StreamQuotes(*StreamRequest, grpc.ServerStreamingServer[StreamMessage]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamQuotes not implemented")
+}
+func (UnimplementedAllyServer) PreviewOrder(context.Context, *Order) (*OrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PreviewOrder not implemented")
+}
+func (UnimplementedAllyServer) PlaceOrder(context.Context, *Order) (*OrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PlaceOrder not implemented")
+}
+func (UnimplementedAllyServer) mustEmbedUnimplementedAllyServer() {}
+func (UnimplementedAllyServer) testEmbeddedByValue()              {}
+
+// UnsafeAllyServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AllyServer will
+// result in compilation errors.
+type UnsafeAllyServer interface {
+	mustEmbedUnimplementedAllyServer()
+}
+
+func RegisterAllyServer(s grpc.ServiceRegistrar, srv AllyServer) {
+	// If the following call pancis, it indicates UnimplementedAllyServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Ally_ServiceDesc, srv)
+}
+
+func _Ally_GetQuotes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QuotesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AllyServer).GetQuotes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ally_GetQuotes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AllyServer).GetQuotes(ctx, req.(*QuotesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ally_StreamQuotes_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AllyServer).StreamQuotes(m, &grpc.GenericServerStream[StreamRequest, StreamMessage]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Ally_StreamQuotesServer = grpc.ServerStreamingServer[StreamMessage]
+
+func _Ally_PreviewOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Order)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AllyServer).PreviewOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ally_PreviewOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AllyServer).PreviewOrder(ctx, req.(*Order))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ally_PlaceOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Order)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AllyServer).PlaceOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ally_PlaceOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AllyServer).PlaceOrder(ctx, req.(*Order))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Ally_ServiceDesc is the grpc.ServiceDesc for Ally service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Ally_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "allyapi.v1.Ally",
+	HandlerType: (*AllyServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetQuotes",
+			Handler:    _Ally_GetQuotes_Handler,
+		},
+		{
+			MethodName: "PreviewOrder",
+			Handler:    _Ally_PreviewOrder_Handler,
+		},
+		{
+			MethodName: "PlaceOrder",
+			Handler:    _Ally_PlaceOrder_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamQuotes",
+			Handler:       _Ally_StreamQuotes_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "allyapi.proto",
+}
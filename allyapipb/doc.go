@@ -0,0 +1,5 @@
+// Package allyapipb holds the gRPC service served by "allyapi serve -grpc",
+// generated from allyapi.proto.
+package allyapipb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative allyapi.proto
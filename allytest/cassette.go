@@ -0,0 +1,264 @@
+package allytest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Mode selects whether a Recorder records or replays interactions
+type Mode int
+
+const (
+	// ModeReplay serves responses from the cassette file and never touches
+	// the network
+	ModeReplay Mode = iota
+
+	// ModeRecord sends requests with the wrapped transport and records them
+	ModeRecord
+)
+
+// Interaction is a recorded request and its response
+type Interaction struct {
+	Request  RecordedRequest
+	Response RecordedResponse
+}
+
+// RecordedRequest is the part of a request used to match it on replay.
+// Headers are not recorded, so OAuth signatures never reach the cassette.
+type RecordedRequest struct {
+	Method string
+	URL    string
+	Body   string `json:",omitempty"`
+}
+
+// RecordedResponse is a recorded response
+type RecordedResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       string
+}
+
+// Headers that are dropped from recorded responses
+var scrubbedHeaders = []string{"Set-Cookie", "Authorization"}
+
+// Recorder is an http.RoundTripper that records API interactions to a
+// cassette file and replays them in tests. To record, pass it to the client
+// and save the cassette when done:
+//
+//	rec, err := allytest.NewRecorder("testdata/quotes.json", allytest.ModeRecord, nil)
+//	client, err := allyapi.NewClient(allyapi.WithTransport(rec))
+//	...
+//	err = rec.Save()
+//
+// To replay, create the recorder with ModeReplay instead.
+type Recorder struct {
+	Path      string
+	Mode      Mode
+	Transport http.RoundTripper
+
+	// If not nil, Scrub is called on each interaction before it is
+	// recorded, e.g. to mask account numbers
+	Scrub func(*Interaction)
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewRecorder returns a recorder for the cassette at path. In replay mode the
+// cassette is loaded immediately.
+func NewRecorder(path string, mode Mode, transport http.RoundTripper) (*Recorder, error) {
+	r := &Recorder{Path: path, Mode: mode, Transport: transport}
+	if mode == ModeRecord {
+		if transport == nil {
+			r.Transport = http.DefaultTransport
+		}
+		return r, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &r.interactions); err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	r.used = make([]bool, len(r.interactions))
+	return r, nil
+}
+
+// Requests are matched on method, path, query, and body so that a cassette
+// recorded against one environment replays against any other
+func matchKey(method, rawURL, body string) string {
+	if i := strings.Index(rawURL, "://"); i >= 0 {
+		rawURL = rawURL[i+3:]
+		if j := strings.Index(rawURL, "/"); j >= 0 {
+			rawURL = rawURL[j:]
+		}
+	}
+	return method + " " + rawURL + "\n" + body
+}
+
+func readBody(req *http.Request) (string, error) {
+	if req.Body == nil {
+		return "", nil
+	}
+	b, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return "", err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(b))
+	return string(b), nil
+}
+
+// RoundTrip records or replays a single request
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Mode == ModeRecord {
+		return r.record(req, body)
+	}
+	return r.replay(req, body)
+}
+
+func (r *Recorder) replay(req *http.Request, body string) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := matchKey(req.Method, req.URL.String(), body)
+	for i, in := range r.interactions {
+		if r.used[i] || matchKey(in.Request.Method, in.Request.URL, in.Request.Body) != key {
+			continue
+		}
+		r.used[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %v", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+			StatusCode:    in.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        in.Response.Header.Clone(),
+			Body:          io.NopCloser(strings.NewReader(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("no recorded interaction for %v %v", req.Method, req.URL)
+}
+
+func (r *Recorder) record(req *http.Request, body string) (*http.Response, error) {
+	resp, err := r.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Record bodies uncompressed, so that the cassette stays readable text
+	// and replays to clients that didn't ask for compression
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			resp.Body.Close()
+			return nil, fmt.Errorf("error decompressing response: %v", err)
+		}
+		resp.Body = &gzipBody{Reader: gz, body: resp.Body}
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Uncompressed = true
+	}
+
+	header := resp.Header.Clone()
+	for _, h := range scrubbedHeaders {
+		header.Del(h)
+	}
+	in := Interaction{
+		Request:  RecordedRequest{Method: req.Method, URL: req.URL.String(), Body: body},
+		Response: RecordedResponse{StatusCode: resp.StatusCode, Header: header},
+	}
+
+	// Record the body as it is read so that streaming responses are
+	// captured up to the point the client stops reading
+	resp.Body = &recordingBody{ReadCloser: resp.Body, recorder: r, interaction: in}
+	return resp, nil
+}
+
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (b *gzipBody) Close() error {
+	b.Reader.Close()
+	return b.body.Close()
+}
+
+type recordingBody struct {
+	io.ReadCloser
+	recorder    *Recorder
+	interaction Interaction
+	buf         bytes.Buffer
+	closed      bool
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	if !b.closed {
+		b.closed = true
+		b.interaction.Response.Body = b.buf.String()
+		b.recorder.add(b.interaction)
+	}
+	return b.ReadCloser.Close()
+}
+
+func (r *Recorder) add(in Interaction) {
+	if r.Scrub != nil {
+		r.Scrub(&in)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interactions = append(r.interactions, in)
+	r.used = append(r.used, true)
+}
+
+// Interactions returns the recorded interactions
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.interactions...)
+}
+
+// Save writes the recorded interactions to the cassette file
+func (r *Recorder) Save() error {
+	if r.Mode != ModeRecord {
+		return errors.New("recorder is not recording")
+	}
+
+	r.mu.Lock()
+	b, err := json.MarshalIndent(r.interactions, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.Path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.Path, append(b, '\n'), 0644)
+}
@@ -0,0 +1,54 @@
+package allytest_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/n8henrie/allyapi"
+	"github.com/n8henrie/allyapi/allytest"
+)
+
+func TestRecorderRoundTrip(t *testing.T) {
+	srv := allytest.NewServer()
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	// The fake API compresses responses, as the real one does
+	rec, err := allytest.NewRecorder(path, allytest.ModeRecord, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.Scrub = func(in *allytest.Interaction) {
+		in.Response.Body = strings.ReplaceAll(in.Response.Body, allytest.AccountID, "87654321")
+	}
+	b, err := srv.Client(allyapi.WithTransport(rec)).Balances(allytest.AccountID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	in := rec.Interactions()
+	if len(in) != 1 || !strings.Contains(in[0].Response.Body, `"accountvalue":"25000.00"`) || in[0].Response.Header.Get("Content-Encoding") != "" {
+		t.Fatalf("recorded %+v, want an uncompressed body", in)
+	}
+
+	rec, err = allytest.NewRecorder(path, allytest.ModeReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := srv.Client(allyapi.WithTransport(rec))
+	replayed, err := client.Balances(allytest.AccountID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.AccountValue != b.AccountValue || replayed.Account != "87654321" {
+		t.Errorf("replayed %+v", replayed)
+	}
+
+	// Each interaction replays once
+	if _, err := client.Balances(allytest.AccountID); err == nil {
+		t.Error("replayed an interaction twice")
+	}
+}
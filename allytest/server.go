@@ -0,0 +1,860 @@
+// Package allytest provides an in-process fake of the Ally Invest API for
+// testing code that uses the allyapi client without real credentials.
+package allytest
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+)
+
+// AccountID is the ID of the single account served by the fake API
+const AccountID = "12345678"
+
+// Server is a fake Ally Invest API. Quotes for symbols in Quotes are served
+// as given; quotes for other symbols are generated.
+type Server struct {
+	*httptest.Server
+
+	// Streams send a quote and a trade for each symbol and then end, or, if
+	// StreamInterval is set, repeat them at that interval until the client
+	// disconnects
+	StreamInterval time.Duration
+
+	mu       sync.Mutex
+	Quotes   map[string]map[string]string
+	requests []*http.Request
+	dir      string
+	orders   []*order
+}
+
+// A placed order, listed in the account's orders
+type order struct {
+	id, symbol, side, typ, price, qty, status string
+	fills                                     []execution
+}
+
+// A partial or full fill of an order
+type execution struct {
+	qty   int
+	price string
+}
+
+// NewServer starts a fake API server; call Close when done with it
+func NewServer() *Server {
+	s := &Server{Quotes: make(map[string]map[string]string)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/market/ext/quotes.json", s.handleQuotes)
+	mux.HandleFunc("/v1/market/ext/quotes.xml", s.handleQuotes)
+	mux.HandleFunc("/v1/market/historical/search.json", s.handleHistorical)
+	mux.HandleFunc("/v1/market/timesales.json", s.handleTimeSales)
+	mux.HandleFunc("/v1/market/options/expirations.json", s.handleExpirations)
+	mux.HandleFunc("/v1/market/options/search.json", s.handleOptionSearch)
+	mux.HandleFunc("/v1/accounts.json", s.handleAccounts)
+	mux.HandleFunc("/v1/accounts/", s.handleAccount)
+	mux.HandleFunc("/v1/member/profile.json", s.handleMember)
+	mux.HandleFunc("/v1/watchlists.json", s.handleWatchlists)
+	mux.HandleFunc("/v1/watchlists/", s.handleWatchlist)
+	mux.HandleFunc("/stream/v1/market/quotes.json", s.handleStream)
+
+	s.Server = httptest.NewServer(s.record(mux))
+	return s
+}
+
+// Close shuts down the server and removes any files it created
+func (s *Server) Close() {
+	s.Server.Close()
+	if s.dir != "" {
+		os.RemoveAll(s.dir)
+	}
+}
+
+// Environment returns the URLs of the fake API
+func (s *Server) Environment() allyapi.Environment {
+	return allyapi.Environment{
+		BaseURL:   s.URL + "/v1",
+		StreamURL: s.URL + "/stream/v1",
+	}
+}
+
+// Client returns a client for the fake API using dummy credentials
+func (s *Server) Client(opts ...allyapi.Option) *allyapi.Client {
+	s.mu.Lock()
+	if s.dir == "" {
+		dir, err := os.MkdirTemp("", "allytest")
+		if err != nil {
+			panic(err)
+		}
+		s.dir = dir
+	}
+	path := filepath.Join(s.dir, "config.toml")
+	s.mu.Unlock()
+
+	creds := allyapi.Credentials{
+		ConsumerKey:    "consumer_key",
+		ConsumerSecret: "consumer_secret",
+		AccessToken:    "access_token",
+		AccessSecret:   "access_secret",
+	}
+	if err := allyapi.StoreCredentials("file", path, &creds); err != nil {
+		panic(err)
+	}
+
+	opts = append([]allyapi.Option{allyapi.WithEnvironment(s.Environment()), allyapi.WithCredentialSource("file", path)}, opts...)
+	client, err := allyapi.NewClient(opts...)
+	if err != nil {
+		panic(err)
+	}
+	return client
+}
+
+// Requests returns the requests the server has received, oldest first
+func (s *Server) Requests() []*http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*http.Request(nil), s.requests...)
+}
+
+// Record requests, reject unsigned ones, and add rate limit headers
+func (s *Server) record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		s.mu.Lock()
+		s.requests = append(s.requests, r)
+		s.mu.Unlock()
+
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "OAuth ") {
+			http.Error(w, "missing OAuth signature", http.StatusUnauthorized)
+			return
+		}
+
+		expire := time.Now().Add(time.Minute).Unix()
+		w.Header().Set("X-Ratelimit-Used", "1")
+		w.Header().Set("X-Ratelimit-Expire", strconv.FormatInt(expire, 10))
+		w.Header().Set("X-Ratelimit-Limit", "60")
+		w.Header().Set("X-Ratelimit-Remaining", "59")
+
+		// Compress like the real API, except for streams
+		if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") && !strings.HasPrefix(r.URL.Path, "/stream/") {
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			defer gz.Close()
+			w = gzipResponseWriter{ResponseWriter: w, Writer: gz}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	io.Writer
+}
+
+func (w gzipResponseWriter) Write(b []byte) (int, error) {
+	return w.Writer.Write(b)
+}
+
+// Quote returns the quote served for symbol
+func (s *Server) Quote(symbol string) map[string]string {
+	symbol = strings.ToUpper(symbol)
+	if opt, err := allyapi.ParseOptionSymbol(symbol); err == nil {
+		return s.optionQuote(symbol, opt)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if q, ok := s.Quotes[symbol]; ok {
+		return q
+	}
+
+	// Generate a stable price from the symbol
+	price := 10.0
+	for _, c := range symbol {
+		price += float64(c % 32)
+	}
+	return map[string]string{
+		"symbol": symbol,
+		"name":   symbol + " Inc",
+		"last":   fmt.Sprintf("%.2f", price),
+		"pcls":   fmt.Sprintf("%.2f", price-1),
+		"chg":    "1.00",
+		"pchg":   fmt.Sprintf("%.2f%%", 100/(price-1)),
+		"bid":    fmt.Sprintf("%.2f", price-0.01),
+		"ask":    fmt.Sprintf("%.2f", price+0.01),
+		"vl":     "1000000",
+	}
+}
+
+// Generate an option quote priced from the underlying's quote, with 30%
+// volatility
+func (s *Server) optionQuote(symbol string, opt allyapi.OptionSymbol) map[string]string {
+	s.mu.Lock()
+	q, ok := s.Quotes[symbol]
+	s.mu.Unlock()
+	if ok {
+		return q
+	}
+
+	under, _ := strconv.ParseFloat(s.Quote(opt.Underlying)["last"], 64)
+	days := math.Max(1, math.Ceil(time.Until(opt.Expiration).Hours()/24))
+	spread := under * 0.3 * math.Sqrt(days/365)
+
+	// Intrinsic value plus time value that shrinks away from the money
+	moneyness := under - opt.Strike.Float64()
+	if opt.Type == allyapi.Put {
+		moneyness = -moneyness
+	}
+	price := math.Max(0, moneyness) + 0.4*spread*math.Exp(-math.Abs(moneyness)/spread)
+	delta := 1 / (1 + math.Exp(-1.7*moneyness/spread))
+	if opt.Type == allyapi.Put {
+		delta = -delta
+	}
+
+	return map[string]string{
+		"symbol":             symbol,
+		"name":               fmt.Sprintf("%v %v %v %v", opt.Underlying, opt.Expiration.Format("Jan 02 2006"), opt.Strike, opt.Type),
+		"last":               fmt.Sprintf("%.2f", price),
+		"bid":                fmt.Sprintf("%.2f", math.Max(0, price-0.05)),
+		"ask":                fmt.Sprintf("%.2f", price+0.05),
+		"vl":                 "100",
+		"openinterest":       "1000",
+		"days_to_expiration": strconv.Itoa(int(days)),
+		"delta":              fmt.Sprintf("%.4f", delta),
+		"gamma":              "0.0200",
+		"theta":              fmt.Sprintf("%.4f", -price/days/2),
+		"vega":               "0.1000",
+		"rho":                "0.0100",
+		"imp_volatility":     "0.3000",
+	}
+}
+
+// Third Fridays of the next four months
+func expirations() []time.Time {
+	var dates []time.Time
+	now := time.Now()
+	for i := 1; len(dates) < 4; i++ {
+		first := time.Date(now.Year(), now.Month()+time.Month(i-1), 1, 0, 0, 0, 0, time.UTC)
+		offset := (int(time.Friday) - int(first.Weekday()) + 7) % 7
+		if d := first.AddDate(0, 0, offset+14); d.After(now) {
+			dates = append(dates, d)
+		}
+	}
+	return dates
+}
+
+func (s *Server) handleExpirations(w http.ResponseWriter, r *http.Request) {
+	var dates []string
+	for _, d := range expirations() {
+		dates = append(dates, d.Format("2006-01-02"))
+	}
+	writeJSON(w, map[string]interface{}{
+		"expirationdates": map[string]interface{}{"date": dates},
+		"error":           "Success",
+	})
+}
+
+// Serve the options on symbols within 25% of its price, for queries such as
+// "xdate-eq:20250117 AND put_call-eq:call"
+func (s *Server) handleOptionSearch(w http.ResponseWriter, r *http.Request) {
+	underlying := strings.ToUpper(r.FormValue("symbol"))
+	last, _ := strconv.ParseFloat(s.Quote(underlying)["last"], 64)
+
+	dates := expirations()
+	types := []allyapi.OptionType{allyapi.Call, allyapi.Put}
+	for _, cond := range strings.Split(r.FormValue("query"), " AND ") {
+		switch {
+		case strings.HasPrefix(cond, "xdate-eq:"):
+			d, err := time.Parse("20060102", strings.TrimPrefix(cond, "xdate-eq:"))
+			if err != nil {
+				http.Error(w, "invalid xdate", http.StatusBadRequest)
+				return
+			}
+			dates = []time.Time{d}
+		case strings.HasPrefix(cond, "put_call-eq:"):
+			typ, err := allyapi.ParseOptionType(strings.TrimPrefix(cond, "put_call-eq:"))
+			if err != nil {
+				http.Error(w, "invalid put_call", http.StatusBadRequest)
+				return
+			}
+			types = []allyapi.OptionType{typ}
+		}
+	}
+
+	step := 5.0
+	if last < 50 {
+		step = 1
+	}
+	var quotes []map[string]string
+	for _, d := range dates {
+		for _, typ := range types {
+			for k := math.Ceil(last*0.75/step) * step; k <= last*1.25; k += step {
+				opt := allyapi.OptionSymbol{Underlying: underlying, Expiration: d, Type: typ, Strike: allyapi.NewDecimal(k)}
+				quotes = append(quotes, s.Quote(opt.String()))
+			}
+		}
+	}
+	writeJSON(w, map[string]interface{}{
+		"quotes": map[string]interface{}{"quote": quotes},
+		"error":  "Success",
+	})
+}
+
+func (s *Server) quotes(r *http.Request) []map[string]string {
+	var fields []string
+	if fids := r.Form.Get("fids"); fids != "" {
+		fields = strings.Split(fids, ",")
+	}
+
+	var quotes []map[string]string
+	for _, sym := range strings.Split(r.Form.Get("symbols"), ",") {
+		if sym == "" {
+			continue
+		}
+		q := s.Quote(sym)
+		if fields != nil {
+			selected := make(map[string]string, len(fields))
+			for _, f := range fields {
+				if v, ok := q[f]; ok {
+					selected[f] = v
+				}
+			}
+			q = selected
+		}
+		quotes = append(quotes, q)
+	}
+	return quotes
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"response": body})
+}
+
+func (s *Server) handleQuotes(w http.ResponseWriter, r *http.Request) {
+	quotes := s.quotes(r)
+
+	if strings.HasSuffix(r.URL.Path, ".xml") {
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprint(w, `<response id="allytest"><elapsedtime>0</elapsedtime><quotes><quotetype>Delayed</quotetype>`)
+		for _, q := range quotes {
+			fmt.Fprint(w, "<quote>")
+			for k, v := range q {
+				fmt.Fprintf(w, "<%v>", k)
+				xml.EscapeText(w, []byte(v))
+				fmt.Fprintf(w, "</%v>", k)
+			}
+			fmt.Fprint(w, "</quote>")
+		}
+		fmt.Fprint(w, `</quotes><error>Success</error></response>`)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"@id":         "allytest",
+		"elapsedtime": "0",
+		"quotes":      map[string]interface{}{"quotetype": "Delayed", "quote": quotes},
+		"error":       "Success",
+	})
+}
+
+var balance = map[string]interface{}{
+	"account":      AccountID,
+	"accountvalue": "25000.00",
+	"buyingpower": map[string]string{
+		"cashavailableforwithdrawal": "5000.00",
+		"stock":                      "10000.00",
+		"options":                    "5000.00",
+	},
+	"money": map[string]string{
+		"cash":          "5000.00",
+		"cashavailable": "5000.00",
+		"total":         "5000.00",
+	},
+	"securities": map[string]string{
+		"stocks": "20000.00",
+		"total":  "20000.00",
+	},
+}
+
+var holdings = map[string]interface{}{
+	"holding": []map[string]interface{}{
+		{
+			"accounttype":   "2",
+			"costbasis":     "15000.00",
+			"gainloss":      "5000.00",
+			"instrument":    map[string]string{"sectyp": "CS", "sym": "AAPL"},
+			"marketvalue":   "20000.00",
+			"price":         "200.00",
+			"purchaseprice": "150.00",
+			"qty":           "100",
+		},
+	},
+	"totalsecurities": "20000.00",
+}
+
+var history = []map[string]interface{}{
+	{
+		"activity": "Trade",
+		"amount":   "-15004.95",
+		"date":     "2020-01-02T00:00:00-05:00",
+		"desc":     "",
+		"symbol":   "AAPL",
+		"transaction": map[string]interface{}{
+			"accounttype":     "2",
+			"commission":      "4.95",
+			"description":     "APPLE INC",
+			"fee":             "0.0",
+			"price":           "150.0",
+			"quantity":        "100.0",
+			"secfee":          "0.0",
+			"security":        map[string]string{"cusip": "037833100", "id": "", "sectyp": "CS", "sym": "AAPL"},
+			"settlementdate":  "2020-01-06T00:00:00-05:00",
+			"side":            "1",
+			"source":          "",
+			"tradedate":       "2020-01-02T00:00:00-05:00",
+			"transactiondate": "2020-01-02T00:00:00-05:00",
+		},
+	},
+	{
+		"activity": "Dividend",
+		"amount":   "77.0",
+		"date":     "2020-02-13T00:00:00-05:00",
+		"desc":     "APPLE INC",
+		"symbol":   "AAPL",
+		"transaction": map[string]interface{}{
+			"accounttype":     "2",
+			"commission":      "0.0",
+			"description":     "APPLE INC CASH DIV",
+			"fee":             "0.0",
+			"price":           "0.0",
+			"quantity":        "0.0",
+			"secfee":          "0.0",
+			"security":        map[string]string{"cusip": "037833100", "id": "", "sectyp": "CS", "sym": "AAPL"},
+			"settlementdate":  "2020-02-13T00:00:00-05:00",
+			"side":            "0",
+			"source":          "",
+			"tradedate":       "2020-02-13T00:00:00-05:00",
+			"transactiondate": "2020-02-13T00:00:00-05:00",
+		},
+	},
+}
+
+// Serve a generated daily bar for each weekday in the requested range, which
+// defaults to the last 30 days, closing at the symbol's previous close
+func (s *Server) handleHistorical(w http.ResponseWriter, r *http.Request) {
+	q := s.Quote(r.FormValue("symbols"))
+	pcls, _ := strconv.ParseFloat(q["pcls"], 64)
+
+	end := time.Now()
+	if t, err := time.Parse("2006-01-02", r.FormValue("enddate")); err == nil {
+		end = t
+	}
+	start := end.AddDate(0, 0, -30)
+	if t, err := time.Parse("2006-01-02", r.FormValue("startdate")); err == nil {
+		start = t
+	}
+
+	var bars []map[string]string
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+			continue
+		}
+		price := pcls + float64(d.YearDay()%7) - 3
+		bars = append(bars, map[string]string{
+			"date":   d.Format("2006-01-02"),
+			"open":   fmt.Sprintf("%.2f", price-0.5),
+			"high":   fmt.Sprintf("%.2f", price+1),
+			"low":    fmt.Sprintf("%.2f", price-1),
+			"close":  fmt.Sprintf("%.2f", price),
+			"volume": "1000000",
+		})
+	}
+	writeJSON(w, map[string]interface{}{
+		"timeseries": map[string]interface{}{"series": map[string]interface{}{"data": bars}},
+		"error":      "Success",
+	})
+}
+
+// Serve generated time and sales for each weekday in the requested range,
+// which defaults to the start date, a bar every interval of the session
+// starting from the symbol's previous close
+func (s *Server) handleTimeSales(w http.ResponseWriter, r *http.Request) {
+	q := s.Quote(r.FormValue("symbols"))
+	pcls, _ := strconv.ParseFloat(q["pcls"], 64)
+
+	start, err := time.ParseInLocation("2006-01-02", r.FormValue("startdate"), allyapi.MarketTime)
+	if err != nil {
+		http.Error(w, "invalid startdate", http.StatusBadRequest)
+		return
+	}
+	end := start
+	if t, err := time.ParseInLocation("2006-01-02", r.FormValue("enddate"), allyapi.MarketTime); err == nil {
+		end = t
+	}
+	step := time.Minute
+	if r.FormValue("interval") == "5min" {
+		step = 5 * time.Minute
+	}
+
+	var quotes []map[string]string
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+			continue
+		}
+		open := d.Add(9*time.Hour + 30*time.Minute)
+		for i, t := 0, open; t.Before(open.Add(390 * time.Minute)); i, t = i+1, t.Add(step) {
+			price := pcls + float64(i%20)/10 - 1
+			quotes = append(quotes, map[string]string{
+				"date":      t.Format("2006-01-02"),
+				"datetime":  t.Format(time.RFC3339),
+				"timestamp": strconv.FormatInt(t.Unix(), 10),
+				"opn":       fmt.Sprintf("%.2f", price-0.05),
+				"hi":        fmt.Sprintf("%.2f", price+0.1),
+				"lo":        fmt.Sprintf("%.2f", price-0.1),
+				"last":      fmt.Sprintf("%.2f", price),
+				"incr_vl":   "1000",
+				"vl":        strconv.Itoa(1000 * (i + 1)),
+			})
+		}
+	}
+	writeJSON(w, map[string]interface{}{
+		"quotes": map[string]interface{}{"quote": quotes},
+		"error":  "Success",
+	})
+}
+
+func (s *Server) handleMember(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"userdata": map[string]interface{}{
+			"account": map[string]string{
+				"account":       AccountID,
+				"fundtrading":   "true",
+				"ira":           "false",
+				"margintrading": "true",
+				"nickname":      "Individual",
+				"optionlevel":   "2",
+				"shared":        "false",
+				"stocktrading":  "true",
+			},
+			"disabled":      "false",
+			"resetpassword": "false",
+			"resetpin":      "false",
+		},
+		"error": "Success",
+	})
+}
+
+func (s *Server) handleAccounts(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"accounts": map[string]interface{}{
+			"accountsummary": map[string]interface{}{
+				"account":         AccountID,
+				"accountbalance":  balance,
+				"accountholdings": holdings,
+			},
+		},
+		"error": "Success",
+	})
+}
+
+// The page of history asked for by the page and count parameters, or all of
+// it without them
+func historyPage(r *http.Request) []map[string]interface{} {
+	count, err := strconv.Atoi(r.FormValue("count"))
+	if err != nil || count <= 0 {
+		return history
+	}
+	page, err := strconv.Atoi(r.FormValue("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	start := min((page-1)*count, len(history))
+	return history[start:min(start+count, len(history))]
+}
+
+// Serve balances, holdings, and orders for AccountID
+func (s *Server) handleAccount(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/v1/accounts/")
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) != 2 || parts[0] != AccountID {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch parts[1] {
+	case "balances.json":
+		writeJSON(w, map[string]interface{}{"accountbalance": balance, "error": "Success"})
+	case "history.json":
+		writeJSON(w, map[string]interface{}{
+			"transactions": map[string]interface{}{"transaction": historyPage(r)},
+			"error":        "Success",
+		})
+	case "holdings.json":
+		writeJSON(w, map[string]interface{}{"accountholdings": holdings, "error": "Success"})
+	case "orders.json":
+		s.handleOrders(w)
+	case "orders.xml":
+		s.handleOrder(w, r, false)
+	case "orders/preview.xml":
+		s.handleOrder(w, r, true)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) handleOrder(w http.ResponseWriter, r *http.Request, preview bool) {
+	type fixmlOrder struct {
+		Acct    string `xml:",attr"`
+		Side    string `xml:",attr"`
+		Typ     string `xml:",attr"`
+		Px      string `xml:",attr"`
+		Instrmt struct {
+			Sym string `xml:",attr"`
+		}
+		OrdQty struct {
+			Qty string `xml:",attr"`
+		}
+	}
+	var msg struct {
+		Order     *fixmlOrder `xml:"Order"`
+		OrderList *struct {
+			Orders []fixmlOrder `xml:"Ord"`
+		} `xml:"NewOrdList"`
+		MultiLegOrder *struct {
+			Acct   string `xml:",attr"`
+			Orders []struct {
+				Leg struct {
+					Sym string `xml:",attr"`
+				}
+			} `xml:"Ord"`
+		} `xml:"NewOrdMleg"`
+	}
+	if r.Method != "POST" || xml.NewDecoder(r.Body).Decode(&msg) != nil ||
+		(msg.Order == nil && (msg.OrderList == nil || len(msg.OrderList.Orders) == 0) && (msg.MultiLegOrder == nil || len(msg.MultiLegOrder.Orders) == 0)) {
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprint(w, `<response id="allytest"><error>Invalid FIXML</error></response>`)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	if preview {
+		fmt.Fprint(w, `<response id="allytest"><elapsedtime>0</elapsedtime>`+
+			`<estcommission>0.00</estcommission><principal>100.00</principal>`+
+			`<secfee>0.00</secfee><marginrequirement>0.00</marginrequirement>`+
+			`<netamt>100.00</netamt><error>Success</error></response>`)
+		return
+	}
+
+	// Single orders are listed in the account's orders
+	s.mu.Lock()
+	id := fmt.Sprintf("SVI-%d", len(s.orders)+1)
+	o := &order{id: id, status: "0"}
+	if msg.Order != nil {
+		o.symbol, o.side, o.typ, o.price, o.qty = msg.Order.Instrmt.Sym, msg.Order.Side, msg.Order.Typ, msg.Order.Px, msg.Order.OrdQty.Qty
+	}
+	s.orders = append(s.orders, o)
+	s.mu.Unlock()
+
+	fmt.Fprintf(w, `<response id="allytest"><elapsedtime>0</elapsedtime>`+
+		`<clientorderid>%v</clientorderid><orderstatus>0</orderstatus>`+
+		`<error>Success</error></response>`, id)
+}
+
+// SetOrderStatus sets the FIXML status code of a placed order, such as "2"
+// for filled, which is listed in the account's orders. Orders are numbered
+// SVI-1, SVI-2, and so on as they are placed.
+func (s *Server) SetOrderStatus(id, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, o := range s.orders {
+		if o.id == id {
+			o.status = status
+		}
+	}
+}
+
+// Fill fills qty more of a placed order at price, making it partially filled
+// or, once all of it is, filled. Each fill is listed as an execution in the
+// account's orders.
+func (s *Server) Fill(id string, qty int, price string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, o := range s.orders {
+		if o.id != id {
+			continue
+		}
+		o.fills = append(o.fills, execution{qty, price})
+		o.status = "1"
+		if o.filled() >= atoi(o.qty) {
+			o.status = "2"
+		}
+	}
+}
+
+func (o *order) filled() int {
+	n := 0
+	for _, f := range o.fills {
+		n += f.qty
+	}
+	return n
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// List the single orders placed as FIXML execution reports, one for each fill
+func (s *Server) handleOrders(w http.ResponseWriter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().In(allyapi.MarketTime).Format("2006-01-02T15:04:05.000-07:00")
+	orders := []map[string]string{}
+	report := func(o *order, status string, leaves int, last string) {
+		msg := fmt.Sprintf(`<FIXML xmlns="http://www.fixprotocol.org/FIXML-5-0-SP2">`+
+			`<ExecRpt OrdID="%v" Stat="%v" Acct="%v" AcctTyp="1" Side="%v" Typ="%v" Px="%v" TmInForce="0" LeavesQty="%v"%v TxnTm="%v">`+
+			`<Instrmt Sym="%v" SecTyp="CS"/><OrdQty Qty="%v"/></ExecRpt></FIXML>`,
+			o.id, status, AccountID, o.side, o.typ, o.price, leaves, last, now, o.symbol, o.qty)
+		orders = append(orders, map[string]string{"fixmlmessage": msg})
+	}
+	for _, o := range s.orders {
+		if o.symbol == "" {
+			continue
+		}
+		leaves := atoi(o.qty)
+		if len(o.fills) == 0 {
+			if o.status == "2" || o.status == "4" || o.status == "8" {
+				leaves = 0
+			}
+			report(o, o.status, leaves, "")
+			continue
+		}
+		for i, f := range o.fills {
+			leaves -= f.qty
+			status := "1"
+			if i == len(o.fills)-1 {
+				status = o.status
+			}
+			report(o, status, max(leaves, 0), fmt.Sprintf(` LastQty="%v" LastPx="%v"`, f.qty, f.price))
+		}
+	}
+	writeJSON(w, map[string]interface{}{
+		"orderstatus": map[string]interface{}{"loaded": "true", "order": orders},
+		"error":       "Success",
+	})
+}
+
+func (s *Server) handleWatchlists(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		writeJSON(w, map[string]interface{}{"error": "Success"})
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"watchlists": map[string]interface{}{
+			"watchlist": []map[string]string{{"id": "DEFAULT"}},
+		},
+		"error": "Success",
+	})
+}
+
+func (s *Server) handleWatchlist(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/watchlists/"), ".json")
+	id, symbols, _ := strings.Cut(path, "/")
+	if id != "DEFAULT" {
+		writeJSON(w, map[string]interface{}{"error": "watchlist not found"})
+		return
+	}
+	// Changes to the watchlist are accepted but not kept
+	if symbols != "" || r.Method != http.MethodGet {
+		writeJSON(w, map[string]interface{}{"error": "Success"})
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"watchlists": map[string]interface{}{
+			"watchlist": map[string]interface{}{
+				"id": "DEFAULT",
+				"watchlistitem": []map[string]interface{}{
+					{"costbasis": "0", "qty": "0", "instrument": map[string]string{"sym": "AAPL"}},
+					{"costbasis": "0", "qty": "0", "instrument": map[string]string{"sym": "MSFT"}},
+				},
+			},
+		},
+		"error": "Success",
+	})
+}
+
+// Send a status message, then one quote and one trade per symbol
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.Encode(map[string]string{"status": "connected"})
+	s.streamQuotes(enc, r)
+
+	if s.StreamInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.StreamInterval)
+	defer ticker.Stop()
+	for {
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		select {
+		case <-ticker.C:
+			s.streamQuotes(enc, r)
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *Server) streamQuotes(enc *json.Encoder, r *http.Request) {
+	now := time.Now()
+	for _, sym := range strings.Split(r.Form.Get("symbols"), ",") {
+		if sym == "" {
+			continue
+		}
+		q := s.Quote(sym)
+		enc.Encode(map[string]interface{}{
+			"quote": map[string]interface{}{
+				"ask":       q["ask"],
+				"asksz":     "1",
+				"bid":       q["bid"],
+				"bidsz":     "1",
+				"datetime":  now.Format(time.RFC3339),
+				"exch":      map[string]string{},
+				"qcond":     "REGULAR",
+				"symbol":    q["symbol"],
+				"timestamp": strconv.FormatInt(now.Unix(), 10),
+			},
+		})
+		enc.Encode(map[string]interface{}{
+			"trade": map[string]interface{}{
+				"cvol":      "100",
+				"datetime":  now.Format(time.RFC3339),
+				"exch":      map[string]string{},
+				"last":      q["last"],
+				"symbol":    q["symbol"],
+				"timestamp": strconv.FormatInt(now.Unix(), 10),
+				"vl":        q["vl"],
+				"vwap":      q["last"],
+			},
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package allyapi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrDuplicateOrder is returned instead of placing an order identical to one
+// placed within the client's DuplicateWindow
+var ErrDuplicateOrder = errors.New("duplicate order")
+
+// AuditEntry is a record of an order request in the audit log
+type AuditEntry struct {
+	Time time.Time `json:"time"`
+
+	// "preview" or "place"
+	Action   string `json:"action"`
+	Account  string `json:"account"`
+	Endpoint string `json:"endpoint"`
+
+	// FIXML message sent
+	Request string `json:"request"`
+
+	// Response, if the request succeeded, or else its error
+	Response *OrderResponse `json:"response,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// Open the audit log for appending, creating it if needed
+func openAuditLog(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+}
+
+// Append an entry to the audit log as one line of JSON, in one write
+func writeAuditEntry(f *os.File, e *AuditEntry) error {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(e); err != nil {
+		return err
+	}
+	_, err := f.Write(b.Bytes())
+	return err
+}
+
+// Record an order request sent to endpoint, with its response or error.
+// The log is opened before the request is sent, so that no order goes
+// unrecorded because the log can't be written; call the returned function
+// with the outcome once there is one.
+func (ac *Client) auditOrder(endpoint string, request []byte) (func(*APIResponse, error), error) {
+	if ac.AuditLog == "" {
+		return func(*APIResponse, error) {}, nil
+	}
+	f, err := openAuditLog(ac.AuditLog)
+	if err != nil {
+		return nil, fmt.Errorf("error opening audit log: %v", err)
+	}
+
+	e := &AuditEntry{Time: time.Now(), Action: "place", Endpoint: endpoint, Request: string(request)}
+	if strings.HasSuffix(endpoint, "/preview.xml") {
+		e.Action = "preview"
+	}
+	if parts := strings.Split(endpoint, "/"); len(parts) > 2 && parts[1] == "accounts" {
+		e.Account = parts[2]
+	}
+	return func(resp *APIResponse, err error) {
+		defer f.Close()
+		if err != nil {
+			e.Error = err.Error()
+		} else if resp != nil && resp.Response != nil {
+			e.Response = &resp.Response.OrderResponse
+		}
+		if err := writeAuditEntry(f, e); err != nil {
+			ac.logger.Error("unable to write audit log", "path", ac.AuditLog, "error", err)
+		}
+	}, nil
+}
+
+// Refuse to place an order whose FIXML message matches one placed
+// successfully within window, according to the audit log
+func (ac *Client) checkDuplicate(endpoint string, request []byte, window time.Duration) error {
+	if window <= 0 || ac.AuditLog == "" || strings.HasSuffix(endpoint, "/preview.xml") {
+		return nil
+	}
+	entries, err := ReadAuditLog(ac.AuditLog)
+	if err != nil {
+		return fmt.Errorf("error reading audit log: %v", err)
+	}
+
+	since := time.Now().Add(-window)
+	for i := len(entries) - 1; i >= 0 && entries[i].Time.After(since); i-- {
+		e := &entries[i]
+		if e.Action != "place" || e.Error != "" || e.Request != string(request) {
+			continue
+		}
+		placed := e.Time.In(MarketTime).Format("15:04:05")
+		if e.Response != nil && e.Response.ClientOrderID != "" {
+			placed += " as order " + e.Response.ClientOrderID
+		}
+		return fmt.Errorf("%w: the same order was placed at %v", ErrDuplicateOrder, placed)
+	}
+	return nil
+}
+
+// ReadAuditLog returns the entries of an audit log, oldest first. A missing
+// log has none.
+func ReadAuditLog(path string) ([]AuditEntry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var e AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%v:%v: %v", path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,74 @@
+package allyapi
+
+import (
+	"net/url"
+	"sync"
+)
+
+// Balance is the value, cash, and buying power of an account
+type Balance struct {
+	Account      string  `json:",omitempty" xml:"account,omitempty"`
+	AccountValue Decimal `json:",omitempty" xml:"accountvalue,omitempty"`
+	BuyingPower  struct {
+		CashAvailableForWithdrawal Decimal `json:",omitempty" xml:"cashavailableforwithdrawal,omitempty"`
+		DayTrading                 Decimal `json:",omitempty" xml:"daytrading,omitempty"`
+		Options                    Decimal `json:",omitempty" xml:"options,omitempty"`
+		Stock                      Decimal `json:",omitempty" xml:"stock,omitempty"`
+	} `json:",omitempty" xml:"buyingpower,omitempty"`
+	FedCall   Decimal `json:",omitempty" xml:"fedcall,omitempty"`
+	HouseCall Decimal `json:",omitempty" xml:"housecall,omitempty"`
+	Money     struct {
+		AccruedInterest   Decimal `json:",omitempty" xml:"accruedinterest,omitempty"`
+		Cash              Decimal `json:",omitempty" xml:"cash,omitempty"`
+		CashAvailable     Decimal `json:",omitempty" xml:"cashavailable,omitempty"`
+		MarginBalance     Decimal `json:",omitempty" xml:"marginbalance,omitempty"`
+		MMF               Decimal `json:",omitempty" xml:"mmf,omitempty"`
+		Total             Decimal `json:",omitempty" xml:"total,omitempty"`
+		UnclearedDeposits Decimal `json:",omitempty" xml:"uncleareddeposits,omitempty"`
+		UnsettledFunds    Decimal `json:",omitempty" xml:"unsettledfunds,omitempty"`
+	} `json:",omitempty" xml:"money,omitempty"`
+	Securities struct {
+		LongOptions  Decimal `json:",omitempty" xml:"longoptions,omitempty"`
+		LongStocks   Decimal `json:",omitempty" xml:"longstocks,omitempty"`
+		Options      Decimal `json:",omitempty" xml:"options,omitempty"`
+		ShortOptions Decimal `json:",omitempty" xml:"shortoptions,omitempty"`
+		ShortStocks  Decimal `json:",omitempty" xml:"shortstocks,omitempty"`
+		Stocks       Decimal `json:",omitempty" xml:"stocks,omitempty"`
+		Total        Decimal `json:",omitempty" xml:"total,omitempty"`
+	} `json:",omitempty" xml:"securities,omitempty"`
+}
+
+// Balances returns the balances of an account
+func (ac *Client) Balances(account string) (*Balance, error) {
+	resp, err := ac.get(ac.endpoint("/accounts/" + url.PathEscape(account) + "/balances"))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Response.AccountBalance == nil {
+		return &Balance{Account: account}, nil
+	}
+	return resp.Response.AccountBalance, nil
+}
+
+// AllBalances returns the balances of each account, in the order of
+// accounts, fetching them concurrently
+func (ac *Client) AllBalances(accounts []string) ([]*Balance, error) {
+	balances := make([]*Balance, len(accounts))
+	errs := make([]error, len(accounts))
+	var wg sync.WaitGroup
+	for i, account := range accounts {
+		wg.Add(1)
+		go func(i int, account string) {
+			defer wg.Done()
+			balances[i], errs[i] = ac.Balances(account)
+		}(i, account)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return balances, nil
+}
@@ -0,0 +1,119 @@
+package allyapi
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SymbolBar is a completed bar of a symbol, as built by a BarBuilder
+type SymbolBar struct {
+	Symbol string `json:"symbol"`
+	Bar
+}
+
+// UnmarshalJSON decodes the symbol along with the bar, whose own
+// UnmarshalJSON would otherwise leave it out
+func (b *SymbolBar) UnmarshalJSON(data []byte) error {
+	var s struct {
+		Symbol string `json:"symbol"`
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	b.Symbol = s.Symbol
+	return b.Bar.UnmarshalJSON(data)
+}
+
+// BarBuilder aggregates streamed trades into open, high, low, and close
+// prices and volume over intervals of Interval for each symbol. Intervals
+// are aligned to market midnight, so one-minute bars start on the minute.
+//
+// A bar is complete once a trade of its symbol arrives in a later interval or
+// Flush is called after it ends. Symbols without trades in an interval have no
+// bar for it. It is safe to use from more than one goroutine.
+type BarBuilder struct {
+	Interval time.Duration
+
+	mu   sync.Mutex
+	bars map[string]*Bar
+}
+
+// NewBarBuilder returns a bar builder for bars of interval
+func NewBarBuilder(interval time.Duration) *BarBuilder {
+	return &BarBuilder{Interval: interval, bars: make(map[string]*Bar)}
+}
+
+// Start of the interval that t is in
+func (b *BarBuilder) start(t time.Time) time.Time {
+	t = t.In(MarketTime)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, MarketTime)
+	return midnight.Add(t.Sub(midnight).Truncate(b.Interval))
+}
+
+// AddTrade adds a trade to its symbol's bar, returning the symbol's previous
+// bar if the trade completes it. Trades without a time are taken to be at
+// now.
+func (b *BarBuilder) AddTrade(t *StreamTrade, now time.Time) *SymbolBar {
+	if t.Symbol == "" || t.Last <= 0 {
+		return nil
+	}
+	symbol := strings.ToUpper(t.Symbol)
+	at := t.Time()
+	if at.IsZero() {
+		at = now
+	}
+	start := b.start(at)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var done *SymbolBar
+	bar := b.bars[symbol]
+	if bar != nil && start.After(bar.Time) {
+		done = &SymbolBar{Symbol: symbol, Bar: *bar}
+		bar = nil
+	}
+	if bar == nil {
+		bar = &Bar{Time: start, Open: t.Last, High: t.Last, Low: t.Last}
+		b.bars[symbol] = bar
+	} else if start.Before(bar.Time) {
+		// Late trades belong to a bar that is already complete
+		return nil
+	}
+	if t.Last > bar.High {
+		bar.High = t.Last
+	}
+	if t.Last < bar.Low {
+		bar.Low = t.Last
+	}
+	bar.Close = t.Last
+	bar.Volume += int64(t.Vl)
+	return done
+}
+
+// Flush returns the bars whose intervals ended by now, sorted by symbol
+func (b *BarBuilder) Flush(now time.Time) []SymbolBar {
+	return b.flush(func(bar *Bar) bool { return !bar.Time.Add(b.Interval).After(now) })
+}
+
+// FlushAll returns every bar, including those still in progress, sorted by
+// symbol
+func (b *BarBuilder) FlushAll() []SymbolBar {
+	return b.flush(func(*Bar) bool { return true })
+}
+
+func (b *BarBuilder) flush(done func(*Bar) bool) []SymbolBar {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var bars []SymbolBar
+	for symbol, bar := range b.bars {
+		if done(bar) {
+			bars = append(bars, SymbolBar{Symbol: symbol, Bar: *bar})
+			delete(b.bars, symbol)
+		}
+	}
+	sort.Slice(bars, func(i, j int) bool { return bars[i].Symbol < bars[j].Symbol })
+	return bars
+}
@@ -0,0 +1,93 @@
+package allyapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned instead of making a request while a client's
+// circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// CircuitBreaker stops a client from making requests for Cooldown once
+// Threshold requests in a row have failed, so that a script stuck on errors
+// doesn't keep calling the API and use up the rate limit. Requests fail with
+// ErrCircuitOpen meanwhile. After the cooldown one request is let through: if
+// it succeeds the breaker closes, and if not it stays open for another
+// cooldown.
+//
+// Failures are network errors and error statuses from the API, other than
+// authorization errors, which are left for the caller to deal with, and 404s.
+// Requests the caller canceled don't count either way.
+type CircuitBreaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// NewCircuitBreaker returns a breaker that opens for cooldown after threshold
+// failures in a row
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{Threshold: threshold, Cooldown: cooldown}
+}
+
+// Open reports whether the breaker is failing requests, and until when
+func (b *CircuitBreaker) Open() (bool, time.Time) {
+	if b == nil {
+		return false, time.Time{}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.openUntil.IsZero(), b.openUntil
+}
+
+// Return ErrCircuitOpen if a request can't be made now. Once the cooldown is
+// over, the one request allowed until it finishes is the probe.
+func (b *CircuitBreaker) allow() error {
+	if b == nil || b.Threshold <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case b.openUntil.IsZero():
+		return nil
+	case b.probing || time.Now().Before(b.openUntil):
+		return fmt.Errorf("%w after %d failures; retrying after %v", ErrCircuitOpen, b.failures, b.openUntil.Format(time.TimeOnly))
+	}
+	b.probing = true
+	return nil
+}
+
+// Count a request's result, reporting whether it opened the breaker
+func (b *CircuitBreaker) record(err error) (opened bool) {
+	if b == nil || b.Threshold <= 0 {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wasProbe := b.probing
+	b.probing = false
+	switch {
+	case err == nil:
+		b.failures = 0
+		b.openUntil = time.Time{}
+		return false
+	case errors.Is(err, context.Canceled), errors.Is(err, ErrUnauthorized), errors.Is(err, ErrNotFound):
+		return false
+	}
+	b.failures++
+	if b.failures >= b.Threshold || wasProbe {
+		b.openUntil = time.Now().Add(b.Cooldown)
+		return true
+	}
+	return false
+}
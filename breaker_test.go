@@ -0,0 +1,75 @@
+package allyapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	b := NewCircuitBreaker(3, time.Hour)
+	fail := &APIError{StatusCode: http.StatusInternalServerError, Message: "Internal Server Error"}
+
+	// Failures the caller deals with don't count, and a success resets the
+	// count
+	for _, err := range []error{fail, fail, nil, fail, fail,
+		context.Canceled,
+		&APIError{StatusCode: http.StatusUnauthorized},
+		fmt.Errorf("wrapped: %w", &APIError{StatusCode: http.StatusNotFound}),
+	} {
+		if err := b.allow(); err != nil {
+			t.Fatal(err)
+		}
+		if b.record(err) {
+			t.Fatalf("opened after %v", err)
+		}
+	}
+
+	if !b.record(fail) {
+		t.Fatal("didn't open after three failures in a row")
+	}
+	if open, until := b.Open(); !open || time.Until(until) < 59*time.Minute {
+		t.Errorf("Open() = %v, %v", open, until)
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("got %v while open, want ErrCircuitOpen", err)
+	}
+
+	// After the cooldown one probe is let through, and a failed probe opens
+	// the breaker again
+	b.openUntil = time.Now()
+	if err := b.allow(); err != nil {
+		t.Fatalf("probe not allowed: %v", err)
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("got %v during the probe, want ErrCircuitOpen", err)
+	}
+	if !b.record(fail) {
+		t.Error("failed probe didn't reopen the breaker")
+	}
+
+	b.openUntil = time.Now()
+	if err := b.allow(); err != nil {
+		t.Fatal(err)
+	}
+	b.record(nil)
+	if open, _ := b.Open(); open {
+		t.Error("still open after a successful probe")
+	}
+}
+
+func TestNilCircuitBreaker(t *testing.T) {
+	var b *CircuitBreaker
+	if err := b.allow(); err != nil {
+		t.Error(err)
+	}
+	if b.record(errors.New("failed")) {
+		t.Error("nil breaker opened")
+	}
+	if open, _ := b.Open(); open {
+		t.Error("nil breaker is open")
+	}
+}
@@ -0,0 +1,137 @@
+package allyapi
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInsufficientBuyingPower is returned by the buying power checks when an
+// order costs more than an account has available for it
+var ErrInsufficientBuyingPower = errors.New("insufficient buying power")
+
+// OrderCost estimates what an order to buy costs, before commission: the
+// limit or stop price, or the ask for market orders, times the quantity.
+// Orders that sell cost nothing.
+func (ac *Client) OrderCost(o *Order) (Decimal, error) {
+	if o.Side != "buy" && o.Side != "buy_to_cover" {
+		return 0, nil
+	}
+
+	var price Decimal
+	switch o.Type {
+	case "limit", "stop_limit":
+		price = o.Price
+	case "stop":
+		price = o.StopPrice
+	default:
+		var err error
+		if price, err = ac.askPrice(o.Symbol); err != nil {
+			return 0, err
+		}
+	}
+
+	return price.MulInt(int64(o.Quantity) * Multiplier(o.Symbol)), nil
+}
+
+// The price a market order to buy symbol would likely fill at: the ask, or
+// the last price if there is no ask
+func (ac *Client) askPrice(symbol string) (Decimal, error) {
+	resp, err := ac.GetQuotes([]string{symbol}, []string{"ask", "last"})
+	if err != nil {
+		return 0, err
+	}
+	if resp.Response.Quotes == nil || len(resp.Response.Quotes.Quote) == 0 {
+		return 0, fmt.Errorf("no quote for %v", symbol)
+	}
+	q := resp.Response.Quotes.Quote[0]
+	price, err := ParseDecimal(q["ask"])
+	if err != nil || price <= 0 {
+		if price, err = ParseDecimal(q["last"]); err != nil {
+			return 0, err
+		}
+	}
+	return price, nil
+}
+
+// MultiLegOrderCost estimates what a multi-leg order costs, before
+// commission: the net debit of a limit order times the quantity. Credits and
+// market orders, whose net price isn't known, cost nothing.
+func MultiLegOrderCost(m *MultiLegOrder) Decimal {
+	if m.Type != "limit" || m.Price <= 0 {
+		return 0
+	}
+	return m.Price.MulInt(int64(m.Quantity) * ContractSize)
+}
+
+// CheckBuyingPower returns an error wrapping ErrInsufficientBuyingPower if
+// an order costs more than the account's available cash or, with
+// allowMargin, its stock or option buying power.
+func (ac *Client) CheckBuyingPower(o *Order, allowMargin bool) error {
+	cost, err := ac.OrderCost(o)
+	if err != nil {
+		return fmt.Errorf("error estimating order cost: %w", err)
+	}
+	return ac.checkBuyingPower(o.Account, cost, IsOptionSymbol(o.Symbol), allowMargin)
+}
+
+// CheckOrdersBuyingPower is CheckBuyingPower for orders placed together, such
+// as a ladder, in one account: it fails if they cost more in all than is
+// available
+func (ac *Client) CheckOrdersBuyingPower(orders []Order, allowMargin bool) error {
+	if len(orders) == 0 {
+		return nil
+	}
+	var total Decimal
+	option := false
+	for i := range orders {
+		cost, err := ac.OrderCost(&orders[i])
+		if err != nil {
+			return fmt.Errorf("error estimating order cost: %w", err)
+		}
+		total += cost
+		option = option || IsOptionSymbol(orders[i].Symbol)
+	}
+	return ac.checkBuyingPower(orders[0].Account, total, option, allowMargin)
+}
+
+// CheckMultiLegBuyingPower is CheckBuyingPower for a multi-leg order
+func (ac *Client) CheckMultiLegBuyingPower(m *MultiLegOrder, allowMargin bool) error {
+	return ac.checkBuyingPower(m.Account, MultiLegOrderCost(m), true, allowMargin)
+}
+
+func (ac *Client) checkBuyingPower(account string, cost Decimal, option, allowMargin bool) error {
+	if cost <= 0 {
+		return nil
+	}
+	available, kind, err := ac.buyingPower(account, option, allowMargin)
+	if err != nil {
+		return err
+	}
+	if cost > available {
+		return fmt.Errorf("%w: order costs about $%v, but only $%v of %v is available", ErrInsufficientBuyingPower, cost.StringFixed(2), available.StringFixed(2), kind)
+	}
+	return nil
+}
+
+// AvailableBuyingPower returns what an account has available to buy stock,
+// or options if option is set: its cash or, with allowMargin, its stock or
+// option buying power
+func (ac *Client) AvailableBuyingPower(account string, option, allowMargin bool) (Decimal, error) {
+	available, _, err := ac.buyingPower(account, option, allowMargin)
+	return available, err
+}
+
+// The buying power available and what kind it is
+func (ac *Client) buyingPower(account string, option, allowMargin bool) (Decimal, string, error) {
+	b, err := ac.Balances(account)
+	if err != nil {
+		return 0, "", fmt.Errorf("error getting balances: %w", err)
+	}
+	if !allowMargin {
+		return b.Money.CashAvailable, "cash", nil
+	}
+	if option {
+		return b.BuyingPower.Options, "option buying power", nil
+	}
+	return b.BuyingPower.Stock, "stock buying power", nil
+}
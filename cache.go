@@ -0,0 +1,134 @@
+package allyapi
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QuoteCache holds recently fetched quotes so that repeated requests within
+// TTL don't use up API calls. If Dir is set, quotes are also saved there so
+// that they are shared between processes.
+type QuoteCache struct {
+	TTL time.Duration
+	Dir string
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	Time  time.Time
+	Quote map[string]string
+}
+
+// NewQuoteCache returns a cache that keeps quotes for ttl, saving them in dir
+// if it is not empty
+func NewQuoteCache(ttl time.Duration, dir string) *QuoteCache {
+	return &QuoteCache{TTL: ttl, Dir: dir}
+}
+
+// DefaultCacheDir returns the allyapi directory in the user's cache directory
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "allyapi")
+}
+
+func cacheKey(symbol string, fields []string) string {
+	return strings.ToUpper(symbol) + "|" + strings.Join(fields, ",")
+}
+
+func (qc *QuoteCache) path() string {
+	return filepath.Join(qc.Dir, "quotes.json")
+}
+
+// Load entries saved on disk the first time the cache is used
+func (qc *QuoteCache) load() {
+	if qc.entries != nil {
+		return
+	}
+	qc.entries = make(map[string]cacheEntry)
+	if qc.Dir == "" {
+		return
+	}
+
+	b, err := os.ReadFile(qc.path())
+	if err != nil {
+		return
+	}
+	// A corrupt cache is no worse than an empty one
+	_ = json.Unmarshal(b, &qc.entries)
+}
+
+func (qc *QuoteCache) save() error {
+	if qc.Dir == "" {
+		return nil
+	}
+
+	// Drop expired entries so the file doesn't grow forever
+	for k, e := range qc.entries {
+		if time.Since(e.Time) > qc.TTL {
+			delete(qc.entries, k)
+		}
+	}
+
+	b, err := json.Marshal(qc.entries)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(qc.Dir, 0700); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so other processes never see a
+	// partially written cache
+	tmp, err := os.CreateTemp(qc.Dir, "quotes-*.json")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), qc.path())
+}
+
+// Get returns the cached quote for symbol and fields, if it hasn't expired
+func (qc *QuoteCache) Get(symbol string, fields []string) (map[string]string, bool) {
+	qc.mu.Lock()
+	defer qc.mu.Unlock()
+	qc.load()
+
+	e, ok := qc.entries[cacheKey(symbol, fields)]
+	if !ok || time.Since(e.Time) > qc.TTL {
+		return nil, false
+	}
+	return e.Quote, true
+}
+
+// Put caches quotes that were fetched with fields
+func (qc *QuoteCache) Put(quotes QuoteArray, fields []string) error {
+	qc.mu.Lock()
+	defer qc.mu.Unlock()
+	qc.load()
+
+	now := time.Now()
+	for _, q := range quotes {
+		if q["symbol"] == "" {
+			continue
+		}
+		qc.entries[cacheKey(q["symbol"], fields)] = cacheEntry{Time: now, Quote: q}
+	}
+	return qc.save()
+}
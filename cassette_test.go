@@ -0,0 +1,323 @@
+package allyapi_test
+
+import (
+	"flag"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+	"github.com/n8henrie/allyapi/allytest"
+)
+
+// The cassettes in testdata/cassettes are replayed to check that responses
+// are parsed. They can be re-recorded against the API with
+//
+//	go test -run Cassettes -record
+//
+// which uses the configured credentials and first account, and replaces the
+// account number with allytest.AccountID. Order placement is only replayed.
+var record = flag.Bool("record", false, "record the cassettes in testdata against the API")
+
+// Return a client that replays or records the cassette called name, and the
+// account to use with it
+func cassetteClient(t *testing.T, name string, opts ...allyapi.Option) (*allyapi.Client, string) {
+	t.Helper()
+	path := filepath.Join("testdata", "cassettes", name+".json")
+	if !*record {
+		rec, err := allytest.NewRecorder(path, allytest.ModeReplay, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		creds := &allyapi.Credentials{ConsumerKey: "key", ConsumerSecret: "secret", AccessToken: "token", AccessSecret: "secret"}
+		client, err := allyapi.NewClient(append(opts, allyapi.WithCredentials(creds), allyapi.WithTransport(rec))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client, allytest.AccountID
+	}
+
+	// Look up the account without recording it
+	live, err := allyapi.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids, err := live.AccountIDs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) == 0 {
+		t.Fatal("no accounts to record")
+	}
+	account := ids[0]
+
+	rec, err := allytest.NewRecorder(path, allytest.ModeRecord, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.Scrub = func(in *allytest.Interaction) {
+		in.Request.URL = strings.ReplaceAll(in.Request.URL, account, allytest.AccountID)
+		in.Request.Body = strings.ReplaceAll(in.Request.Body, account, allytest.AccountID)
+		in.Response.Body = strings.ReplaceAll(in.Response.Body, account, allytest.AccountID)
+	}
+	client, err := allyapi.NewClient(append(opts, allyapi.WithTransport(rec))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := rec.Save(); err != nil {
+			t.Error(err)
+		}
+	})
+	return client, account
+}
+
+func date(s string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02", s, allyapi.MarketTime)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestCassettes(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts []allyapi.Option
+
+		// Whether the cassette can be recorded; orders can't be placed just
+		// to record them
+		recordable bool
+
+		run   func(c *allyapi.Client, account string) (interface{}, error)
+		check func(t *testing.T, got interface{})
+	}{
+		{
+			name:       "quotes",
+			recordable: true,
+			run: func(c *allyapi.Client, account string) (interface{}, error) {
+				return c.GetQuotes([]string{"AAPL", "MSFT"}, []string{"symbol", "last", "bid", "ask", "pchg"})
+			},
+			check: func(t *testing.T, got interface{}) {
+				quotes := got.(*allyapi.APIResponse).Response.Quotes.Quote
+				if len(quotes) != 2 || quotes[0]["symbol"] != "AAPL" || quotes[0]["last"] != "172.62" || quotes[1]["pchg"] != "0.37 %" {
+					t.Errorf("quotes = %v", quotes)
+				}
+			},
+		},
+		{
+			// A single quote is an object rather than an array
+			name:       "quotes_xml",
+			opts:       []allyapi.Option{allyapi.WithFormat("xml")},
+			recordable: true,
+			run: func(c *allyapi.Client, account string) (interface{}, error) {
+				return c.GetQuotes([]string{"AAPL"}, []string{"symbol", "last", "bid", "ask"})
+			},
+			check: func(t *testing.T, got interface{}) {
+				quotes := got.(*allyapi.APIResponse).Response.Quotes.Quote
+				if len(quotes) != 1 || quotes[0]["symbol"] != "AAPL" || quotes[0]["ask"] != "172.64" {
+					t.Errorf("quotes = %v", quotes)
+				}
+			},
+		},
+		{
+			name:       "accounts",
+			recordable: true,
+			run: func(c *allyapi.Client, account string) (interface{}, error) {
+				return c.AccountIDs()
+			},
+			check: func(t *testing.T, got interface{}) {
+				if ids := got.([]string); len(ids) != 1 || ids[0] != allytest.AccountID {
+					t.Errorf("accounts = %v", ids)
+				}
+			},
+		},
+		{
+			name:       "balances",
+			recordable: true,
+			run: func(c *allyapi.Client, account string) (interface{}, error) {
+				return c.Balances(account)
+			},
+			check: func(t *testing.T, got interface{}) {
+				b := got.(*allyapi.Balance)
+				if b.AccountValue.String() != "25312.87" || b.Money.CashAvailable.String() != "4012.55" || b.BuyingPower.Stock.String() != "8025.1" {
+					t.Errorf("balances = %+v", b)
+				}
+			},
+		},
+		{
+			// A single holding is an object rather than an array
+			name:       "holdings",
+			recordable: true,
+			run: func(c *allyapi.Client, account string) (interface{}, error) {
+				return c.Holdings(account)
+			},
+			check: func(t *testing.T, got interface{}) {
+				positions, err := got.(allyapi.Holdings).Positions()
+				if err != nil || len(positions) != 1 || positions["AAPL"] != 100 {
+					t.Errorf("positions = %v, %v", positions, err)
+				}
+			},
+		},
+		{
+			name:       "history",
+			recordable: true,
+			run: func(c *allyapi.Client, account string) (interface{}, error) {
+				return c.History(account, "all", "all")
+			},
+			check: func(t *testing.T, got interface{}) {
+				txs := got.(allyapi.Transactions)
+				if len(txs) != 2 || txs[0].Symbol != "AAPL" || txs[0].Transaction.Quantity != "100.0" || txs[1].Activity != "Dividend" {
+					t.Fatalf("history = %+v", txs)
+				}
+				if when, err := txs[1].Time(); err != nil || when.Format("2006-01-02") != "2024-02-15" {
+					t.Errorf("dividend date = %v, %v", when, err)
+				}
+			},
+		},
+		{
+			name:       "orders",
+			recordable: true,
+			run: func(c *allyapi.Client, account string) (interface{}, error) {
+				return c.Orders(account)
+			},
+			check: func(t *testing.T, got interface{}) {
+				orders := got.([]allyapi.OrderStatus)
+				if len(orders) != 1 {
+					t.Fatalf("got %d orders, want 1", len(orders))
+				}
+				o := orders[0]
+				if o.Symbol != "AAPL" || o.Side != "buy" || o.Type != "limit" || o.Status != allyapi.StatusPartiallyFilled ||
+					o.Filled != 4 || o.Remaining != 6 || o.AvgPrice.String() != "170.1" || o.Price.String() != "170.1" {
+					t.Errorf("order = %+v", o)
+				}
+			},
+		},
+		{
+			name:       "preview_order",
+			recordable: true,
+			run: func(c *allyapi.Client, account string) (interface{}, error) {
+				return c.PreviewOrder(&allyapi.Order{Account: account, Symbol: "AAPL", Side: "buy", Type: "limit", Quantity: 1, Price: allyapi.DecimalFromInt(100)})
+			},
+			check: func(t *testing.T, got interface{}) {
+				r := got.(*allyapi.APIResponse).Response
+				if r.Principal.String() != "100" || r.NetAmt.String() != "100" || r.Warning == nil || r.Warning.WarningCode != "1" {
+					t.Errorf("preview = %+v", r.OrderResponse)
+				}
+			},
+		},
+		{
+			name: "place_order",
+			run: func(c *allyapi.Client, account string) (interface{}, error) {
+				return c.PlaceOrder(&allyapi.Order{Account: account, Symbol: "AAPL", Side: "buy", Type: "limit", Quantity: 10, Price: allyapi.NewDecimal(170.1)})
+			},
+			check: func(t *testing.T, got interface{}) {
+				r := got.(*allyapi.APIResponse).Response
+				if r.ClientOrderID != "SVI-2020000001" || r.OrderStatus != "0" {
+					t.Errorf("response = %+v", r.OrderResponse)
+				}
+			},
+		},
+		{
+			name:       "member",
+			recordable: true,
+			run: func(c *allyapi.Client, account string) (interface{}, error) {
+				return c.Member()
+			},
+			check: func(t *testing.T, got interface{}) {
+				m := got.(*allyapi.Member)
+				if len(m.Account) != 1 || m.Account[0].Account != allytest.AccountID || m.Account[0].Nickname != "Individual" {
+					t.Errorf("member = %+v", m)
+				}
+			},
+		},
+		{
+			name:       "watchlists",
+			recordable: true,
+			run: func(c *allyapi.Client, account string) (interface{}, error) {
+				ids, err := c.Watchlists()
+				if err != nil || len(ids) == 0 {
+					return ids, err
+				}
+				symbols, err := c.Watchlist(ids[0])
+				return append(ids, symbols...), err
+			},
+			check: func(t *testing.T, got interface{}) {
+				if ids := got.([]string); strings.Join(ids, ",") != "DEFAULT,AAPL,MSFT" {
+					t.Errorf("watchlist and symbols = %v", ids)
+				}
+			},
+		},
+		{
+			name:       "historical",
+			recordable: true,
+			run: func(c *allyapi.Client, account string) (interface{}, error) {
+				return c.HistoricalQuotes("AAPL", "daily", date("2024-03-04"), date("2024-03-05"))
+			},
+			check: func(t *testing.T, got interface{}) {
+				bars := got.(allyapi.Bars)
+				if len(bars) != 2 {
+					t.Fatalf("got %d bars, want 2", len(bars))
+				}
+				b := bars[1]
+				if !b.Time.Equal(date("2024-03-05")) || b.Open.String() != "170.76" || b.Close.String() != "170.12" || b.Volume != 95132355 {
+					t.Errorf("bar = %+v", b)
+				}
+			},
+		},
+		{
+			name:       "timesales",
+			recordable: true,
+			run: func(c *allyapi.Client, account string) (interface{}, error) {
+				return c.TimeSales("AAPL", "5min", date("2024-03-04"), date("2024-03-04"))
+			},
+			check: func(t *testing.T, got interface{}) {
+				bars := got.(allyapi.Bars)
+				if len(bars) != 3 {
+					t.Fatalf("got %d bars, want 3", len(bars))
+				}
+				b := bars[1]
+				if b.Time.In(allyapi.MarketTime).Format("15:04") != "09:35" || b.Close.String() != "176.26" || b.Volume != 1203456 {
+					t.Errorf("bar = %+v at %v", b, b.Time)
+				}
+			},
+		},
+		{
+			name:       "options",
+			recordable: true,
+			run: func(c *allyapi.Client, account string) (interface{}, error) {
+				dates, err := c.OptionExpirations("AAPL")
+				if err != nil || len(dates) == 0 {
+					return nil, err
+				}
+				return c.OptionChain("AAPL", dates[0], allyapi.Call)
+			},
+			check: func(t *testing.T, got interface{}) {
+				chain := got.([]allyapi.OptionQuote)
+				if len(chain) != 2 {
+					t.Fatalf("got %d options, want 2", len(chain))
+				}
+				q := chain[1]
+				if q.Symbol != "AAPL240315C00175000" || q.Strike.String() != "175" || q.Type != allyapi.Call ||
+					q.Bid.String() != "1.52" || q.OpenInterest != 48213 || q.Delta != 0.4512 || q.ImpliedVolatility != 0.2231 {
+					t.Errorf("option = %+v", q)
+				}
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if *record && !tt.recordable {
+				t.Skip("not recorded")
+			}
+			client, account := cassetteClient(t, tt.name, tt.opts...)
+			got, err := tt.run(client, account)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !*record {
+				tt.check(t, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package allyapi
+
+import (
+	"math"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OptionExpirations returns the expiration dates of options on symbol
+func (ac *Client) OptionExpirations(symbol string) ([]time.Time, error) {
+	query := url.Values{"symbol": {strings.ToUpper(symbol)}}
+	resp, err := ac.get(ac.endpoint("/market/options/expirations") + "?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	if resp.Response.ExpirationDates == nil {
+		return nil, nil
+	}
+
+	var dates []time.Time
+	for _, d := range resp.Response.ExpirationDates.Date {
+		t, err := time.ParseInLocation("2006-01-02", d, MarketTime)
+		if err != nil {
+			ac.logger.Warn("skipping invalid expiration date", "date", d)
+			continue
+		}
+		dates = append(dates, t)
+	}
+	return dates, nil
+}
+
+// OptionChain returns quotes, including greeks, for the options of type typ
+// on symbol that expire on expiration
+func (ac *Client) OptionChain(symbol string, expiration time.Time, typ OptionType) ([]OptionQuote, error) {
+	data := map[string][]string{
+		"symbol": {strings.ToUpper(symbol)},
+		"query":  {"xdate-eq:" + expiration.In(MarketTime).Format("20060102") + " AND put_call-eq:" + typ.String()},
+		"fids":   {strings.Join(OptionQuoteFields, ",")},
+	}
+	resp, err := ac.post(ac.endpoint("/market/options/search"), data)
+	if err != nil {
+		return nil, err
+	}
+	return optionQuotes(resp)
+}
+
+// ChainFilter selects options from a chain. Zero fields don't filter.
+type ChainFilter struct {
+	// Range of the absolute value of delta, e.g. 0.2 to 0.4 for both calls
+	// and puts
+	MinDelta, MaxDelta float64
+
+	// Only options that are out of the money at Underlying, the price of the
+	// underlying
+	OTM        bool
+	Underlying Decimal
+
+	MinOpenInterest int64
+}
+
+// Match reports whether the option passes the filter
+func (f *ChainFilter) Match(q *OptionQuote) bool {
+	delta := math.Abs(q.Delta)
+	if (f.MinDelta > 0 && delta < f.MinDelta) || (f.MaxDelta > 0 && delta > f.MaxDelta) {
+		return false
+	}
+	if f.OTM {
+		if q.Type == Call && q.Strike <= f.Underlying {
+			return false
+		}
+		if q.Type == Put && q.Strike >= f.Underlying {
+			return false
+		}
+	}
+	return q.OpenInterest >= f.MinOpenInterest
+}
+
+// FilterChain returns the options in chain that pass the filter
+func FilterChain(chain []OptionQuote, f ChainFilter) []OptionQuote {
+	var matched []OptionQuote
+	for i := range chain {
+		if f.Match(&chain[i]) {
+			matched = append(matched, chain[i])
+		}
+	}
+	return matched
+}
@@ -0,0 +1,220 @@
+package allyapi_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+	"github.com/n8henrie/allyapi/allytest"
+)
+
+func TestGetQuotes(t *testing.T) {
+	srv := allytest.NewServer()
+	defer srv.Close()
+	srv.Quotes["AAPL"] = map[string]string{"symbol": "AAPL", "last": "40.00", "bid": "39.99"}
+
+	for _, format := range []string{"json", "xml"} {
+		client := srv.Client(allyapi.WithFormat(format))
+		resp, err := client.GetQuotes([]string{"AAPL", "MSFT"}, nil)
+		if err != nil {
+			t.Fatalf("%v: %v", format, err)
+		}
+		quotes := resp.Response.Quotes.Quote
+		if len(quotes) != 2 || quotes[0]["last"] != "40.00" || quotes[0]["bid"] != "39.99" || quotes[1]["symbol"] != "MSFT" {
+			t.Errorf("%v: quotes = %v", format, quotes)
+		}
+	}
+
+	for _, r := range srv.Requests() {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "OAuth ") {
+			t.Errorf("unsigned request to %v", r.URL.Path)
+		}
+	}
+}
+
+func TestAccountRequests(t *testing.T) {
+	srv := allytest.NewServer()
+	defer srv.Close()
+	client := srv.Client()
+
+	ids, err := client.AccountIDs()
+	if err != nil || len(ids) != 1 || ids[0] != allytest.AccountID {
+		t.Errorf("AccountIDs() = %v, %v", ids, err)
+	}
+
+	b, err := client.Balances(allytest.AccountID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.AccountValue.String() != "25000" || b.Money.CashAvailable.String() != "5000" || b.BuyingPower.Stock.String() != "10000" {
+		t.Errorf("balances = %+v", b)
+	}
+
+	holdings, err := client.Holdings(allytest.AccountID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	positions, err := holdings.Positions()
+	if err != nil || len(positions) != 1 || positions["AAPL"] != 100 {
+		t.Errorf("positions = %v, %v", positions, err)
+	}
+
+	txs, err := client.History(allytest.AccountID, "all", "all")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 2 || txs[0].Activity != "Trade" || txs[1].Activity != "Dividend" || txs[1].Amount.String() != "77" {
+		t.Errorf("history = %+v", txs)
+	}
+
+	if _, err := client.Balances("87654321"); !errors.Is(err, allyapi.ErrNotFound) {
+		t.Errorf("got %v for another account, want ErrNotFound", err)
+	}
+}
+
+func TestPlaceOrder(t *testing.T) {
+	srv := allytest.NewServer()
+	defer srv.Close()
+	client := srv.Client()
+
+	o := &allyapi.Order{Account: allytest.AccountID, Symbol: "AAPL", Side: "buy", Type: "limit", Quantity: 10, Price: allyapi.NewDecimal(39.5)}
+	resp, err := client.PlaceOrder(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := resp.Response.ClientOrderID
+	if id != "SVI-1" {
+		t.Fatalf("order ID = %q, want SVI-1", id)
+	}
+
+	reqs := srv.Requests()
+	last := reqs[len(reqs)-1]
+	if last.Method != http.MethodPost || !strings.HasSuffix(last.URL.Path, "/orders.xml") {
+		t.Errorf("placed with %v %v", last.Method, last.URL.Path)
+	}
+
+	srv.Fill(id, 4, "39.50")
+	status, err := client.Order(allytest.AccountID, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Status != allyapi.StatusPartiallyFilled || status.Filled != 4 || status.Remaining != 6 || status.AvgPrice.String() != "39.5" || status.Done() {
+		t.Errorf("after a partial fill: %+v", status)
+	}
+
+	srv.Fill(id, 6, "39.40")
+	orders, err := client.Orders(allytest.AccountID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 1 || orders[0].Status != allyapi.StatusFilled || orders[0].Filled != 10 || orders[0].AvgPrice.String() != "39.44" || !orders[0].Done() {
+		t.Errorf("after filling: %+v", orders)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	srv := allytest.NewServer()
+	defer srv.Close()
+	client := srv.Client()
+	client.DryRun = true
+	client.DryRunOutput = io.Discard
+
+	o := &allyapi.Order{Account: allytest.AccountID, Symbol: "AAPL", Side: "buy", Type: "market", Quantity: 1}
+	if _, err := client.PlaceOrder(o); !errors.Is(err, allyapi.ErrDryRun) {
+		t.Errorf("got %v, want ErrDryRun", err)
+	}
+	if n := len(srv.Requests()); n != 0 {
+		t.Errorf("dry run sent %d requests", n)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestProxyWithTransport(t *testing.T) {
+	srv := allytest.NewServer()
+	defer srv.Close()
+	proxy, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The API's host doesn't resolve, so requests only arrive through the
+	// proxy, and they were sent by the given transport if it dialed
+	env := allyapi.Environment{BaseURL: "http://ally.invalid/v1", StreamURL: "http://ally.invalid/stream/v1"}
+	var dials int32
+	transport := &http.Transport{DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+		atomic.AddInt32(&dials, 1)
+		return (&net.Dialer{}).DialContext(ctx, network, addr)
+	}}
+	for name, opts := range map[string][]allyapi.Option{
+		"transport first": {allyapi.WithTransport(transport), allyapi.WithProxy(proxy)},
+		"proxy first":     {allyapi.WithProxy(proxy), allyapi.WithTransport(transport)},
+	} {
+		before := len(srv.Requests())
+		atomic.StoreInt32(&dials, 0)
+		if _, err := srv.Client(append(opts, allyapi.WithEnvironment(env))...).AccountIDs(); err != nil {
+			t.Errorf("%v: %v", name, err)
+			continue
+		}
+		requests := srv.Requests()[before:]
+		if len(requests) != 1 || requests[0].Host != "ally.invalid" {
+			t.Errorf("%v: proxied %v requests, the first to %v", name, len(requests), requests[0].Host)
+		}
+		if atomic.LoadInt32(&dials) == 0 {
+			t.Errorf("%v: the transport wasn't used", name)
+		}
+	}
+	if transport.Proxy != nil {
+		t.Error("the given transport was changed")
+	}
+
+	// Only an *http.Transport has a proxy to set
+	creds := &allyapi.Credentials{ConsumerKey: "key", ConsumerSecret: "secret", AccessToken: "token", AccessSecret: "secret"}
+	other := roundTripFunc(http.DefaultTransport.RoundTrip)
+	if _, err := allyapi.NewClient(allyapi.WithCredentials(creds), allyapi.WithTransport(other), allyapi.WithProxy(proxy)); err == nil {
+		t.Error("set a proxy on a transport that can't have one")
+	}
+}
+
+func TestErrorStatuses(t *testing.T) {
+	srv := allytest.NewServer()
+	defer srv.Close()
+
+	status := http.StatusUnauthorized
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, `{"response": {"@id": "abc", "error": "failed"}}`)
+	})
+	client := srv.Client()
+	client.Breaker = allyapi.NewCircuitBreaker(2, time.Hour)
+
+	_, err := client.Balances(allytest.AccountID)
+	var apiErr *allyapi.APIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, allyapi.ErrUnauthorized) || apiErr.Message != "failed" || apiErr.RequestID != "abc" {
+		t.Fatalf("got %#v, want an unauthorized APIError", err)
+	}
+
+	// Server errors open the breaker, after which requests aren't sent
+	status = http.StatusInternalServerError
+	for i := 0; i < 2; i++ {
+		if _, err := client.Balances(allytest.AccountID); !errors.Is(err, allyapi.ErrServer) {
+			t.Fatalf("got %v, want ErrServer", err)
+		}
+	}
+	if _, err := client.Balances(allytest.AccountID); !errors.Is(err, allyapi.ErrCircuitOpen) {
+		t.Errorf("got %v, want ErrCircuitOpen", err)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/n8henrie/allyapi"
+)
+
+func accountsCommand() *command {
+	cmd := newCommand("accounts", "accounts [COMMAND]", "Show a summary of all accounts")
+	cmd.commands = []*command{
+		accountsListCommand(),
+	}
+	cmd.run = func(args []string) error {
+		client := newClient()
+		defer client.Wait()
+
+		accounts, err := client.Accounts()
+		if err != nil {
+			return fmt.Errorf("error getting accounts: %v", err)
+		}
+		return printResponse(accounts)
+	}
+	return cmd
+}
+
+const accountFlagUsage = "Account ID or nickname (default: $ALLY_ACCOUNT, default_account in the config file, or the only account)"
+
+func addAccountFlag(fs *flag.FlagSet) *string {
+	return fs.String("account", "", accountFlagUsage)
+}
+
+// Resolve an account ID or nickname. If it is empty, use $ALLY_ACCOUNT, the
+// default account from the config file or, failing those, the only account.
+func defaultAccount(client *allyapi.Client, name string) (string, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		name = os.Getenv("ALLY_ACCOUNT")
+	}
+	if id := cfg.AccountID(name); id != "" {
+		return id, nil
+	}
+
+	ids, err := client.AccountIDs()
+	if err != nil {
+		return "", fmt.Errorf("error getting accounts: %v", err)
+	}
+	switch len(ids) {
+	case 0:
+		return "", errors.New("no accounts found")
+	case 1:
+		return ids[0], nil
+	}
+	return "", fmt.Errorf("multiple accounts (%v); use -account, $ALLY_ACCOUNT, or default_account", strings.Join(ids, ", "))
+}
+
+func accountsListCommand() *command {
+	cmd := newCommand("list", "accounts list", "List account IDs and their nicknames from the config file")
+	output := addOutputFlags(cmd.flags)
+	cmd.run = func(args []string) error {
+		if err := output.validate(); err != nil {
+			return err
+		}
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		client := newClient()
+		defer client.Wait()
+
+		ids, err := client.AccountIDs()
+		if err != nil {
+			return fmt.Errorf("error getting accounts: %v", err)
+		}
+
+		def := cfg.AccountID(os.Getenv("ALLY_ACCOUNT"))
+		var rows []map[string]string
+		for _, id := range ids {
+			row := map[string]string{"account": id, "nickname": cfg.Accounts[id]}
+			if id == def {
+				row["default"] = "*"
+			}
+			rows = append(rows, row)
+		}
+		return output.printRows([]string{"account", "nickname", "default"}, rows)
+	}
+	return cmd
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+)
+
+// Fields that alert rules can test
+var alertFields = map[string]bool{
+	"last":       true,
+	"bid":        true,
+	"ask":        true,
+	"change":     true,
+	"pct_change": true,
+	"volume":     true,
+}
+
+// Quote fields fetched to evaluate rules
+var alertQuoteFields = []string{"symbol", "last", "bid", "ask", "pcls", "vl"}
+
+// A rule such as "AAPL last > 200"
+type alertRule struct {
+	text   string
+	symbol string
+	field  string
+	op     string
+	value  float64
+}
+
+func parseAlertRule(text string) (*alertRule, error) {
+	parts := strings.Fields(text)
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid alert %q: want SYMBOL FIELD OP VALUE", text)
+	}
+	r := &alertRule{
+		text:   text,
+		symbol: strings.ToUpper(parts[0]),
+		field:  strings.ToLower(parts[1]),
+		op:     parts[2],
+	}
+	if !alertFields[r.field] {
+		return nil, fmt.Errorf("invalid alert %q: unknown field %q", text, parts[1])
+	}
+	switch r.op {
+	case ">", ">=", "<", "<=", "==":
+	default:
+		return nil, fmt.Errorf("invalid alert %q: unknown operator %q", text, r.op)
+	}
+	v, err := strconv.ParseFloat(strings.TrimSuffix(parts[3], "%"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid alert %q: %v", text, err)
+	}
+	r.value = v
+	return r, nil
+}
+
+func (r *alertRule) matches(v float64) bool {
+	switch r.op {
+	case ">":
+		return v > r.value
+	case ">=":
+		return v >= r.value
+	case "<":
+		return v < r.value
+	case "<=":
+		return v <= r.value
+	case "==":
+		return v == r.value
+	}
+	return false
+}
+
+// Latest values for a symbol
+type alertQuote struct {
+	last, bid, ask, prevClose float64
+	volume                    float64
+}
+
+func (q *alertQuote) field(name string) (float64, bool) {
+	switch name {
+	case "last":
+		return q.last, q.last != 0
+	case "bid":
+		return q.bid, q.bid != 0
+	case "ask":
+		return q.ask, q.ask != 0
+	case "volume":
+		return q.volume, true
+	case "change":
+		return q.last - q.prevClose, q.last != 0 && q.prevClose != 0
+	case "pct_change":
+		if q.last == 0 || q.prevClose == 0 {
+			return 0, false
+		}
+		return (q.last - q.prevClose) / q.prevClose * 100, true
+	}
+	return 0, false
+}
+
+// Sends a message when an alert fires
+type notifier interface {
+	notify(title, message string) error
+}
+
+// Prints alerts to stdout
+type printNotifier struct{}
+
+func (printNotifier) notify(title, message string) error {
+	fmt.Printf("%v  %v: %v\n", time.Now().Format("2006-01-02 15:04:05"), title, message)
+	return nil
+}
+
+// Evaluates rules as quotes arrive. A rule fires when it starts matching and
+// fires again only after it has stopped matching.
+type alertEngine struct {
+	mu        sync.Mutex
+	rules     []*alertRule
+	quotes    map[string]*alertQuote
+	triggered map[*alertRule]bool
+	notifiers []notifier
+}
+
+func newAlertEngine(rules []*alertRule, notifiers []notifier) *alertEngine {
+	return &alertEngine{
+		rules:     rules,
+		quotes:    make(map[string]*alertQuote),
+		triggered: make(map[*alertRule]bool),
+		notifiers: notifiers,
+	}
+}
+
+func (e *alertEngine) symbols() []string {
+	seen := make(map[string]bool)
+	var symbols []string
+	for _, r := range e.rules {
+		if !seen[r.symbol] {
+			seen[r.symbol] = true
+			symbols = append(symbols, r.symbol)
+		}
+	}
+	return symbols
+}
+
+func (e *alertEngine) quote(symbol string) *alertQuote {
+	q, ok := e.quotes[symbol]
+	if !ok {
+		q = &alertQuote{}
+		e.quotes[symbol] = q
+	}
+	return q
+}
+
+// Update from polled quotes, which also provide the previous close
+func (e *alertEngine) updateQuotes(quotes allyapi.QuoteArray) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, q := range quotes {
+		aq := e.quote(q["symbol"])
+		aq.last = parseFloat(q["last"])
+		aq.bid = parseFloat(q["bid"])
+		aq.ask = parseFloat(q["ask"])
+		aq.prevClose = parseFloat(q["pcls"])
+		aq.volume = parseFloat(q["vl"])
+		e.evaluate(q["symbol"])
+	}
+}
+
+// Update from a streamed quote or trade
+func (e *alertEngine) update(m *allyapi.APIResponse) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	switch {
+	case m.Quote != nil:
+		q := e.quote(m.Quote.Symbol)
+		q.bid = m.Quote.Bid.Float64()
+		q.ask = m.Quote.Ask.Float64()
+		e.evaluate(m.Quote.Symbol)
+	case m.Trade != nil:
+		q := e.quote(m.Trade.Symbol)
+		q.last = m.Trade.Last.Float64()
+		q.volume = float64(m.Trade.Vl)
+		e.evaluate(m.Trade.Symbol)
+	}
+	return nil
+}
+
+// Called with e.mu held
+func (e *alertEngine) evaluate(symbol string) {
+	q := e.quotes[symbol]
+	for _, r := range e.rules {
+		if r.symbol != symbol {
+			continue
+		}
+		v, ok := q.field(r.field)
+		if !ok {
+			continue
+		}
+		matched := r.matches(v)
+		if matched && !e.triggered[r] {
+			msg := fmt.Sprintf("%v is %v", r.field, strconv.FormatFloat(v, 'f', 2, 64))
+			for _, n := range e.notifiers {
+				if err := n.notify("Alert: "+r.text, msg); err != nil {
+					slog.Warn("error sending notification", "error", err)
+				}
+			}
+		}
+		e.triggered[r] = matched
+	}
+}
+
+// Load the rules from the config file
+func loadAlertRules() ([]*alertRule, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Alerts) == 0 {
+		return nil, fmt.Errorf("no alerts in %v; add e.g. alerts = [\"AAPL last > 200\"]", *configFlag)
+	}
+
+	var rules []*alertRule
+	for _, text := range cfg.Alerts {
+		r, err := parseAlertRule(text)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func alertsListCommand() *command {
+	cmd := newCommand("list", "alerts list", "List the alert rules in the config file")
+	cmd.run = func(args []string) error {
+		rules, err := loadAlertRules()
+		if err != nil {
+			return err
+		}
+		for _, r := range rules {
+			fmt.Println(r.text)
+		}
+		return nil
+	}
+	return cmd
+}
+
+func alertsWatchCommand() *command {
+	cmd := newCommand("watch", "alerts watch [flags]", "Watch quotes and send a notification when an alert fires")
+	poll := cmd.flags.Duration("poll", 0, "Poll quotes at this interval instead of streaming them")
+	desktop := cmd.flags.Bool("notify", false, "Also post alerts to Notification Center (macOS)")
+
+	cmd.run = func(args []string) error {
+		rules, err := loadAlertRules()
+		if err != nil {
+			return err
+		}
+		notifiers := []notifier{printNotifier{}}
+		if *desktop {
+			n, err := newDesktopNotifier()
+			if err != nil {
+				return err
+			}
+			notifiers = append(notifiers, n)
+		}
+		engine := newAlertEngine(rules, notifiers)
+		symbols := engine.symbols()
+
+		client := newClient()
+		defer client.Wait()
+
+		// Seed previous closes so that change rules work when streaming
+		quotes, err := client.GetQuotes(symbols, alertQuoteFields)
+		if err != nil {
+			return fmt.Errorf("error getting quotes: %v", err)
+		}
+		if quotes.Response.Quotes != nil {
+			engine.updateQuotes(quotes.Response.Quotes.Quote)
+		}
+
+		if *poll <= 0 {
+			err := client.StreamQuotes(symbols, engine.update)
+			switch {
+			case rootCtx.Err() != nil:
+				return nil
+			case err != nil:
+				return fmt.Errorf("error streaming quotes: %v", err)
+			}
+			return errors.New("stream ended")
+		}
+
+		for sleep(*poll) {
+			quotes, err := client.GetQuotes(symbols, alertQuoteFields)
+			if err != nil {
+				slog.Error("error getting quotes", "error", err)
+				continue
+			}
+			if quotes.Response.Quotes != nil {
+				engine.updateQuotes(quotes.Response.Quotes.Quote)
+			}
+		}
+		return nil
+	}
+	return cmd
+}
+
+func alertsCommand() *command {
+	cmd := newCommand("alerts", "alerts COMMAND [flags]", "Evaluate price alert rules from the config file")
+	cmd.commands = []*command{
+		alertsListCommand(),
+		alertsWatchCommand(),
+	}
+	return cmd
+}
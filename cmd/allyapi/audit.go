@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/n8henrie/allyapi"
+)
+
+// File that order requests are logged to, from the config file or in the
+// state directory
+var auditLogPath = filepath.Join(allyapi.DefaultStateDir(), "orders.log")
+
+var auditColumns = []string{"time", "action", "account", "order_id", "status", "net_amount", "error"}
+
+func auditRow(e *allyapi.AuditEntry) map[string]string {
+	row := map[string]string{
+		"time":    e.Time.In(allyapi.MarketTime).Format("2006-01-02 15:04:05"),
+		"action":  e.Action,
+		"account": e.Account,
+		"error":   e.Error,
+		"request": e.Request,
+	}
+	if r := e.Response; r != nil {
+		row["order_id"] = r.ClientOrderID
+		row["status"] = r.OrderStatus
+		row["net_amount"] = r.NetAmt.StringFixed(2)
+	}
+	return row
+}
+
+func orderLogCommand() *command {
+	cmd := newCommand("log", "orders log [flags]", "Show the log of order requests sent")
+	cmd.footer = "Every order previewed or placed is logged, with the FIXML sent (the request\n" +
+		"field) and the response, to orders.log in the state directory, or to\n" +
+		"audit_log in the config file."
+	output := addOutputFlags(cmd.flags)
+	last := cmd.flags.Int("n", 0, "Show only the last N entries")
+	cmd.run = func(args []string) error {
+		if err := output.validate(); err != nil {
+			return err
+		}
+		entries, err := allyapi.ReadAuditLog(auditLogPath)
+		if err != nil {
+			return fmt.Errorf("error reading audit log: %v", err)
+		}
+		if *last > 0 && len(entries) > *last {
+			entries = entries[len(entries)-*last:]
+		}
+
+		var rows []map[string]string
+		for i := range entries {
+			rows = append(rows, auditRow(&entries[i]))
+		}
+		return output.printRows(auditColumns, rows)
+	}
+	return cmd
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dghubble/oauth1"
+	"github.com/n8henrie/allyapi"
+	"golang.org/x/term"
+)
+
+// OAuth endpoints of an environment, on the host of its API, e.g.
+// https://devapi.invest.ally.com/oauth/request_token for Dev
+func oauthEndpoint(env allyapi.Environment) (oauth1.Endpoint, error) {
+	u, err := url.Parse(env.BaseURL)
+	if err != nil {
+		return oauth1.Endpoint{}, fmt.Errorf("invalid API URL: %v", err)
+	}
+	endpoint := func(path string) string {
+		return (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/oauth/" + path}).String()
+	}
+	return oauth1.Endpoint{
+		RequestTokenURL: endpoint("request_token"),
+		AuthorizeURL:    endpoint("authorize"),
+		AccessTokenURL:  endpoint("access_token"),
+	}, nil
+}
+
+var stdin = bufio.NewReader(os.Stdin)
+
+func prompt(label string) (string, error) {
+	fmt.Fprintf(os.Stderr, "%v: ", label)
+	line, err := stdin.ReadString('\n')
+	if err != nil && !(err == io.EOF && line != "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// Prompt without echoing input when stdin is a terminal
+func promptSecret(label string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return prompt(label)
+	}
+	fmt.Fprintf(os.Stderr, "%v: ", label)
+	b, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// Perform the OAuth 1.0a out-of-band request/authorize/access flow with the
+// environment selected by -env
+func authorize(consumerKey, consumerSecret string) (string, string, error) {
+	env, err := allyapi.EnvironmentByName(*envFlag)
+	if err != nil {
+		return "", "", usageError(err.Error())
+	}
+	endpoint, err := oauthEndpoint(env)
+	if err != nil {
+		return "", "", err
+	}
+	config := oauth1.Config{
+		ConsumerKey:    consumerKey,
+		ConsumerSecret: consumerSecret,
+		CallbackURL:    "oob",
+		Endpoint:       endpoint,
+	}
+
+	requestToken, requestSecret, err := config.RequestToken()
+	if err != nil {
+		return "", "", fmt.Errorf("error getting request token: %v", err)
+	}
+
+	authURL, err := config.AuthorizationURL(requestToken)
+	if err != nil {
+		return "", "", err
+	}
+	fmt.Fprintf(os.Stderr, "Open this URL in your browser and authorize allyapi:\n\n%v\n\n", authURL)
+
+	verifier, err := prompt("Verification code")
+	if err != nil {
+		return "", "", err
+	}
+
+	accessToken, accessSecret, err := config.AccessToken(requestToken, requestSecret, verifier)
+	if err != nil {
+		return "", "", fmt.Errorf("error getting access token: %v", err)
+	}
+	return accessToken, accessSecret, nil
+}
+
+func authSetup(source, path, profile string) error {
+	var creds allyapi.Credentials
+	var err error
+
+	if creds.ConsumerKey, err = prompt("Consumer key"); err != nil {
+		return err
+	}
+	if creds.ConsumerSecret, err = promptSecret("Consumer secret"); err != nil {
+		return err
+	}
+
+	answer, err := prompt("Do you already have an access token and secret? [Y/n]")
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(strings.ToLower(answer), "n") {
+		creds.AccessToken, creds.AccessSecret, err = authorize(creds.ConsumerKey, creds.ConsumerSecret)
+		if err != nil {
+			return err
+		}
+	} else {
+		if creds.AccessToken, err = prompt("Access token"); err != nil {
+			return err
+		}
+		if creds.AccessSecret, err = promptSecret("Access secret"); err != nil {
+			return err
+		}
+	}
+
+	if err := creds.Validate(); err != nil {
+		return err
+	}
+	if err := allyapi.StoreProfileCredentials(source, path, profile, &creds); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "Credentials saved")
+	return nil
+}
+
+func authStoreCommand() *command {
+	cmd := newCommand("store", "auth store -key NAME", "Save one credential, read from a prompt or stdin, to the credential store selected by -creds and -profile")
+	cmd.footer = "NAME is consumer_key, consumer_secret, access_token, or access_secret, e.g.\n" +
+		"  echo \"$SECRET\" | allyapi auth store -key access_secret"
+	key := cmd.flags.String("key", "", "Name of the credential")
+	cmd.completeFlags = map[string]func() []string{
+		"key": completeWords(allyapi.CredentialNames()...),
+	}
+	cmd.run = func(args []string) error {
+		if *key == "" {
+			return usageError("expected -key")
+		}
+		// Checked before asking for the value, though StoreCredential
+		// checks it too
+		if !slices.Contains(allyapi.CredentialNames(), *key) {
+			return usageError(fmt.Sprintf("unknown credential %q", *key))
+		}
+		value, err := promptSecret(*key)
+		if err != nil {
+			return err
+		}
+		if value == "" {
+			return fmt.Errorf("no value given for %v", *key)
+		}
+		if err := allyapi.StoreCredential(*credsFlag, *configFlag, *profileFlag, *key, value); err != nil {
+			return fmt.Errorf("error saving %v: %v", *key, err)
+		}
+		fmt.Fprintf(os.Stderr, "Saved %v\n", *key)
+		return nil
+	}
+	return cmd
+}
+
+func authVerifyCommand() *command {
+	cmd := newCommand("verify", "auth verify", "Check that the credentials work, and show their accounts and the rate limit")
+	cmd.run = func(args []string) error {
+		client := newClient()
+		defer client.Wait()
+
+		// Rejected credentials map to their own exit code
+		member, err := client.Member()
+		if err != nil {
+			return fmt.Errorf("error verifying credentials: %w", err)
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "Credentials:\tvalid\n")
+		for _, a := range member.Account {
+			account := a.Account
+			if a.Nickname != "" {
+				account += " (" + a.Nickname + ")"
+			}
+			fmt.Fprintf(tw, "Account:\t%v\n", account)
+		}
+		if member.Disabled == "true" {
+			fmt.Fprintf(tw, "Disabled:\tyes\n")
+		}
+		if used, remaining, limit, expires := client.RateLimit(); !expires.IsZero() {
+			fmt.Fprintf(tw, "Rate limit:\t%v of %v remaining (%v used), resets %v\n", remaining, limit, used, expires.Format(time.RFC1123))
+		}
+		return tw.Flush()
+	}
+	return cmd
+}
+
+func authCommand() *command {
+	setup := newCommand("setup", "auth setup", "Enter API credentials, or authorize with OAuth, and save them to the credential store selected by -creds and -profile")
+	setup.run = func(args []string) error {
+		if err := authSetup(*credsFlag, *configFlag, *profileFlag); err != nil {
+			return fmt.Errorf("error setting up credentials: %v", err)
+		}
+		return nil
+	}
+
+	cmd := newCommand("auth", "auth COMMAND", "Manage API credentials")
+	cmd.commands = []*command{setup, authStoreCommand(), authVerifyCommand()}
+	return cmd
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/n8henrie/allyapi"
+)
+
+func TestOAuthEndpoint(t *testing.T) {
+	for env, want := range map[allyapi.Environment]string{
+		allyapi.Live: "https://api.invest.ally.com/oauth/request_token",
+		allyapi.Dev:  "https://devapi.invest.ally.com/oauth/request_token",
+	} {
+		endpoint, err := oauthEndpoint(env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if endpoint.RequestTokenURL != want {
+			t.Errorf("request token URL of %v = %v, want %v", env.BaseURL, endpoint.RequestTokenURL, want)
+		}
+	}
+}
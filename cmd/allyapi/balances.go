@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/n8henrie/allyapi"
+)
+
+var balanceColumns = []string{"account", "value", "cash", "market_value", "buying_power"}
+
+func balanceRow(b *allyapi.Balance) map[string]string {
+	return map[string]string{
+		"account":      b.Account,
+		"value":        b.AccountValue.StringFixed(2),
+		"cash":         b.Money.Total.StringFixed(2),
+		"market_value": b.Securities.Total.StringFixed(2),
+		"buying_power": b.BuyingPower.Stock.StringFixed(2),
+	}
+}
+
+func balancesCommand() *command {
+	cmd := newCommand("balances", "balances [flags]", "Show the value, cash, and buying power of an account")
+	output := addOutputFlags(cmd.flags)
+	account := addAccountFlag(cmd.flags)
+	all := cmd.flags.Bool("all", false, "Show every account, with a total")
+
+	cmd.run = func(args []string) error {
+		if err := output.validate(); err != nil {
+			return err
+		}
+
+		client := newClient()
+		defer client.Wait()
+
+		if !*all {
+			id, err := defaultAccount(client, *account)
+			if err != nil {
+				return err
+			}
+			b, err := client.Balances(id)
+			if err != nil {
+				return fmt.Errorf("error getting balances: %v", err)
+			}
+			return output.printRows(balanceColumns, []map[string]string{balanceRow(b)})
+		}
+
+		ids, err := client.AccountIDs()
+		if err != nil {
+			return fmt.Errorf("error getting accounts: %v", err)
+		}
+		balances, err := client.AllBalances(ids)
+		if err != nil {
+			return fmt.Errorf("error getting balances: %v", err)
+		}
+
+		total := &allyapi.Balance{Account: "TOTAL"}
+		var rows []map[string]string
+		for _, b := range balances {
+			total.AccountValue += b.AccountValue
+			total.Money.Total += b.Money.Total
+			total.Securities.Total += b.Securities.Total
+			total.BuyingPower.Stock += b.BuyingPower.Stock
+			rows = append(rows, balanceRow(b))
+		}
+		rows = append(rows, balanceRow(total))
+		return output.printRows(balanceColumns, rows)
+	}
+	return cmd
+}
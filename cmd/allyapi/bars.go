@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+)
+
+// Columns of bars built from streamed trades
+var streamBarColumns = []string{"symbol", "time", "open", "high", "low", "close", "volume"}
+
+func streamBarRow(b *allyapi.SymbolBar) map[string]string {
+	return map[string]string{
+		"symbol": b.Symbol,
+		"time":   b.Time.Format(time.RFC3339),
+		"open":   b.Open.String(),
+		"high":   b.High.String(),
+		"low":    b.Low.String(),
+		"close":  b.Close.String(),
+		"volume": strconv.FormatInt(b.Volume, 10),
+	}
+}
+
+// Builds bars from streamed trades and prints each one once it is complete,
+// as a line of JSON or CSV or through the output template, also writing it
+// to its symbol's file if files is set
+type barPrinter struct {
+	builder *allyapi.BarBuilder
+	output  *outputFlags
+
+	mu    sync.Mutex
+	csv   *csv.Writer
+	files *barFiles
+
+	done    chan struct{}
+	wg      sync.WaitGroup
+	ticking bool
+}
+
+func newBarPrinter(output *outputFlags, interval time.Duration, files *barFiles) (*barPrinter, error) {
+	switch output.format {
+	case "json", "ndjson", "csv":
+	default:
+		return nil, fmt.Errorf("%v output is not supported for bars", output.format)
+	}
+	p := &barPrinter{
+		builder: allyapi.NewBarBuilder(interval),
+		output:  output,
+		files:   files,
+		done:    make(chan struct{}),
+	}
+	if output.format == "csv" && output.tmpl == nil {
+		p.csv = csv.NewWriter(os.Stdout)
+		p.csv.Write(streamBarColumns)
+		p.csv.Flush()
+	}
+	return p, nil
+}
+
+// Complete bars on time even when no later trade arrives. Replays don't, as
+// their trades' times aren't now.
+func (p *barPrinter) tick() {
+	p.ticking = true
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.done:
+				return
+			case now := <-ticker.C:
+				p.print(p.builder.Flush(now)...)
+			}
+		}
+	}()
+}
+
+// Add a streamed trade to its symbol's bar
+func (p *barPrinter) handle(m *allyapi.APIResponse) error {
+	if m.Trade == nil {
+		return nil
+	}
+	if b := p.builder.AddTrade(m.Trade, time.Now()); b != nil {
+		p.print(*b)
+	}
+	return nil
+}
+
+func (p *barPrinter) print(bars ...allyapi.SymbolBar) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := range bars {
+		row := streamBarRow(&bars[i])
+		if p.files != nil {
+			if err := p.files.write(row); err != nil {
+				slog.Warn("error writing bar", "symbol", bars[i].Symbol, "error", err)
+			}
+		}
+		switch {
+		case p.output.tmpl != nil:
+			writeTemplate(os.Stdout, p.output.tmpl, row)
+		case p.csv != nil:
+			fields := make([]string, len(streamBarColumns))
+			for j, c := range streamBarColumns {
+				fields[j] = row[c]
+			}
+			p.csv.Write(fields)
+			p.csv.Flush()
+		default:
+			b, _ := json.Marshal(bars[i])
+			fmt.Println(string(b))
+		}
+	}
+}
+
+// Stop completing bars, printing those whose intervals have ended. Bars still
+// in progress are dropped, except at the end of a replay, where they are
+// printed as they are.
+func (p *barPrinter) close() {
+	close(p.done)
+	p.wg.Wait()
+	if p.ticking {
+		p.print(p.builder.Flush(time.Now())...)
+	} else {
+		p.print(p.builder.FlushAll()...)
+	}
+	if p.files != nil {
+		if err := p.files.close(); err != nil {
+			slog.Warn("error closing bar files", "error", err)
+		}
+	}
+}
+
+// Appends bars to a CSV file for each symbol in a directory, named like
+// AAPL.csv, with the columns of streamBarColumns. Rows are flushed as they
+// are written so that other programs can read the files during the day.
+type barFiles struct {
+	dir     string
+	files   map[string]*os.File
+	writers map[string]*csv.Writer
+}
+
+func newBarFiles(dir string) (*barFiles, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &barFiles{dir: dir, files: make(map[string]*os.File), writers: make(map[string]*csv.Writer)}, nil
+}
+
+// Append a bar to its symbol's file, opening it and, if it's new, writing
+// the header first
+func (f *barFiles) write(row map[string]string) error {
+	symbol := row["symbol"]
+	w := f.writers[symbol]
+	if w == nil {
+		path := filepath.Join(f.dir, strings.ReplaceAll(symbol, string(filepath.Separator), "_")+".csv")
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return err
+		}
+		w = csv.NewWriter(file)
+		if info.Size() == 0 {
+			w.Write(streamBarColumns)
+		}
+		f.files[symbol], f.writers[symbol] = file, w
+	}
+
+	fields := make([]string, len(streamBarColumns))
+	for i, c := range streamBarColumns {
+		fields[i] = row[c]
+	}
+	w.Write(fields)
+	w.Flush()
+	return w.Error()
+}
+
+func (f *barFiles) close() error {
+	var err error
+	for symbol, file := range f.files {
+		if cerr := file.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		delete(f.files, symbol)
+		delete(f.writers, symbol)
+	}
+	return err
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+	"golang.org/x/term"
+)
+
+// Levels of a sparkline, lowest first
+var sparkLevels = []rune("▁▂▃▄▅▆▇█")
+
+// A bar's prices, for charting
+type chartBar struct {
+	open, high, low, close float64
+}
+
+// A chart of bars in columns of a terminal
+type chart struct {
+	symbol   string
+	bars     allyapi.Bars
+	intraday bool
+	color    bool
+}
+
+// Merge consecutive bars so that there are at most width of them
+func (c *chart) columns(width int) []chartBar {
+	n := len(c.bars)
+	if width < 1 {
+		width = 1
+	}
+	cols := make([]chartBar, 0, min(n, width))
+	for i := 0; i < min(n, width); i++ {
+		first, last := i*n/min(n, width), (i+1)*n/min(n, width)
+		col := chartBar{open: c.bars[first].Open.Float64(), close: c.bars[last-1].Close.Float64()}
+		col.high, col.low = c.bars[first].High.Float64(), c.bars[first].Low.Float64()
+		for _, b := range c.bars[first+1 : last] {
+			col.high = max(col.high, b.High.Float64())
+			col.low = min(col.low, b.Low.Float64())
+		}
+		cols = append(cols, col)
+	}
+	return cols
+}
+
+// Range of the bars' prices
+func (c *chart) bounds() (low, high float64) {
+	low, high = c.bars[0].Low.Float64(), c.bars[0].High.Float64()
+	for _, b := range c.bars[1:] {
+		low = min(low, b.Low.Float64())
+		high = max(high, b.High.Float64())
+	}
+	return low, high
+}
+
+func (c *chart) paint(s string, direction int) string {
+	if !c.color {
+		return s
+	}
+	return colorize(s, direction)
+}
+
+func (c *chart) timeLabel(i int) string {
+	t := c.bars[i].Time.In(allyapi.MarketTime)
+	if !c.intraday {
+		return t.Format("2006-01-02")
+	}
+	first, last := c.bars[0].Time.In(allyapi.MarketTime), c.bars[len(c.bars)-1].Time.In(allyapi.MarketTime)
+	if first.YearDay() != last.YearDay() || first.Year() != last.Year() {
+		return t.Format("01-02 15:04")
+	}
+	return t.Format("15:04")
+}
+
+// Write the symbol, period, and change in price over it
+func (c *chart) writeSummary(w io.Writer) {
+	low, high := c.bounds()
+	first, last := c.bars[0].Open.Float64(), c.bars[len(c.bars)-1].Close.Float64()
+	change := last - first
+	direction := compareFloats(last, first)
+	fmt.Fprintf(w, "%v  %v to %v  last %.2f  %s  low %.2f  high %.2f\n",
+		c.symbol, c.timeLabel(0), c.timeLabel(len(c.bars)-1), last,
+		c.paint(fmt.Sprintf("%+.2f (%v)", change, formatPercent(change, first)), direction),
+		low, high)
+}
+
+// Write the closes as a line of block characters
+func (c *chart) writeSparkline(w io.Writer, width int) {
+	cols := c.columns(width)
+	low, high := cols[0].close, cols[0].close
+	for _, col := range cols {
+		low, high = min(low, col.close), max(high, col.close)
+	}
+	var b strings.Builder
+	for i, col := range cols {
+		level := 0
+		if high > low {
+			level = int((col.close - low) / (high - low) * float64(len(sparkLevels)-1))
+		}
+		direction := 0
+		if i > 0 {
+			direction = compareFloats(col.close, cols[i-1].close)
+		}
+		b.WriteString(c.paint(string(sparkLevels[level]), direction))
+	}
+	fmt.Fprintln(w, b.String())
+}
+
+// Write the bars as candlesticks height rows high, with the prices on the
+// left and the first and last times below. Bodies of candles closing up are
+// solid, and those closing down shaded.
+func (c *chart) writeCandles(w io.Writer, width, height int) {
+	low, high := c.bounds()
+	labelWidth := len(fmt.Sprintf("%.2f", high))
+	cols := c.columns(width - labelWidth - 2)
+	step := (high - low) / float64(height)
+	if step == 0 {
+		step = 1
+	}
+
+	for row := 0; row < height; row++ {
+		top := high - float64(row)*step
+		bottom := top - step
+		var label string
+		switch row {
+		case 0:
+			label = fmt.Sprintf("%*.2f", labelWidth, high)
+		case height / 2:
+			label = fmt.Sprintf("%*.2f", labelWidth, (top+bottom)/2)
+		case height - 1:
+			label = fmt.Sprintf("%*.2f", labelWidth, low)
+		default:
+			label = strings.Repeat(" ", labelWidth)
+		}
+
+		var b strings.Builder
+		for _, col := range cols {
+			direction := compareFloats(col.close, col.open)
+			bodyTop, bodyBottom := max(col.open, col.close), min(col.open, col.close)
+			switch {
+			case bodyTop >= bottom && bodyBottom <= top:
+				if direction < 0 {
+					b.WriteString(c.paint("▒", direction))
+				} else {
+					b.WriteString(c.paint("█", direction))
+				}
+			case col.high >= bottom && col.low <= top:
+				b.WriteString(c.paint("│", direction))
+			default:
+				b.WriteByte(' ')
+			}
+		}
+		fmt.Fprintf(w, "%v ┤%v\n", label, b.String())
+	}
+
+	first, last := c.timeLabel(0), c.timeLabel(len(c.bars)-1)
+	fmt.Fprintf(w, "%v └%v\n", strings.Repeat(" ", labelWidth), strings.Repeat("─", len(cols)))
+	gap := max(len(cols)-len(first)-len(last), 1)
+	fmt.Fprintf(w, "%v  %v%v%v\n", strings.Repeat(" ", labelWidth), first, strings.Repeat(" ", gap), last)
+}
+
+// 1 if a is greater than b, -1 if less, or 0
+func compareFloats(a, b float64) int {
+	switch {
+	case a > b:
+		return 1
+	case a < b:
+		return -1
+	}
+	return 0
+}
+
+// Parse a chart's period into its start date: a date as for -from, or a
+// length of time, where months may also be written as e.g. 3mo
+func parsePeriod(s string) (time.Time, error) {
+	date := strings.ToLower(s)
+	if strings.HasSuffix(date, "mo") {
+		date = strings.TrimSuffix(date, "o")
+	}
+	t, err := parseDate(date)
+	if err != nil {
+		return t, usageError(fmt.Sprintf("invalid period %q: use e.g. 5d, 3mo, 1y, or YYYY-MM-DD", s))
+	}
+	return t, nil
+}
+
+func chartCommand() *command {
+	cmd := newCommand("chart", "chart [flags] SYMBOL", "Chart a symbol's prices in the terminal")
+	cmd.footer = "Daily prices are charted since -period ago, or, with -intraday, prices from\n" +
+		"time and sales every -interval. Columns merge bars to fit the width. In\n" +
+		"candlesticks, bodies closing up are solid and those closing down shaded."
+	period := cmd.flags.String("period", "", "Chart prices since this long ago, e.g. 5d, 3mo, or 1y, or since a date (default 3mo, or today with -intraday)")
+	style := cmd.flags.String("style", "candles", "Chart style: candles or sparkline")
+	intraday := cmd.flags.Bool("intraday", false, "Chart time and sales instead of daily prices")
+	interval := cmd.flags.String("interval", "5min", "Interval of each intraday bar: 1min, 5min, or tick")
+	width := cmd.flags.Int("width", 0, "Width of the chart in columns (0 fits the terminal)")
+	height := cmd.flags.Int("height", 15, "Height of candlesticks in rows")
+
+	cmd.run = func(args []string) error {
+		if len(args) != 1 {
+			cmd.printUsage()
+			return usageError("expected one symbol")
+		}
+		switch *style {
+		case "candles", "sparkline":
+		default:
+			return usageError(fmt.Sprintf("invalid style: %q", *style))
+		}
+		switch *interval {
+		case "1min", "5min", "tick":
+		default:
+			return usageError(fmt.Sprintf("invalid interval: %q", *interval))
+		}
+		if *height < 3 {
+			return usageError("-height must be at least 3")
+		}
+		if *period == "" {
+			*period = "3mo"
+			if *intraday {
+				*period = "today"
+			}
+		}
+		from, err := parsePeriod(*period)
+		if err != nil {
+			return err
+		}
+
+		stdout := int(os.Stdout.Fd())
+		if *width <= 0 {
+			*width = 80
+			if w, _, err := term.GetSize(stdout); err == nil && w > 0 {
+				*width = w
+			}
+		}
+
+		client := newClient()
+		defer client.Wait()
+
+		c := &chart{
+			symbol:   strings.ToUpper(args[0]),
+			intraday: *intraday,
+			color:    colorEnabled() && term.IsTerminal(stdout),
+		}
+		if *intraday {
+			c.bars, err = client.TimeSales(c.symbol, *interval, from, time.Time{})
+		} else {
+			c.bars, err = client.HistoricalQuotes(c.symbol, "daily", from, time.Time{})
+		}
+		if err != nil {
+			return fmt.Errorf("error getting prices: %v", err)
+		}
+		if len(c.bars) == 0 {
+			return fmt.Errorf("no prices of %v since %v", c.symbol, from.Format("2006-01-02"))
+		}
+		sort.Slice(c.bars, func(i, j int) bool { return c.bars[i].Time.Before(c.bars[j].Time) })
+
+		c.writeSummary(os.Stdout)
+		if *style == "sparkline" {
+			c.writeSparkline(os.Stdout, *width)
+		} else {
+			c.writeCandles(os.Stdout, *width, *height)
+		}
+		return nil
+	}
+	return cmd
+}
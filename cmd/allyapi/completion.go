@@ -0,0 +1,199 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/n8henrie/allyapi"
+)
+
+const bashCompletion = `_allyapi() {
+	local IFS=$'\n'
+	COMPREPLY=($(allyapi __complete -- "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _allyapi allyapi
+`
+
+const zshCompletion = `#compdef allyapi
+_allyapi() {
+	local -a completions
+	completions=("${(@f)$(allyapi __complete -- "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+	compadd -a completions
+}
+compdef _allyapi allyapi
+`
+
+const fishCompletion = `function __allyapi_complete
+	set -l tokens (commandline -opc) (commandline -ct)
+	allyapi __complete -- $tokens[2..-1] 2>/dev/null
+end
+complete -c allyapi -f -a '(__allyapi_complete)'
+`
+
+// Completions for the values of flags with these names
+var flagCompletions = map[string]func() []string{
+	"account":         completeAccounts,
+	"as":              completeWords("csv", "ofx", "qif"),
+	"watchlist":       completeWatchlists,
+	"creds":           completeWords("auto", "env", "file", "keychain"),
+	"profile":         completeProfiles,
+	"by":              completeWords("symbol", "month"),
+	"env":             completeWords("live", "dev"),
+	"interval":        completeWords("daily", "weekly", "monthly"),
+	"kind":            completeWords("quote", "trade", "snapshot"),
+	"log-format":      completeWords("text", "json"),
+	"log-level":       completeWords("debug", "info", "warn", "error"),
+	"output":          completeWords("table", "csv", "json", "ndjson"),
+	"response-format": completeWords("json", "xml"),
+	"side":            completeWords("buy", "sell", "sell_short", "buy_to_cover"),
+	"tif":             completeWords("day", "gtc", "moc"),
+	"type":            completeWords("market", "limit", "stop", "stop_limit", "trailing_stop"),
+}
+
+func completeWords(words ...string) func() []string {
+	return func() []string { return words }
+}
+
+// Account IDs from the API, if there are credentials, and nicknames from the
+// config file
+func completeAccounts() []string {
+	var ids []string
+	if client, err := tryNewClient(); err == nil {
+		ids, _ = client.AccountIDs()
+	}
+	if cfg, err := loadConfig(); err == nil {
+		for _, nickname := range cfg.Accounts {
+			if nickname != "" {
+				ids = append(ids, nickname)
+			}
+		}
+	}
+	return ids
+}
+
+func completeProfiles() []string {
+	var names []string
+	if cfg, err := allyapi.LoadConfigFile(*configFlag); err == nil {
+		for name := range cfg.Profiles {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Watchlists from the config file and, if there are credentials, the API
+func completeWatchlists() []string {
+	var names []string
+	if client, err := tryNewClient(); err == nil {
+		names, _ = client.Watchlists()
+	}
+	if cfg, err := loadConfig(); err == nil {
+		for name := range cfg.Watchlists {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// Find candidates for the last of words, which are the command line
+// arguments up to and including the word being completed
+func complete(root *command, words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current := words[len(words)-1]
+
+	c := root
+	var pending *flag.Flag
+	for _, w := range words[:len(words)-1] {
+		if pending != nil {
+			pending = nil
+			continue
+		}
+		if strings.HasPrefix(w, "-") {
+			name := strings.TrimLeft(w, "-")
+			if f := c.flags.Lookup(name); f != nil && !strings.Contains(name, "=") && !isBoolFlag(f) {
+				pending = f
+			}
+			continue
+		}
+		if sub := c.find(w); sub != nil {
+			c = sub
+		}
+	}
+
+	var candidates []string
+	switch {
+	case pending != nil:
+		fn, ok := c.completeFlags[pending.Name]
+		if !ok {
+			fn, ok = flagCompletions[pending.Name]
+		}
+		if ok {
+			candidates = fn()
+		}
+	case strings.HasPrefix(current, "-"):
+		c.flags.VisitAll(func(f *flag.Flag) {
+			candidates = append(candidates, "-"+f.Name)
+		})
+	default:
+		for _, sub := range c.commands {
+			if !sub.hidden {
+				candidates = append(candidates, sub.name)
+			}
+		}
+		if c.completeArgs != nil {
+			candidates = append(candidates, c.completeArgs()...)
+		}
+	}
+
+	var matches []string
+	for _, cand := range candidates {
+		if strings.HasPrefix(cand, current) {
+			matches = append(matches, cand)
+		}
+	}
+	return matches
+}
+
+func completionCommand() *command {
+	cmd := newCommand("completion", "completion bash|zsh|fish", "Print a shell completion script")
+	cmd.completeArgs = completeWords("bash", "zsh", "fish")
+	cmd.run = func(args []string) error {
+		if len(args) != 1 {
+			cmd.printUsage()
+			return fmt.Errorf("expected one shell")
+		}
+		switch args[0] {
+		case "bash":
+			fmt.Print(bashCompletion)
+		case "zsh":
+			fmt.Print(zshCompletion)
+		case "fish":
+			fmt.Print(fishCompletion)
+		default:
+			return fmt.Errorf("unsupported shell: %q", args[0])
+		}
+		return nil
+	}
+	return cmd
+}
+
+// Hidden command called by the completion scripts
+func completeCommand(root *command) *command {
+	cmd := newCommand("__complete", "__complete [ARGS...]", "Print completions for a partial command line")
+	cmd.hidden = true
+	cmd.run = func(args []string) error {
+		for _, m := range complete(root, args) {
+			fmt.Println(m)
+		}
+		return nil
+	}
+	return cmd
+}
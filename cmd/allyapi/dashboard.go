@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+)
+
+//go:embed dashboard.html
+var dashboardHTML []byte
+
+// A quote on the dashboard, from its board
+type dashboardQuote struct {
+	Symbol string  `json:"symbol"`
+	Last   float64 `json:"last"`
+	Change float64 `json:"change"`
+	Pct    float64 `json:"pct"`
+	Bid    float64 `json:"bid"`
+	Ask    float64 `json:"ask"`
+	Volume int     `json:"volume"`
+
+	// Direction of the last price move: 1 for up, -1 for down
+	Tick int `json:"tick"`
+}
+
+// The board's quotes, in its order, and the stream's status
+func (b *board) snapshot() ([]dashboardQuote, string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	quotes := make([]dashboardQuote, len(b.symbols))
+	for i, s := range b.symbols {
+		row := b.rows[s]
+		quotes[i] = dashboardQuote{Symbol: s, Last: row.last.Float64(), Bid: row.bid.Float64(), Ask: row.ask.Float64(), Volume: row.volume, Tick: row.tick}
+		if row.prevClose != 0 {
+			change := row.last - row.prevClose
+			quotes[i].Change = change.Float64()
+			quotes[i].Pct = change.Float64() / row.prevClose.Float64() * 100
+		}
+	}
+	return quotes, b.status
+}
+
+// Serves the dashboard's page and the data it shows: quotes kept up to date
+// by the stream, and the account's positions and open orders
+type dashboardServer struct {
+	*apiServer
+	board *board
+}
+
+func (s *dashboardServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleIndex)
+	mux.HandleFunc("/api/quotes", s.handleBoard)
+	mux.HandleFunc("/api/positions", s.handlePositions)
+	mux.HandleFunc("/api/orders", s.handleOpenOrders)
+	mux.HandleFunc("/events", s.handleEvents)
+	return s.checkHost(mux)
+}
+
+func (s *dashboardServer) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(dashboardHTML)
+}
+
+// GET /api/quotes
+func (s *dashboardServer) handleBoard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	quotes, status := s.board.snapshot()
+	writeJSONResponse(w, http.StatusOK, map[string]interface{}{"quotes": quotes, "status": status})
+}
+
+// GET /api/orders[?account=ID], the orders that can still fill, newest first
+func (s *dashboardServer) handleOpenOrders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	account := r.URL.Query().Get("account")
+	if account == "" {
+		account = s.account
+	}
+	orders, err := s.client.Orders(account)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	open := []allyapi.OrderStatus{}
+	for _, o := range orders {
+		if !o.Done() {
+			open = append(open, o)
+		}
+	}
+	sort.SliceStable(open, func(i, j int) bool { return open[i].Time.After(open[j].Time) })
+	writeJSONResponse(w, http.StatusOK, open)
+}
+
+func dashboardCommand() *command {
+	cmd := newCommand("dashboard", "dashboard [flags] [SYMBOL...]", "Serve a web dashboard of live quotes, positions, and open orders")
+	cmd.footer = "Quotes of the symbols given, which may include @WATCHLIST, and of those\n" +
+		"held in the account are streamed live. Positions and open orders are\n" +
+		"refreshed every 30 seconds while the page is open. Other symbols can be\n" +
+		"streamed as server-sent events from /events?symbols=A,B, as by serve.\n\n" +
+		"The dashboard has no authentication of its own, so only listen on\n" +
+		"addresses that untrusted users can't reach."
+	listen := cmd.flags.String("listen", "127.0.0.1:8080", "Address to serve the dashboard on")
+	account := addAccountFlag(cmd.flags)
+
+	cmd.run = func(args []string) error {
+		symbols, err := expandWatchlists(parseSymbols(args))
+		if err != nil {
+			return err
+		}
+
+		client := newClient()
+		defer client.Wait()
+
+		id, err := defaultAccount(client, *account)
+		if err != nil {
+			return err
+		}
+		holdings, err := client.Holdings(id)
+		if err != nil {
+			return fmt.Errorf("error getting holdings: %v", err)
+		}
+		positions, err := holdings.Positions()
+		if err != nil {
+			return err
+		}
+		seen := make(map[string]bool)
+		for _, s := range symbols {
+			seen[s] = true
+		}
+		var held []string
+		for sym, qty := range positions {
+			if qty != 0 && !seen[sym] {
+				held = append(held, sym)
+			}
+		}
+		sort.Strings(held)
+		symbols = append(symbols, held...)
+
+		b := newBoard(symbols)
+		if len(symbols) > 0 {
+			quotes, err := client.GetQuotes(symbols, watchFields)
+			if err != nil {
+				return fmt.Errorf("error getting quotes: %v", err)
+			}
+			if quotes.Response.Quotes != nil {
+				b.seed(quotes.Response.Quotes.Quote)
+			}
+			messages, err := client.Stream(rootCtx, symbols)
+			if err != nil {
+				return fmt.Errorf("error streaming quotes: %v", err)
+			}
+			go func() {
+				for m := range messages {
+					if m.Err != nil {
+						slog.Error("error streaming quotes", "error", m.Err)
+					}
+					b.update(m)
+				}
+			}()
+		}
+
+		l, err := net.Listen("tcp", *listen)
+		if err != nil {
+			return err
+		}
+		s := &dashboardServer{apiServer: &apiServer{client: client, account: id, host: listenHost(*listen)}, board: b}
+		srv := &http.Server{Handler: s.handler(), ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			<-rootCtx.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			srv.Shutdown(ctx)
+		}()
+		slog.Info("serving dashboard", "addr", l.Addr().String(), "account", id)
+		if err := srv.Serve(l); err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+)
+
+// Where the record of each plan's buys is kept
+func dcaStatePath() string {
+	return filepath.Join(allyapi.DefaultStateDir(), "dca.json")
+}
+
+// The plans in the config file, sorted by name, or just those named
+func loadDCAPlans(names []string) (map[string]allyapi.DCAPlan, []string, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading config file: %v", err)
+	}
+	if len(names) == 0 {
+		for name := range cfg.DCA {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	for _, name := range names {
+		p, ok := cfg.DCA[name]
+		if !ok {
+			return nil, nil, usageError(fmt.Sprintf("unknown plan: %q", name))
+		}
+		if err := p.Validate(); err != nil {
+			return nil, nil, fmt.Errorf("plan %v: %v", name, err)
+		}
+	}
+	return cfg.DCA, names, nil
+}
+
+var dcaColumns = []string{"name", "symbol", "amount", "qty", "type", "schedule", "last", "next", "buys", "invested"}
+
+func dcaListCommand() *command {
+	cmd := newCommand("list", "dca list [flags]", "List recurring buys and when each is next due")
+	output := addOutputFlags(cmd.flags)
+	cmd.run = func(args []string) error {
+		if err := output.validate(); err != nil {
+			return err
+		}
+		plans, names, err := loadDCAPlans(nil)
+		if err != nil {
+			return err
+		}
+		state, err := allyapi.LoadDCAState(dcaStatePath())
+		if err != nil {
+			return fmt.Errorf("error reading DCA state: %v", err)
+		}
+
+		var rows []map[string]string
+		for _, name := range names {
+			p, run := plans[name], state[name]
+			row := map[string]string{
+				"name":     name,
+				"symbol":   strings.ToUpper(p.Symbol),
+				"type":     p.Type,
+				"schedule": p.Schedule,
+				"buys":     strconv.Itoa(run.Buys),
+				"invested": run.Invested.StringFixed(2),
+				"next":     "now",
+			}
+			if row["type"] == "" {
+				row["type"] = "market"
+			}
+			if p.Amount > 0 {
+				row["amount"] = p.Amount.StringFixed(2)
+			} else {
+				row["qty"] = strconv.Itoa(p.Quantity)
+			}
+			if !run.Last.IsZero() {
+				row["last"] = run.Last.In(allyapi.MarketTime).Format("2006-01-02 15:04")
+				next, _ := p.Next(run.Last)
+				row["next"] = next.Format("2006-01-02")
+			}
+			if run.Pending != nil {
+				row["next"] = "pending"
+			}
+			rows = append(rows, row)
+		}
+		return output.printRows(dcaColumns, rows)
+	}
+	return cmd
+}
+
+func dcaRunCommand() *command {
+	cmd := newCommand("run", "dca run [flags] [NAME...]", "Place the recurring buys that are due")
+	cmd.footer = "Plans are configured in the config file, e.g.\n" +
+		"  [dca.vti]\n" +
+		"  symbol = \"VTI\"\n" +
+		"  amount = 250        # or quantity = 2\n" +
+		"  schedule = \"weekly\" # daily, weekly, biweekly, or monthly\n" +
+		"  type = \"limit\"      # market by default\n" +
+		"  limit_pct = 0.5     # limit at the ask plus 0.5%\n\n" +
+		"Each buy is asked about before it is placed unless -yes is given. Run it\n" +
+		"daily from cron or launchd with -yes, or keep it running with -poll. When a\n" +
+		"buy was last placed is kept in " + dcaStatePath() + ",\n" +
+		"so plans only buy once per period however often this is run. -dry-run\n" +
+		"and -preview don't count as buys.\n\n" +
+		"A buy is marked pending there before it is placed. If it can't be\n" +
+		"recorded after, the plan won't buy again, and -poll stops, until the\n" +
+		"account's orders are checked and the \"pending\" entry removed."
+	account := addAccountFlag(cmd.flags)
+	yes := cmd.flags.Bool("yes", false, "Place the buys without asking")
+	now := cmd.flags.Bool("now", false, "Buy now even if the plans aren't due")
+	preview := cmd.flags.Bool("preview", false, "Preview the buys instead of placing them")
+	poll := cmd.flags.Duration("poll", 0, "Keep running, checking for due buys at this interval, e.g. 1h (0 checks once)")
+	pf := addPlaceFlags(cmd.flags)
+	cmd.completeArgs = func() []string {
+		_, names, _ := loadDCAPlans(nil)
+		return names
+	}
+
+	cmd.run = func(args []string) error {
+		if *poll < 0 {
+			return usageError("-poll must not be negative")
+		}
+		plans, names, err := loadDCAPlans(args)
+		if err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			return errors.New("no recurring buys in the config file; see allyapi help dca run")
+		}
+
+		client := newClient()
+		defer client.Wait()
+		pf.guard(client)
+
+		for {
+			for _, name := range names {
+				p := plans[name]
+				if err := runDCAPlan(client, name, &p, *account, pf, *now, *yes, *preview); err != nil {
+					if *poll == 0 || errors.Is(err, errDCAUnrecorded) {
+						return err
+					}
+					slog.Error("error running recurring buy", "plan", name, "error", err)
+				}
+			}
+			if *poll == 0 || !sleep(*poll) {
+				return nil
+			}
+		}
+	}
+	return cmd
+}
+
+// Returned when a buy may have been placed but couldn't be recorded, so that
+// polling stops rather than buying again
+var errDCAUnrecorded = errors.New("buy not recorded")
+
+// Place a plan's buy if it is due, or if force is set, and record it. The
+// buy is marked pending in the state before it is placed, so that one placed
+// without being recorded isn't placed again.
+func runDCAPlan(client *allyapi.Client, name string, p *allyapi.DCAPlan, account string, pf *placeFlags, force, yes, preview bool) error {
+	path := dcaStatePath()
+	state, err := allyapi.LoadDCAState(path)
+	if err != nil {
+		return fmt.Errorf("error reading DCA state: %v", err)
+	}
+	run := state[name]
+	if run.Pending != nil {
+		return fmt.Errorf("plan %v: a buy started at %v may have been placed without being recorded; check the account's orders, then remove the plan's \"pending\" entry from %v", name, run.Pending.Format(time.DateTime), path)
+	}
+	if due, err := p.Due(run.Last, time.Now()); err != nil {
+		return err
+	} else if !due && !force {
+		return nil
+	}
+
+	if p.Account != "" && account == "" {
+		account = p.Account
+	}
+	if account, err = defaultAccount(client, account); err != nil {
+		return err
+	}
+	o, err := client.DCAOrder(p, account)
+	if err != nil {
+		return fmt.Errorf("plan %v: %v", name, err)
+	}
+	cost, err := client.OrderCost(o)
+	if err != nil {
+		return fmt.Errorf("error estimating order cost: %v", err)
+	}
+	desc := fmt.Sprintf("Buy %v %v at %v (about %v) for plan %v", o.Quantity, o.Symbol, o.Type, cost.StringFixed(2), name)
+	if o.Type == "limit" {
+		desc = fmt.Sprintf("Buy %v %v at a limit of %v (about %v) for plan %v", o.Quantity, o.Symbol, o.Price.StringFixed(2), cost.StringFixed(2), name)
+	}
+
+	if preview {
+		fmt.Println(desc)
+		resp, err := client.PreviewOrder(o)
+		if errors.Is(err, allyapi.ErrDryRun) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("error previewing order: %w", err)
+		}
+		return printResponse(resp)
+	}
+	if !yes && !*dryRunFlag {
+		answer, err := prompt(desc + "? [y/N]")
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if !strings.HasPrefix(strings.ToLower(answer), "y") {
+			return nil
+		}
+	} else {
+		fmt.Println(desc)
+	}
+
+	if err := pf.checkBuyingPower(func(allowMargin bool) error { return client.CheckBuyingPower(o, allowMargin) }); err != nil {
+		return err
+	}
+
+	pending := run
+	started := time.Now()
+	pending.Pending = &started
+	state[name] = pending
+	if err := state.Save(path); err != nil {
+		return fmt.Errorf("error saving DCA state: %v", err)
+	}
+
+	// The buy is no longer pending once placed, or once the API or -dry-run
+	// has refused it; after other errors it may have been placed
+	resp, err := client.PlaceOrder(o)
+	var apiErr *allyapi.APIError
+	switch {
+	case err == nil:
+		run.Last, run.OrderID = time.Now(), resp.Response.ClientOrderID
+		run.Buys++
+		run.Invested += cost
+	case errors.Is(err, allyapi.ErrDryRun), errors.As(err, &apiErr):
+	default:
+		return fmt.Errorf("%w: error placing order: %w", errDCAUnrecorded, err)
+	}
+	state[name] = run
+	if saveErr := state.Save(path); saveErr != nil {
+		if err == nil {
+			printResponse(resp)
+		}
+		return fmt.Errorf("%w: error saving DCA state: %v", errDCAUnrecorded, saveErr)
+	}
+	switch {
+	case errors.Is(err, allyapi.ErrDryRun):
+		return nil
+	case err != nil:
+		return fmt.Errorf("error placing order: %w", err)
+	}
+	return printResponse(resp)
+}
+
+func dcaCommand() *command {
+	cmd := newCommand("dca", "dca COMMAND [flags]", "Dollar-cost average with recurring buys")
+	cmd.commands = []*command{
+		dcaListCommand(),
+		dcaRunCommand(),
+	}
+	return cmd
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/n8henrie/allyapi"
+)
+
+var projectedDividendColumns = []string{"symbol", "qty", "annual_div", "income", "yield"}
+
+// Sum dividend income by key, returning the keys in order
+func sumDividends(txs allyapi.Transactions, year int, key func(*allyapi.Transaction) string) ([]string, map[string]allyapi.Decimal) {
+	sums := make(map[string]allyapi.Decimal)
+	for i := range txs {
+		t := &txs[i]
+		if !strings.EqualFold(t.Activity, "dividend") {
+			continue
+		}
+		when, err := t.Time()
+		if err != nil || (year != 0 && when.Year() != year) {
+			continue
+		}
+		sums[key(t)] += t.Amount
+	}
+
+	keys := make([]string, 0, len(sums))
+	for k := range sums {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, sums
+}
+
+func dividendSymbol(t *allyapi.Transaction) string {
+	if t.Symbol != "" {
+		return strings.ToUpper(t.Symbol)
+	}
+	return strings.ToUpper(t.Transaction.Security.Sym)
+}
+
+func dividendMonth(t *allyapi.Transaction) string {
+	when, _ := t.Time()
+	return when.Format("2006-01")
+}
+
+// Project a year of dividends from current holdings and each symbol's
+// indicated annual dividend
+func projectDividends(client *allyapi.Client, holdings allyapi.Holdings) ([]map[string]string, error) {
+	var symbols []string
+	qty := make(map[string]allyapi.Decimal)
+	for _, h := range holdings {
+		sym := strings.ToUpper(h.Instrument.Sym)
+		if _, ok := qty[sym]; !ok {
+			symbols = append(symbols, sym)
+		}
+		qty[sym] += parseDecimal(h.Qty)
+	}
+	if len(symbols) == 0 {
+		return nil, nil
+	}
+
+	resp, err := client.GetQuotes(symbols, []string{"symbol", "last", "iad"})
+	if err != nil {
+		return nil, fmt.Errorf("error getting quotes: %v", err)
+	}
+
+	var rows []map[string]string
+	var total, value allyapi.Decimal
+	if resp.Response.Quotes != nil {
+		for _, q := range resp.Response.Quotes.Quote {
+			sym := q["symbol"]
+			iad, last := parseDecimal(q["iad"]), parseDecimal(q["last"])
+			income := iad.Mul(qty[sym])
+			total += income
+			value += last.Mul(qty[sym])
+			rows = append(rows, map[string]string{
+				"symbol":     sym,
+				"qty":        qty[sym].String(),
+				"annual_div": formatMoney(iad),
+				"income":     formatMoney(income),
+				"yield":      formatPercent(iad.Float64(), last.Float64()),
+			})
+		}
+	}
+	rows = append(rows, map[string]string{
+		"symbol": "TOTAL",
+		"income": formatMoney(total),
+		"yield":  formatPercent(total.Float64(), value.Float64()),
+	})
+	return rows, nil
+}
+
+func dividendsCommand() *command {
+	cmd := newCommand("dividends", "dividends [flags]", "Report dividend income by symbol or month, or project it from current holdings")
+	output := addOutputFlags(cmd.flags)
+	account := addAccountFlag(cmd.flags)
+	pageSize := addPageSizeFlag(cmd.flags)
+	by := cmd.flags.String("by", "symbol", "Group received dividends by symbol or month")
+	year := cmd.flags.Int("year", 0, "Only include dividends received in this year")
+	projected := cmd.flags.Bool("projected", false, "Project annual income and yield from current holdings instead")
+
+	cmd.run = func(args []string) error {
+		if err := output.validate(); err != nil {
+			return err
+		}
+		var key func(*allyapi.Transaction) string
+		switch *by {
+		case "symbol":
+			key = dividendSymbol
+		case "month":
+			key = dividendMonth
+		default:
+			return fmt.Errorf("invalid grouping: %q", *by)
+		}
+
+		client := newClient()
+		defer client.Wait()
+
+		id, err := defaultAccount(client, *account)
+		if err != nil {
+			return err
+		}
+
+		if *projected {
+			holdings, err := client.Holdings(id)
+			if err != nil {
+				return fmt.Errorf("error getting holdings: %v", err)
+			}
+			rows, err := projectDividends(client, holdings)
+			if err != nil {
+				return err
+			}
+			return output.printRows(projectedDividendColumns, rows)
+		}
+
+		txs, err := getHistory(client, id, "all", *pageSize)
+		if err != nil {
+			return err
+		}
+		keys, sums := sumDividends(txs, *year, key)
+
+		var rows []map[string]string
+		var total allyapi.Decimal
+		for _, k := range keys {
+			total += sums[k]
+			rows = append(rows, map[string]string{*by: k, "income": formatMoney(sums[k])})
+		}
+		rows = append(rows, map[string]string{*by: "TOTAL", "income": formatMoney(total)})
+		return output.printRows([]string{*by, "income"}, rows)
+	}
+	return cmd
+}
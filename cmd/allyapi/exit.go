@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/n8henrie/allyapi"
+)
+
+// Exit codes, so that scripts can tell failures apart
+const (
+	exitOK            = 0
+	exitError         = 1
+	exitUsage         = 2
+	exitAuth          = 3
+	exitRateLimit     = 4
+	exitAPIError      = 5
+	exitInvalidSymbol = 6
+	exitRefused       = 7
+	exitInterrupted   = 130
+)
+
+const exitCodeHelp = `Exit status:
+  0  success
+  1  other error
+  2  invalid usage
+  3  missing or rejected credentials
+  4  rate limit reached, or requests paused after repeated failures
+  5  error returned by the API
+  6  invalid symbol
+  7  order refused as a duplicate or for lack of buying power
+  130  interrupted by SIGINT or SIGTERM`
+
+// Errors that map to exit codes but don't come from the API
+var (
+	errCredentials   = errors.New("credentials")
+	errInvalidSymbol = errors.New("invalid symbol")
+)
+
+// An invalid command line
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+func exitCode(err error) int {
+	var apiErr *allyapi.APIError
+	var usageErr usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.Is(err, errCredentials), errors.Is(err, allyapi.ErrUnauthorized):
+		return exitAuth
+	case errors.Is(err, allyapi.ErrRateLimited), errors.Is(err, allyapi.ErrCircuitOpen):
+		return exitRateLimit
+	case errors.Is(err, errInvalidSymbol):
+		return exitInvalidSymbol
+	case errors.Is(err, allyapi.ErrDuplicateOrder), errors.Is(err, allyapi.ErrInsufficientBuyingPower):
+		return exitRefused
+	case errors.As(err, &apiErr):
+		return exitAPIError
+	}
+	return exitError
+}
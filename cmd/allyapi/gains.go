@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+)
+
+var gainsColumns = []string{"kind", "period", "symbol", "qty", "cost", "value", "gain", "gain_pct", "short_term", "long_term"}
+
+// Gains of lots realized in a period or held in a position, split by term
+type gains struct {
+	qty, cost, value    allyapi.Decimal
+	shortTerm, longTerm allyapi.Decimal
+
+	// Set for a position without a quote, which has no value
+	unpriced bool
+}
+
+func (g *gains) add(qty, cost, value allyapi.Decimal, term string) {
+	g.qty += qty
+	g.cost += cost
+	g.value += value
+	if term == "long" {
+		g.longTerm += value - cost
+	} else {
+		g.shortTerm += value - cost
+	}
+}
+
+func (g *gains) row(kind, period, symbol string) map[string]string {
+	row := map[string]string{
+		"kind":   kind,
+		"period": period,
+		"symbol": symbol,
+		"cost":   formatMoney(g.cost),
+	}
+	if symbol != "" && symbol != "TOTAL" {
+		row["qty"] = g.qty.String()
+	}
+	if !g.unpriced {
+		row["value"] = formatMoney(g.value)
+		row["gain"] = formatMoney(g.value - g.cost)
+		row["gain_pct"] = formatPercent((g.value - g.cost).Float64(), g.cost.Float64())
+		row["short_term"] = formatMoney(g.shortTerm)
+		row["long_term"] = formatMoney(g.longTerm)
+	}
+	return row
+}
+
+// Name of the period that t is in: its month, quarter, or year
+func gainsPeriod(t time.Time, period string) string {
+	switch period {
+	case "quarter":
+		return fmt.Sprintf("%v-Q%v", t.Year(), (int(t.Month())+2)/3)
+	case "year":
+		return strconv.Itoa(t.Year())
+	}
+	return t.Format("2006-01")
+}
+
+// Add the gains of open lots, valued at prices, to those of their symbols,
+// and return the total. Symbols without a price are marked unpriced, and
+// left out of the total.
+func addUnrealized(bySymbol map[string]*gains, open []lot, prices map[string]allyapi.Decimal, now time.Time) gains {
+	var total gains
+	for _, l := range open {
+		if l.qty <= 0 {
+			continue
+		}
+		g := bySymbol[l.symbol]
+		price, ok := prices[l.symbol]
+		if !ok {
+			g.unpriced = true
+			g.qty += l.qty
+			g.cost += l.cost
+			continue
+		}
+
+		value := price.Mul(l.qty).MulInt(allyapi.Multiplier(l.symbol))
+		term := holdingTerm(l.acquired, now)
+		g.add(l.qty, l.cost, value, term)
+		total.add(l.qty, l.cost, value, term)
+	}
+	return total
+}
+
+func gainsCommand() *command {
+	cmd := newCommand("gains", "gains [flags]", "Show realized gains by period and unrealized gains by position")
+	cmd.footer = "Sales are matched to the earliest purchases of each symbol, as by taxlots.\n" +
+		"Realized gains are those of lots sold from -from to -to, and unrealized\n" +
+		"gains those of the lots still open, valued at the latest quotes. Gains are\n" +
+		"long term for lots held more than a year. Positions without a quote are\n" +
+		"shown without a value and left out of the unrealized total."
+	output := addOutputFlags(cmd.flags)
+	account := addAccountFlag(cmd.flags)
+	pageSize := addPageSizeFlag(cmd.flags)
+	period := cmd.flags.String("period", "month", "Group realized gains by month, quarter, or year")
+	fromFlag := cmd.flags.String("from", "", "Show gains realized on or after this date, e.g. 2024-01-01, ytd, or 90d")
+	toFlag := cmd.flags.String("to", "", "Show gains realized on or before this date")
+
+	cmd.run = func(args []string) error {
+		switch *period {
+		case "month", "quarter", "year":
+		default:
+			return usageError(fmt.Sprintf("invalid period: %q", *period))
+		}
+		from, to, err := parseDateRange(*fromFlag, *toFlag)
+		if err != nil {
+			return err
+		}
+		if err := output.validate(); err != nil {
+			return err
+		}
+
+		client := newClient()
+		defer client.Wait()
+
+		id, err := defaultAccount(client, *account)
+		if err != nil {
+			return err
+		}
+		txs, err := getHistory(client, id, "trade", *pageSize)
+		if err != nil {
+			return err
+		}
+		open, realized := matchLots(txs)
+
+		// Realized gains by period, oldest first
+		byPeriod := make(map[string]*gains)
+		var periods []string
+		var realizedTotal gains
+		for _, l := range realized {
+			sold := l.sold.In(allyapi.MarketTime)
+			if !from.IsZero() && sold.Before(from) || !to.IsZero() && !sold.Before(to.AddDate(0, 0, 1)) {
+				continue
+			}
+			p := gainsPeriod(sold, *period)
+			if byPeriod[p] == nil {
+				byPeriod[p] = &gains{}
+				periods = append(periods, p)
+			}
+			term := holdingTerm(l.acquired, l.sold)
+			byPeriod[p].add(l.qty, l.cost, l.proceeds, term)
+			realizedTotal.add(l.qty, l.cost, l.proceeds, term)
+		}
+		sort.Strings(periods)
+
+		// Unrealized gains by position, valued at the latest quotes
+		bySymbol := make(map[string]*gains)
+		var symbols []string
+		for _, l := range open {
+			if l.qty <= 0 {
+				continue
+			}
+			if bySymbol[l.symbol] == nil {
+				bySymbol[l.symbol] = &gains{}
+				symbols = append(symbols, l.symbol)
+			}
+		}
+		sort.Strings(symbols)
+		prices := make(map[string]allyapi.Decimal)
+		if len(symbols) > 0 {
+			resp, err := client.GetQuotes(symbols, []string{"symbol", "last"})
+			if err != nil {
+				return fmt.Errorf("error getting quotes: %v", err)
+			}
+			if resp.Response.Quotes != nil {
+				for _, q := range resp.Response.Quotes.Quote {
+					if last := parseDecimal(q["last"]); last > 0 {
+						prices[strings.ToUpper(q["symbol"])] = last
+					}
+				}
+			}
+		}
+		unrealizedTotal := addUnrealized(bySymbol, open, prices, time.Now())
+		var unpriced []string
+		for _, sym := range symbols {
+			if bySymbol[sym].unpriced {
+				unpriced = append(unpriced, sym)
+			}
+		}
+		if len(unpriced) > 0 {
+			slog.Warn("no quote for some positions, which are left out of the total", "symbols", strings.Join(unpriced, ","))
+		}
+
+		totals := output.format == "table" || output.format == "csv"
+		var rows []map[string]string
+		for _, p := range periods {
+			rows = append(rows, byPeriod[p].row("realized", p, ""))
+		}
+		if totals && len(periods) > 0 {
+			rows = append(rows, realizedTotal.row("realized", "TOTAL", ""))
+		}
+		for _, sym := range symbols {
+			rows = append(rows, bySymbol[sym].row("unrealized", "", sym))
+		}
+		if totals && len(symbols) > 0 {
+			rows = append(rows, unrealizedTotal.row("unrealized", "", "TOTAL"))
+		}
+		return output.printRows(gainsColumns, rows)
+	}
+	return cmd
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+)
+
+func TestAddUnrealized(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, allyapi.MarketTime)
+	open := []lot{
+		{symbol: "AAPL", acquired: now.AddDate(-2, 0, 0), qty: parseDecimal("10"), cost: parseDecimal("1500")},
+		{symbol: "AAPL", acquired: now.AddDate(0, -1, 0), qty: parseDecimal("5"), cost: parseDecimal("950")},
+		{symbol: "AAPL250117C00200000", acquired: now.AddDate(0, -1, 0), qty: parseDecimal("2"), cost: parseDecimal("600.65")},
+		{symbol: "DELISTED", acquired: now.AddDate(0, -1, 0), qty: parseDecimal("100"), cost: parseDecimal("250")},
+	}
+	bySymbol := map[string]*gains{"AAPL": {}, "AAPL250117C00200000": {}, "DELISTED": {}}
+	prices := map[string]allyapi.Decimal{"AAPL": parseDecimal("200"), "AAPL250117C00200000": parseDecimal("4.5")}
+
+	total := addUnrealized(bySymbol, open, prices, now)
+
+	aapl := bySymbol["AAPL"].row("unrealized", "", "AAPL")
+	if aapl["qty"] != "15" || aapl["value"] != "3000.00" || aapl["long_term"] != "500.00" || aapl["short_term"] != "50.00" {
+		t.Errorf("AAPL = %v", aapl)
+	}
+
+	// Two contracts at 4.50 a share are worth 900
+	option := bySymbol["AAPL250117C00200000"].row("unrealized", "", "AAPL250117C00200000")
+	if option["value"] != "900.00" || option["gain"] != "299.35" {
+		t.Errorf("option = %v, want a value of 900.00 and a gain of 299.35", option)
+	}
+
+	delisted := bySymbol["DELISTED"].row("unrealized", "", "DELISTED")
+	if _, ok := delisted["value"]; ok || delisted["cost"] != "250.00" {
+		t.Errorf("unquoted position = %v, want a cost and no value", delisted)
+	}
+	if row := total.row("unrealized", "", "TOTAL"); row["cost"] != "3050.65" || row["value"] != "3900.00" {
+		t.Errorf("total = %v, want the quoted positions' cost and value only", row)
+	}
+}
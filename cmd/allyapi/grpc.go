@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/n8henrie/allyapi"
+	"github.com/n8henrie/allyapi/allyapipb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Serves the gRPC interface in allyapipb/allyapi.proto with one client
+type grpcServer struct {
+	allyapipb.UnimplementedAllyServer
+	client  *allyapi.Client
+	account string
+}
+
+// Convert an error to a gRPC status with the code that best matches its cause
+func grpcError(err error) error {
+	code := codes.Unknown
+	switch {
+	case errors.Is(err, allyapi.ErrRateLimited):
+		code = codes.ResourceExhausted
+	case errors.Is(err, allyapi.ErrCircuitOpen):
+		code = codes.Unavailable
+	case errors.Is(err, allyapi.ErrUnauthorized):
+		code = codes.Unauthenticated
+	case errors.Is(err, allyapi.ErrDuplicateOrder):
+		code = codes.AlreadyExists
+	case errors.Is(err, allyapi.ErrDryRun):
+		code = codes.FailedPrecondition
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	}
+	return status.Error(code, err.Error())
+}
+
+func (s *grpcServer) GetQuotes(ctx context.Context, req *allyapipb.QuotesRequest) (*allyapipb.QuotesResponse, error) {
+	symbols, err := expandWatchlists(parseSymbols(req.Symbols))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if len(symbols) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no symbols given")
+	}
+	resp, err := s.client.GetQuotes(symbols, parseFields(strings.Join(req.Fields, ",")))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	quotes := &allyapipb.QuotesResponse{}
+	if resp.Response.Quotes != nil {
+		for _, q := range resp.Response.Quotes.Quote {
+			quotes.Quotes = append(quotes.Quotes, &allyapipb.Quote{Fields: q})
+		}
+	}
+	return quotes, nil
+}
+
+func (s *grpcServer) StreamQuotes(req *allyapipb.StreamRequest, stream allyapipb.Ally_StreamQuotesServer) error {
+	symbols, err := expandWatchlists(parseSymbols(req.Symbols))
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if len(symbols) == 0 {
+		return status.Error(codes.InvalidArgument, "no symbols given")
+	}
+	ch, err := s.client.Stream(stream.Context(), symbols)
+	if err != nil {
+		return grpcError(err)
+	}
+
+	for m := range ch {
+		msg := &allyapipb.StreamMessage{}
+		switch m.Event {
+		case allyapi.QuoteEvent:
+			msg.Message = &allyapipb.StreamMessage_Quote{Quote: &allyapipb.StreamQuote{
+				Symbol:    m.Quote.Symbol,
+				Bid:       m.Quote.Bid.String(),
+				Ask:       m.Quote.Ask.String(),
+				BidSize:   int64(m.Quote.Bidsz),
+				AskSize:   int64(m.Quote.Asksz),
+				Timestamp: m.Quote.Timestamp,
+			}}
+		case allyapi.TradeEvent:
+			msg.Message = &allyapipb.StreamMessage_Trade{Trade: &allyapipb.StreamTrade{
+				Symbol:           m.Trade.Symbol,
+				Last:             m.Trade.Last.String(),
+				Volume:           int64(m.Trade.Vl),
+				CumulativeVolume: int64(m.Trade.Cvol),
+				Vwap:             m.Trade.Vwap.String(),
+				Timestamp:        m.Trade.Timestamp,
+			}}
+		default:
+			if m.Err != nil {
+				return grpcError(m.Err)
+			}
+			msg.Message = &allyapipb.StreamMessage_Status{Status: m.Status}
+		}
+		if err := stream.Send(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Convert an order from the protocol, using the server's account by default
+func (s *grpcServer) order(o *allyapipb.Order) (*allyapi.Order, error) {
+	order := &allyapi.Order{
+		Account:      o.Account,
+		Symbol:       o.Symbol,
+		Side:         o.Side,
+		Type:         o.Type,
+		Quantity:     int(o.Quantity),
+		TrailPercent: o.TrailPercent,
+		TimeInForce:  o.TimeInForce,
+	}
+	if order.Account == "" {
+		order.Account = s.account
+	}
+	for _, p := range []struct {
+		name string
+		s    string
+		d    *allyapi.Decimal
+	}{{"price", o.Price, &order.Price}, {"stop_price", o.StopPrice, &order.StopPrice}, {"trail_amount", o.TrailAmount, &order.TrailAmount}} {
+		var err error
+		if *p.d, err = allyapi.ParseDecimal(p.s); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid %v: %v", p.name, err)
+		}
+	}
+	return order, nil
+}
+
+func (s *grpcServer) submitOrder(o *allyapipb.Order, submit func(*allyapi.Order) (*allyapi.APIResponse, error)) (*allyapipb.OrderResponse, error) {
+	order, err := s.order(o)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := submit(order)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	r := resp.Response.OrderResponse
+	out := &allyapipb.OrderResponse{
+		ClientOrderId:     r.ClientOrderID,
+		OrderStatus:       r.OrderStatus,
+		EstCommission:     r.EstCommission.String(),
+		Principal:         r.Principal.String(),
+		SecFee:            r.SecFee.String(),
+		MarginRequirement: r.MarginRequirement.String(),
+		NetAmount:         r.NetAmt.String(),
+	}
+	if r.Warning != nil {
+		out.Warning = r.Warning.WarningText
+	}
+	return out, nil
+}
+
+func (s *grpcServer) PreviewOrder(ctx context.Context, o *allyapipb.Order) (*allyapipb.OrderResponse, error) {
+	return s.submitOrder(o, s.client.PreviewOrder)
+}
+
+func (s *grpcServer) PlaceOrder(ctx context.Context, o *allyapipb.Order) (*allyapipb.OrderResponse, error) {
+	if o.Force {
+		return s.submitOrder(o, s.client.PlaceDuplicateOrder)
+	}
+	return s.submitOrder(o, s.client.PlaceOrder)
+}
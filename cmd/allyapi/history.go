@@ -0,0 +1,346 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+)
+
+// Columns of the history export
+var historyColumns = []string{"date", "symbol", "side", "quantity", "price", "fees", "amount"}
+
+// Flatten a transaction into an export row. Trades are buys or sells by the
+// sign of the quantity; other activity, such as dividends, keeps its name.
+func historyRow(t *allyapi.Transaction) map[string]string {
+	d := t.Transaction
+	qty, _ := strconv.ParseFloat(d.Quantity, 64)
+
+	side := strings.ToLower(t.Activity)
+	if strings.EqualFold(t.Activity, "trade") {
+		side = "buy"
+		if qty < 0 {
+			side = "sell"
+		}
+	}
+
+	date := t.Date
+	if tm, err := t.Time(); err == nil {
+		date = tm.Format("2006-01-02")
+	}
+	symbol := t.Symbol
+	if symbol == "" {
+		symbol = d.Security.Sym
+	}
+
+	row := map[string]string{
+		"date":   date,
+		"symbol": symbol,
+		"side":   side,
+		"fees":   (d.Commission + d.Fee + d.SecFee).StringFixed(2),
+		"amount": t.Amount.StringFixed(2),
+	}
+	if qty != 0 {
+		row["quantity"] = strconv.FormatFloat(abs(qty), 'f', -1, 64)
+		row["price"] = d.Price.String()
+	}
+	return row
+}
+
+// Description of a transaction, without repeating the security name
+func transactionMemo(t *allyapi.Transaction) string {
+	desc := strings.TrimSpace(t.Desc)
+	detail := strings.TrimSpace(t.Transaction.Description)
+	if detail == "" || strings.Contains(detail, desc) {
+		return detail
+	}
+	if strings.Contains(desc, detail) {
+		return desc
+	}
+	return desc + " " + detail
+}
+
+func abs(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
+
+// Relative dates count back from today by days, weeks, months, or years
+var relativeDateUnits = map[byte]func(t time.Time, n int) time.Time{
+	'd': func(t time.Time, n int) time.Time { return t.AddDate(0, 0, -n) },
+	'w': func(t time.Time, n int) time.Time { return t.AddDate(0, 0, -7*n) },
+	'm': func(t time.Time, n int) time.Time { return t.AddDate(0, -n, 0) },
+	'y': func(t time.Time, n int) time.Time { return t.AddDate(-n, 0, 0) },
+}
+
+// Parse a date flag in market time, like the API's dates. Besides
+// YYYY-MM-DD, it may be today, mtd or ytd for the start of the month or year,
+// or a number of days, weeks, months, or years ago, e.g. 30d or 6m.
+func parseDate(s string) (time.Time, error) {
+	now := time.Now().In(allyapi.MarketTime)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, allyapi.MarketTime)
+
+	switch strings.ToLower(s) {
+	case "today":
+		return today, nil
+	case "mtd":
+		return today.AddDate(0, 0, 1-today.Day()), nil
+	case "ytd":
+		return today.AddDate(0, 0, 1-today.YearDay()), nil
+	}
+	if len(s) > 1 {
+		if ago, ok := relativeDateUnits[s[len(s)-1]]; ok {
+			if n, err := strconv.Atoi(s[:len(s)-1]); err == nil && n >= 0 {
+				return ago(today, n), nil
+			}
+		}
+	}
+
+	t, err := time.ParseInLocation("2006-01-02", s, allyapi.MarketTime)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: use YYYY-MM-DD, today, mtd, ytd, or e.g. 30d", s)
+	}
+	return t, nil
+}
+
+// Parse the dates of -from and -to flags, either of which may be empty
+func parseDateRange(fromFlag, toFlag string) (from, to time.Time, err error) {
+	if fromFlag != "" {
+		if from, err = parseDate(fromFlag); err != nil {
+			return from, to, err
+		}
+	}
+	if toFlag != "" {
+		if to, err = parseDate(toFlag); err != nil {
+			return from, to, err
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return from, to, usageError("-to is before -from")
+	}
+	return from, to, nil
+}
+
+// Keep transactions dated from from to to, inclusive; zero times are
+// unbounded
+func filterTransactions(txs allyapi.Transactions, from, to time.Time) allyapi.Transactions {
+	var kept allyapi.Transactions
+	for _, t := range txs {
+		tm, err := t.Time()
+		if err != nil {
+			continue
+		}
+		if !from.IsZero() && tm.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !tm.Before(to.AddDate(0, 0, 1)) {
+			continue
+		}
+		kept = append(kept, t)
+	}
+	return kept
+}
+
+func addPageSizeFlag(fs *flag.FlagSet) *int {
+	return fs.Int("page-size", 0, "Request at most this many transactions at a time (0 requests them all at once)")
+}
+
+// Types of transactions to export, and the API's transactions parameter to
+// request each with
+var historyTypes = map[string]string{
+	"all":         "all",
+	"bookkeeping": "bookkeeping",
+	"dividends":   "all",
+	"trades":      "trade",
+}
+
+// Keep transactions of a history type; dividends aren't a type of their own
+// in the API, so are found by their activity
+func filterTransactionType(txs allyapi.Transactions, typ string) allyapi.Transactions {
+	if typ != "dividends" {
+		return txs
+	}
+	var kept allyapi.Transactions
+	for _, t := range txs {
+		if strings.EqualFold(t.Activity, "dividend") {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// Get an account's transactions of type txType, a page at a time
+func getHistory(client *allyapi.Client, account, txType string, pageSize int) (allyapi.Transactions, error) {
+	return getHistoryRange(client, account, txType, pageSize, time.Time{}, time.Time{})
+}
+
+// Get an account's transactions dated from from to to, asking the API for
+// the narrowest range that covers them and filtering the rest
+func getHistoryRange(client *allyapi.Client, account, txType string, pageSize int, from, to time.Time) (allyapi.Transactions, error) {
+	dateRange := allyapi.HistoryRange(from, to, time.Now())
+	txs, err := client.HistoryPages(account, dateRange, txType, pageSize).All()
+	if err != nil {
+		return nil, fmt.Errorf("error getting history: %v", err)
+	}
+	return filterTransactions(txs, from, to), nil
+}
+
+func writeHistoryCSV(w io.Writer, txs allyapi.Transactions) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(historyColumns); err != nil {
+		return err
+	}
+	for i := range txs {
+		row := historyRow(&txs[i])
+		fields := make([]string, len(historyColumns))
+		for j, c := range historyColumns {
+			fields[j] = row[c]
+		}
+		if err := cw.Write(fields); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func historyExportCommand() *command {
+	cmd := newCommand("export", "history export [flags]", "Export transactions as CSV, OFX, or QIF")
+	account := addAccountFlag(cmd.flags)
+	pageSize := addPageSizeFlag(cmd.flags)
+	fromFlag := cmd.flags.String("from", "", "First date to export: YYYY-MM-DD, ytd, mtd, today, or e.g. 30d for 30 days ago")
+	toFlag := cmd.flags.String("to", "", "Last date to export, like -from")
+	out := cmd.flags.String("out", "-", "File to write, or - for stdout")
+	as := cmd.flags.String("as", "", "File format: csv, ofx, or qif (default from the -out extension, or csv)")
+	typ := cmd.flags.String("type", "all", "Transactions to export: all, trades, dividends, or bookkeeping")
+	cmd.completeFlags = map[string]func() []string{"type": completeWords("all", "trades", "dividends", "bookkeeping")}
+
+	cmd.run = func(args []string) error {
+		from, to, err := parseDateRange(*fromFlag, *toFlag)
+		if err != nil {
+			return err
+		}
+		txType, ok := historyTypes[*typ]
+		if !ok {
+			return usageError(fmt.Sprintf("invalid transaction type: %q", *typ))
+		}
+
+		format := *as
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*out)), ".")
+			if format != "ofx" && format != "qif" {
+				format = "csv"
+			}
+		}
+		switch format {
+		case "csv", "ofx", "qif":
+		default:
+			return usageError(fmt.Sprintf("invalid export format: %q", format))
+		}
+
+		client := newClient()
+		defer client.Wait()
+
+		id, err := defaultAccount(client, *account)
+		if err != nil {
+			return err
+		}
+		txs, err := getHistoryRange(client, id, txType, *pageSize, from, to)
+		if err != nil {
+			return err
+		}
+		txs = filterTransactionType(txs, *typ)
+
+		w := io.Writer(os.Stdout)
+		if *out != "-" {
+			f, err := os.Create(*out)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		switch format {
+		case "ofx":
+			err = writeHistoryOFX(w, id, txs)
+		case "qif":
+			err = writeHistoryQIF(w, txs)
+		default:
+			err = writeHistoryCSV(w, txs)
+		}
+		if err != nil {
+			return fmt.Errorf("error writing history: %v", err)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// Columns of historical quotes
+var barColumns = []string{"date", "open", "high", "low", "close", "volume"}
+
+func barRow(b allyapi.Bar) map[string]string {
+	return map[string]string{
+		"date":   b.Time.Format("2006-01-02"),
+		"open":   b.Open.StringFixed(2),
+		"high":   b.High.StringFixed(2),
+		"low":    b.Low.StringFixed(2),
+		"close":  b.Close.StringFixed(2),
+		"volume": strconv.FormatInt(b.Volume, 10),
+	}
+}
+
+func historyQuotesCommand() *command {
+	cmd := newCommand("quotes", "history quotes [flags] SYMBOL", "Print historical prices of a symbol")
+	output := addOutputFlags(cmd.flags)
+	interval := cmd.flags.String("interval", "daily", "Interval of each price: daily, weekly, or monthly")
+	fromFlag := cmd.flags.String("from", "", "First date: YYYY-MM-DD, ytd, mtd, today, or e.g. 1y for a year ago")
+	toFlag := cmd.flags.String("to", "", "Last date, like -from")
+
+	cmd.run = func(args []string) error {
+		if len(args) != 1 {
+			cmd.printUsage()
+			return usageError("expected one symbol")
+		}
+		if err := output.validate(); err != nil {
+			return err
+		}
+
+		from, to, err := parseDateRange(*fromFlag, *toFlag)
+		if err != nil {
+			return err
+		}
+
+		client := newClient()
+		defer client.Wait()
+
+		bars, err := client.HistoricalQuotes(strings.ToUpper(args[0]), *interval, from, to)
+		if err != nil {
+			return fmt.Errorf("error getting historical quotes: %v", err)
+		}
+		rows := make([]map[string]string, len(bars))
+		for i, b := range bars {
+			rows[i] = barRow(b)
+		}
+		return output.printRows(barColumns, rows)
+	}
+	return cmd
+}
+
+func historyCommand() *command {
+	cmd := newCommand("history", "history COMMAND [flags]", "Work with account transaction history and historical prices")
+	cmd.commands = []*command{
+		historyExportCommand(),
+		historyQuotesCommand(),
+	}
+	return cmd
+}
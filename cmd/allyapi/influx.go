@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+)
+
+// Points are written in batches of up to this many, or every influxFlushEvery
+const (
+	influxBatchSize  = 500
+	influxFlushEvery = time.Second
+)
+
+type influxFlags struct {
+	url   string
+	token string
+}
+
+func addInfluxFlags(fs *flag.FlagSet) *influxFlags {
+	f := &influxFlags{}
+	fs.StringVar(&f.url, "influx", "", "Write points to this InfluxDB write URL, e.g. http://localhost:8086/api/v2/write?org=me&bucket=quotes")
+	fs.StringVar(&f.token, "influx-token", os.Getenv("INFLUX_TOKEN"), "InfluxDB API token (default $INFLUX_TOKEN)")
+	return f
+}
+
+// Writes quotes and trades to InfluxDB in line protocol, one measurement per
+// symbol with fields for last, bid, ask, and volume
+type influxSink struct {
+	url    string
+	token  string
+	client *http.Client
+
+	mu   sync.Mutex
+	buf  bytes.Buffer
+	n    int
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newInfluxSink(url, token string) *influxSink {
+	s := &influxSink{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+		done:   make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.flushLoop()
+	return s
+}
+
+// Escape commas, spaces, and equals signs in measurement names and tag values
+var influxEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+
+// Add a point for symbol with the non-empty fields, which must already be
+// formatted as line protocol values
+func (s *influxSink) addPoint(symbol, typ string, fields [][2]string, t time.Time) error {
+	var line strings.Builder
+	fmt.Fprintf(&line, "%v,type=%v ", influxEscaper.Replace(symbol), typ)
+	n := 0
+	for _, f := range fields {
+		if f[1] == "" {
+			continue
+		}
+		if n > 0 {
+			line.WriteByte(',')
+		}
+		fmt.Fprintf(&line, "%v=%v", f[0], f[1])
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	fmt.Fprintf(&line, " %d\n", t.UnixNano())
+
+	s.mu.Lock()
+	s.buf.WriteString(line.String())
+	s.n++
+	full := s.n >= influxBatchSize
+	s.mu.Unlock()
+
+	if full {
+		return s.flush()
+	}
+	return nil
+}
+
+func influxFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// Format a quote field as a float, or "" if it isn't a number
+func influxQuoteField(q map[string]string, name string) string {
+	f, err := strconv.ParseFloat(q[name], 64)
+	if err != nil {
+		return ""
+	}
+	return influxFloat(f)
+}
+
+func influxQuoteInt(q map[string]string, name string) string {
+	i, err := strconv.ParseInt(q[name], 10, 64)
+	if err != nil {
+		return ""
+	}
+	return strconv.FormatInt(i, 10) + "i"
+}
+
+// Streamed messages should carry a timestamp, but don't record points at the
+// epoch if one doesn't
+func timeOrNow(t time.Time) time.Time {
+	if t.IsZero() {
+		return time.Now()
+	}
+	return t
+}
+
+func (s *influxSink) send(m *allyapi.APIResponse) error {
+	switch {
+	case m.Quote != nil:
+		return s.addPoint(m.Quote.Symbol, "quote", [][2]string{
+			{"bid", influxFloat(m.Quote.Bid.Float64())},
+			{"ask", influxFloat(m.Quote.Ask.Float64())},
+		}, timeOrNow(m.Quote.Time()))
+	case m.Trade != nil:
+		return s.addPoint(m.Trade.Symbol, "trade", [][2]string{
+			{"last", influxFloat(m.Trade.Last.Float64())},
+			{"volume", strconv.Itoa(m.Trade.Vl) + "i"},
+		}, timeOrNow(m.Trade.Time()))
+	}
+	return nil
+}
+
+// Write a snapshot of quotes, e.g. from the quotes command
+func (s *influxSink) writeQuotes(quotes allyapi.QuoteArray) error {
+	now := time.Now()
+	for _, q := range quotes {
+		err := s.addPoint(q["symbol"], "snapshot", [][2]string{
+			{"last", influxQuoteField(q, "last")},
+			{"bid", influxQuoteField(q, "bid")},
+			{"ask", influxQuoteField(q, "ask")},
+			{"volume", influxQuoteInt(q, "vl")},
+		}, now)
+		if err != nil {
+			return err
+		}
+	}
+	return s.flush()
+}
+
+func (s *influxSink) flushLoop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(influxFlushEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			if err := s.flush(); err != nil {
+				slog.Warn("error writing to InfluxDB", "error", err)
+			}
+		}
+	}
+}
+
+// Send the buffered points
+func (s *influxSink) flush() error {
+	s.mu.Lock()
+	if s.n == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	body := append([]byte(nil), s.buf.Bytes()...)
+	s.buf.Reset()
+	s.n = 0
+	s.mu.Unlock()
+
+	req, err := http.NewRequest("POST", s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Token "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("InfluxDB returned %v: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+func (s *influxSink) close() error {
+	close(s.done)
+	s.wg.Wait()
+	return s.flush()
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+)
+
+// Kafka API keys and the versions of them used
+const (
+	kafkaProduce         = 0
+	kafkaProduceVersion  = 3
+	kafkaMetadata        = 3
+	kafkaMetadataVersion = 4
+)
+
+// Produce requests wait this long for the leader to write the record
+const kafkaTimeout = 10 * time.Second
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// Publishes quotes and trades to Kafka, keyed by symbol so that each symbol's
+// messages go to one partition in order. Each message is acknowledged by its
+// partition's leader before the next is sent.
+type kafkaSink struct {
+	mu        sync.Mutex
+	bootstrap []string
+	topic     string
+	corrID    int32
+
+	// Connections and addresses of brokers by node ID, and the leaders of
+	// each topic's partitions
+	conns   map[int32]*kafkaConn
+	addrs   map[int32]string
+	leaders map[string][]int32
+}
+
+type kafkaConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// Connect to any of the comma-separated brokers, e.g. localhost:9092. topic
+// may contain {symbol}, which is replaced by each message's symbol.
+func newKafkaSink(brokers, topic string) (*kafkaSink, error) {
+	k := &kafkaSink{
+		topic:   topic,
+		conns:   make(map[int32]*kafkaConn),
+		addrs:   make(map[int32]string),
+		leaders: make(map[string][]int32),
+	}
+	for _, b := range strings.Split(brokers, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			if _, _, err := net.SplitHostPort(b); err != nil {
+				b = net.JoinHostPort(b, "9092")
+			}
+			k.bootstrap = append(k.bootstrap, b)
+		}
+	}
+	if len(k.bootstrap) == 0 {
+		return nil, errors.New("no Kafka brokers given")
+	}
+
+	// Check that the topic is there now, unless it depends on the symbol
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if !strings.Contains(topic, "{symbol}") {
+		if _, err := k.partitions(topic); err != nil {
+			k.closeConns()
+			return nil, err
+		}
+	} else if _, err := k.conn(-1); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Connection to a broker, or with node -1 to any bootstrap broker
+func (k *kafkaSink) conn(node int32) (*kafkaConn, error) {
+	if c, ok := k.conns[node]; ok {
+		return c, nil
+	}
+	addrs := k.bootstrap
+	if node >= 0 {
+		addr, ok := k.addrs[node]
+		if !ok {
+			return nil, fmt.Errorf("unknown Kafka broker %d", node)
+		}
+		addrs = []string{addr}
+	}
+	var err error
+	for _, addr := range addrs {
+		var conn net.Conn
+		if conn, err = net.DialTimeout("tcp", addr, 10*time.Second); err == nil {
+			c := &kafkaConn{Conn: conn, r: bufio.NewReader(conn)}
+			k.conns[node] = c
+			return c, nil
+		}
+	}
+	return nil, err
+}
+
+func (k *kafkaSink) closeConns() {
+	for node, c := range k.conns {
+		c.Close()
+		delete(k.conns, node)
+	}
+}
+
+// Send a request to a broker and return the body of its response
+func (k *kafkaSink) request(node int32, apiKey, version int16, body []byte) ([]byte, error) {
+	c, err := k.conn(node)
+	if err != nil {
+		return nil, err
+	}
+	k.corrID++
+	req := binary.BigEndian.AppendUint32(nil, 0)
+	req = binary.BigEndian.AppendUint16(req, uint16(apiKey))
+	req = binary.BigEndian.AppendUint16(req, uint16(version))
+	req = binary.BigEndian.AppendUint32(req, uint32(k.corrID))
+	req = appendKafkaString(req, "allyapi")
+	req = append(req, body...)
+	binary.BigEndian.PutUint32(req, uint32(len(req)-4))
+
+	c.SetDeadline(time.Now().Add(kafkaTimeout + 5*time.Second))
+	resp, err := k.roundTrip(c, req)
+	if err != nil {
+		// The connection may be out of step with its responses now
+		c.Close()
+		delete(k.conns, node)
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (k *kafkaSink) roundTrip(c *kafkaConn, req []byte) ([]byte, error) {
+	if _, err := c.Write(req); err != nil {
+		return nil, err
+	}
+	var header [8]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header[:4])
+	if size < 4 || size > 1<<24 {
+		return nil, fmt.Errorf("invalid Kafka response size %d", size)
+	}
+	if id := int32(binary.BigEndian.Uint32(header[4:])); id != k.corrID {
+		return nil, fmt.Errorf("Kafka response %d doesn't match request %d", id, k.corrID)
+	}
+	resp := make([]byte, size-4)
+	_, err := io.ReadFull(c.r, resp)
+	return resp, err
+}
+
+// Leaders of a topic's partitions, by partition, looked up from a broker's
+// metadata the first time
+func (k *kafkaSink) partitions(topic string) ([]int32, error) {
+	if leaders, ok := k.leaders[topic]; ok {
+		return leaders, nil
+	}
+
+	body := binary.BigEndian.AppendUint32(nil, 1)
+	body = appendKafkaString(body, topic)
+	body = append(body, 1) // allow_auto_topic_creation
+	resp, err := k.request(-1, kafkaMetadata, kafkaMetadataVersion, body)
+	if err != nil {
+		return nil, fmt.Errorf("error getting Kafka metadata: %v", err)
+	}
+
+	r := &kafkaReader{b: resp}
+	r.int32() // throttle_time_ms
+	for i := r.int32(); i > 0; i-- {
+		node, host, port := r.int32(), r.string(), r.int32()
+		r.string() // rack
+		k.addrs[node] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	r.string() // cluster_id
+	r.int32()  // controller_id
+
+	var leaders []int32
+	for i := r.int32(); i > 0; i-- {
+		code, name := r.int16(), r.string()
+		r.int8() // is_internal
+		if code != 0 && name == topic {
+			r.err = kafkaError(code)
+		}
+		for j := r.int32(); j > 0; j-- {
+			r.int16() // error_code
+			partition, leader := r.int32(), r.int32()
+			r.skipArray(4) // replica_nodes
+			r.skipArray(4) // isr_nodes
+			if name != topic {
+				continue
+			}
+			for int(partition) >= len(leaders) {
+				leaders = append(leaders, -1)
+			}
+			leaders[partition] = leader
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("error getting Kafka metadata of %v: %v", topic, r.err)
+	}
+	if len(leaders) == 0 {
+		return nil, fmt.Errorf("Kafka topic %v has no partitions", topic)
+	}
+	k.leaders[topic] = leaders
+	return leaders, nil
+}
+
+// Produce a record to a partition of a topic and wait for its leader to
+// acknowledge it
+func (k *kafkaSink) produce(topic string, partition, leader int32, key, value []byte) error {
+	body := binary.BigEndian.AppendUint16(nil, 0xffff) // transactional_id
+	body = binary.BigEndian.AppendUint16(body, 1)      // acks from the leader
+	body = binary.BigEndian.AppendUint32(body, uint32(kafkaTimeout.Milliseconds()))
+	body = binary.BigEndian.AppendUint32(body, 1)
+	body = appendKafkaString(body, topic)
+	body = binary.BigEndian.AppendUint32(body, 1)
+	body = binary.BigEndian.AppendUint32(body, uint32(partition))
+	batch := kafkaRecordBatch(key, value, time.Now())
+	body = binary.BigEndian.AppendUint32(body, uint32(len(batch)))
+	body = append(body, batch...)
+
+	resp, err := k.request(leader, kafkaProduce, kafkaProduceVersion, body)
+	if err != nil {
+		return err
+	}
+	r := &kafkaReader{b: resp}
+	for i := r.int32(); i > 0; i-- {
+		r.string()
+		for j := r.int32(); j > 0; j-- {
+			r.int32()
+			if code := r.int16(); code != 0 && r.err == nil {
+				r.err = kafkaError(code)
+			}
+			r.int64() // base_offset
+			r.int64() // log_append_time_ms
+		}
+	}
+	return r.err
+}
+
+func (k *kafkaSink) send(msg *allyapi.APIResponse) error {
+	symbol := streamSymbol(msg)
+	if symbol == "" {
+		return nil
+	}
+	value, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	topic := strings.ReplaceAll(k.topic, "{symbol}", symbol)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	leaders, err := k.partitions(topic)
+	if err != nil {
+		return err
+	}
+	key := []byte(symbol)
+	partition := (murmur2(key) & 0x7fffffff) % int32(len(leaders))
+	if err := k.produce(topic, partition, leaders[partition], key, value); err != nil {
+		// Look up the leaders again next time, in case they moved
+		delete(k.leaders, topic)
+		return fmt.Errorf("error producing to Kafka topic %v: %v", topic, err)
+	}
+	return nil
+}
+
+func (k *kafkaSink) close() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.closeConns()
+	return nil
+}
+
+// A record batch, in the v2 message format, of one record
+func kafkaRecordBatch(key, value []byte, t time.Time) []byte {
+	var record []byte
+	record = append(record, 0)              // attributes
+	record = binary.AppendVarint(record, 0) // timestamp_delta
+	record = binary.AppendVarint(record, 0) // offset_delta
+	record = binary.AppendVarint(record, int64(len(key)))
+	record = append(record, key...)
+	record = binary.AppendVarint(record, int64(len(value)))
+	record = append(record, value...)
+	record = binary.AppendVarint(record, 0) // headers
+
+	// Everything from the attributes on is covered by the CRC
+	ms := uint64(t.UnixMilli())
+	var tail []byte
+	tail = binary.BigEndian.AppendUint16(tail, 0) // attributes
+	tail = binary.BigEndian.AppendUint32(tail, 0) // last_offset_delta
+	tail = binary.BigEndian.AppendUint64(tail, ms)
+	tail = binary.BigEndian.AppendUint64(tail, ms)
+	tail = binary.BigEndian.AppendUint64(tail, ^uint64(0)) // producer_id
+	tail = binary.BigEndian.AppendUint16(tail, 0xffff)     // producer_epoch
+	tail = binary.BigEndian.AppendUint32(tail, 0xffffffff) // base_sequence
+	tail = binary.BigEndian.AppendUint32(tail, 1)
+	tail = binary.AppendVarint(tail, int64(len(record)))
+	tail = append(tail, record...)
+
+	batch := binary.BigEndian.AppendUint64(nil, 0) // base_offset
+	batch = binary.BigEndian.AppendUint32(batch, uint32(4+1+4+len(tail)))
+	batch = binary.BigEndian.AppendUint32(batch, 0xffffffff) // partition_leader_epoch
+	batch = append(batch, 2)                                 // magic
+	batch = binary.BigEndian.AppendUint32(batch, crc32.Checksum(tail, crc32c))
+	return append(batch, tail...)
+}
+
+// The hash of Kafka's default partitioner, so that symbols go to the same
+// partitions as from other producers
+func murmur2(data []byte) int32 {
+	const (
+		seed = 0x9747b28c
+		m    = 0x5bd1e995
+	)
+	h := uint32(seed) ^ uint32(len(data))
+	n := len(data) &^ 3
+	for i := 0; i < n; i += 4 {
+		k := binary.LittleEndian.Uint32(data[i:])
+		k *= m
+		k ^= k >> 24
+		k *= m
+		h *= m
+		h ^= k
+	}
+	switch len(data) & 3 {
+	case 3:
+		h ^= uint32(data[n+2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[n+1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[n])
+		h *= m
+	}
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return int32(h)
+}
+
+func appendKafkaString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// Names of the Kafka errors a producer is likely to see
+var kafkaErrors = map[int16]string{
+	2:  "corrupt message",
+	3:  "unknown topic or partition",
+	5:  "leader not available",
+	6:  "not leader for partition",
+	7:  "request timed out",
+	10: "message too large",
+	17: "invalid topic",
+	29: "topic authorization failed",
+	31: "cluster authorization failed",
+}
+
+func kafkaError(code int16) error {
+	if name, ok := kafkaErrors[code]; ok {
+		return fmt.Errorf("%v (code %d)", name, code)
+	}
+	return fmt.Errorf("Kafka error code %d", code)
+}
+
+// Reads the fields of a response, remembering the first error
+type kafkaReader struct {
+	b   []byte
+	err error
+}
+
+func (r *kafkaReader) next(n int) []byte {
+	if r.err != nil {
+		return make([]byte, n)
+	}
+	if n < 0 || n > len(r.b) {
+		r.err = errors.New("truncated Kafka response")
+		return make([]byte, max(n, 0))
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b
+}
+
+func (r *kafkaReader) int8() int8   { return int8(r.next(1)[0]) }
+func (r *kafkaReader) int16() int16 { return int16(binary.BigEndian.Uint16(r.next(2))) }
+func (r *kafkaReader) int32() int32 { return int32(binary.BigEndian.Uint32(r.next(4))) }
+func (r *kafkaReader) int64() int64 { return int64(binary.BigEndian.Uint64(r.next(8))) }
+
+// A string, or a null string as empty
+func (r *kafkaReader) string() string {
+	n := r.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(r.next(int(n)))
+}
+
+// Skip an array of fixed-size elements
+func (r *kafkaReader) skipArray(size int) {
+	if n := r.int32(); n > 0 {
+		r.next(int(n) * size)
+	}
+}
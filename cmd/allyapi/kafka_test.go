@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+)
+
+// Vectors from Kafka's own tests of Utils.murmur2
+func TestMurmur2(t *testing.T) {
+	for key, want := range map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+		"abc": 479470107,
+	} {
+		if got := murmur2([]byte(key)); got != want {
+			t.Errorf("murmur2(%q) = %d, want %d", key, got, want)
+		}
+	}
+}
+
+func TestCRC32C(t *testing.T) {
+	// The standard check value of CRC-32C
+	if got := crc32.Checksum([]byte("123456789"), crc32c); got != 0xe3069283 {
+		t.Errorf("crc32c = %#x, want 0xe3069283", got)
+	}
+}
+
+func TestKafkaRecordBatch(t *testing.T) {
+	batch := kafkaRecordBatch([]byte("k"), []byte("v"), time.UnixMilli(1700000000000))
+
+	want := []byte{
+		0, 0, 0, 0, 0, 0, 0, 0, // base_offset
+		0, 0, 0, 58, // batch_length
+		0xff, 0xff, 0xff, 0xff, // partition_leader_epoch
+		2,          // magic
+		0, 0, 0, 0, // crc, checked below
+		0, 0, // attributes
+		0, 0, 0, 0, // last_offset_delta
+		0, 0, 1, 0x8b, 0xcf, 0xe5, 0x68, 0, // base_timestamp
+		0, 0, 1, 0x8b, 0xcf, 0xe5, 0x68, 0, // max_timestamp
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, // producer_id
+		0xff, 0xff, // producer_epoch
+		0xff, 0xff, 0xff, 0xff, // base_sequence
+		0, 0, 0, 1, // records
+		16,  // length of the record, zigzag encoded
+		0,   // attributes
+		0,   // timestamp_delta
+		0,   // offset_delta
+		2,   // key length
+		'k', // key
+		2,   // value length
+		'v', // value
+		0,   // headers
+	}
+	if len(batch) != len(want) {
+		t.Fatalf("batch is %d bytes, want %d:\n% x", len(batch), len(want), batch)
+	}
+	crc := binary.BigEndian.Uint32(batch[17:21])
+	if got := crc32.Checksum(batch[21:], crc32c); crc != got {
+		t.Errorf("crc = %#x, want %#x", crc, got)
+	}
+	copy(batch[17:21], []byte{0, 0, 0, 0})
+	if !bytes.Equal(batch, want) {
+		t.Errorf("batch =\n% x\nwant\n% x", batch, want)
+	}
+}
+
+// A broker that answers metadata requests with three partitions of topic,
+// led by itself, and records the partition and key of produced records
+type fakeBroker struct {
+	net.Listener
+	topic    string
+	produced chan [2]string
+}
+
+func newFakeBroker(t *testing.T, topic string) *fakeBroker {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &fakeBroker{Listener: l, topic: topic, produced: make(chan [2]string, 10)}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *fakeBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	host, portStr, _ := net.SplitHostPort(b.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return
+		}
+		req := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return
+		}
+		apiKey, corrID := binary.BigEndian.Uint16(req), req[4:8]
+		body := req[10+binary.BigEndian.Uint16(req[8:]):]
+
+		var resp []byte
+		switch apiKey {
+		case kafkaMetadata:
+			resp = binary.BigEndian.AppendUint32(resp, 0) // throttle_time_ms
+			resp = binary.BigEndian.AppendUint32(resp, 1)
+			resp = binary.BigEndian.AppendUint32(resp, 7)
+			resp = appendKafkaString(resp, host)
+			resp = binary.BigEndian.AppendUint32(resp, uint32(port))
+			resp = binary.BigEndian.AppendUint16(resp, 0xffff) // rack
+			resp = binary.BigEndian.AppendUint16(resp, 0xffff) // cluster_id
+			resp = binary.BigEndian.AppendUint32(resp, 7)      // controller_id
+			resp = binary.BigEndian.AppendUint32(resp, 1)
+			resp = binary.BigEndian.AppendUint16(resp, 0)
+			resp = appendKafkaString(resp, b.topic)
+			resp = append(resp, 0)
+			resp = binary.BigEndian.AppendUint32(resp, 3)
+			for p := 0; p < 3; p++ {
+				resp = binary.BigEndian.AppendUint16(resp, 0)
+				resp = binary.BigEndian.AppendUint32(resp, uint32(p))
+				resp = binary.BigEndian.AppendUint32(resp, 7)
+				resp = binary.BigEndian.AppendUint32(resp, 0)
+				resp = binary.BigEndian.AppendUint32(resp, 0)
+			}
+		case kafkaProduce:
+			// transactional_id, acks, timeout, one topic, one partition
+			r := &kafkaReader{b: body}
+			r.string()
+			r.int16()
+			r.int32()
+			r.int32()
+			r.string()
+			r.int32()
+			partition := r.int32()
+			batch := r.next(int(r.int32()))
+			record := bytes.NewReader(batch[61:])
+			binary.ReadVarint(record) // length
+			record.ReadByte()         // attributes
+			binary.ReadVarint(record) // timestamp_delta
+			binary.ReadVarint(record) // offset_delta
+			n, _ := binary.ReadVarint(record)
+			key := make([]byte, n)
+			record.Read(key)
+			b.produced <- [2]string{strconv.Itoa(int(partition)), string(key)}
+
+			resp = binary.BigEndian.AppendUint32(resp, 1)
+			resp = appendKafkaString(resp, b.topic)
+			resp = binary.BigEndian.AppendUint32(resp, 1)
+			resp = binary.BigEndian.AppendUint32(resp, uint32(partition))
+			resp = binary.BigEndian.AppendUint16(resp, 0)
+			resp = binary.BigEndian.AppendUint64(resp, 0)
+			resp = binary.BigEndian.AppendUint64(resp, 0)
+			resp = binary.BigEndian.AppendUint32(resp, 0)
+		}
+		out := binary.BigEndian.AppendUint32(nil, uint32(len(resp)+4))
+		out = append(append(out, corrID...), resp...)
+		if _, err := conn.Write(out); err != nil {
+			return
+		}
+	}
+}
+
+func TestKafkaSink(t *testing.T) {
+	broker := newFakeBroker(t, "quotes")
+	k, err := newKafkaSink(broker.Addr().String(), "quotes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer k.close()
+
+	if err := k.send(&allyapi.APIResponse{Trade: &allyapi.StreamTrade{Symbol: "AAPL"}}); err != nil {
+		t.Fatal(err)
+	}
+	got := <-broker.produced
+	want := strconv.Itoa(int((murmur2([]byte("AAPL")) & 0x7fffffff) % 3))
+	if got != [2]string{want, "AAPL"} {
+		t.Errorf("produced to partition %v with key %q, want partition %v", got[0], got[1], want)
+	}
+
+	// Messages without a symbol aren't produced
+	if err := k.send(&allyapi.APIResponse{Status: "connected"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-broker.produced:
+		t.Errorf("produced %v for a status message", got)
+	default:
+	}
+}
+
+func TestKafkaSinkUnknownTopic(t *testing.T) {
+	broker := newFakeBroker(t, "quotes")
+	if _, err := newKafkaSink(broker.Addr().String(), "trades"); err == nil {
+		t.Error("expected an error for a topic without partitions")
+	}
+}
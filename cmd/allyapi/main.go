@@ -0,0 +1,391 @@
+// Command allyapi is a command line client for the Ally Invest API.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+)
+
+var version = "undefined"
+var showVersionFlag, dryRunFlag, quietFlag, debugFlag, noColorFlag *bool
+
+// Cancelled on SIGINT or SIGTERM, aborting requests and ending commands that
+// run until interrupted
+var rootCtx = context.Background()
+
+var credsFlag, configFlag, profileFlag, responseFormatFlag, envFlag, logLevelFlag, logFormatFlag, proxyFlag *string
+var breakerFlag *int
+var breakerCooldownFlag *time.Duration
+
+// A command or a group of subcommands, each with its own flags and help
+type command struct {
+	name     string
+	usage    string
+	summary  string
+	flags    *flag.FlagSet
+	run      func(args []string) error
+	commands []*command
+
+	// Omit from help and completion
+	hidden bool
+
+	// Dynamic completion of positional arguments
+	completeArgs func() []string
+
+	// Completion of flag values, overriding flagCompletions
+	completeFlags map[string]func() []string
+
+	// Printed at the end of the help
+	footer string
+}
+
+func newCommand(name, usage, summary string) *command {
+	c := &command{
+		name:    name,
+		usage:   usage,
+		summary: summary,
+		flags:   flag.NewFlagSet(name, flag.ExitOnError),
+	}
+	c.flags.Usage = c.printUsage
+	return c
+}
+
+func (c *command) printUsage() {
+	w := c.flags.Output()
+	fmt.Fprintf(w, "Usage: allyapi %v\n\n%v\n", c.usage, c.summary)
+
+	if len(c.commands) > 0 {
+		fmt.Fprintln(w, "\nCommands:")
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, sub := range c.commands {
+			if !sub.hidden {
+				fmt.Fprintf(tw, "  %v\t%v\n", sub.name, sub.summary)
+			}
+		}
+		tw.Flush()
+	}
+
+	hasFlags := false
+	c.flags.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		fmt.Fprintln(w, "\nFlags:")
+		c.flags.PrintDefaults()
+	}
+	if c.footer != "" {
+		fmt.Fprintf(w, "\n%v\n", c.footer)
+	}
+}
+
+func (c *command) find(name string) *command {
+	for _, sub := range c.commands {
+		if sub.name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+func (c *command) execute(args []string) error {
+	c.flags.Parse(args)
+	return c.dispatch(c.flags.Args())
+}
+
+// Run the command, or pass the remaining arguments to a subcommand. Commands
+// with both run their subcommands when the first argument names one.
+func (c *command) dispatch(args []string) error {
+	if c.run != nil {
+		if len(args) > 0 {
+			if sub := c.find(args[0]); sub != nil {
+				return sub.execute(args[1:])
+			}
+		}
+		return c.run(args)
+	}
+
+	if len(args) == 0 {
+		c.printUsage()
+		os.Exit(exitUsage)
+	}
+	sub := c.find(args[0])
+	if sub == nil {
+		return usageError(fmt.Sprintf("unknown command: %q", strings.TrimSpace(c.name+" "+args[0])))
+	}
+	return sub.execute(args[1:])
+}
+
+func helpCommand(root *command) *command {
+	cmd := newCommand("help", "help [COMMAND...]", "Show help for a command")
+	cmd.run = func(args []string) error {
+		c := root
+		for _, name := range args {
+			if c = c.find(name); c == nil {
+				return usageError(fmt.Sprintf("unknown command: %q", strings.Join(args, " ")))
+			}
+		}
+		c.flags.SetOutput(os.Stdout)
+		c.printUsage()
+		return nil
+	}
+	return cmd
+}
+
+func versionCommand() *command {
+	cmd := newCommand("version", "version", "Print version")
+	cmd.run = func(args []string) error {
+		printVersion()
+		return nil
+	}
+	return cmd
+}
+
+func rootCommand() *command {
+	root := newCommand("", "[flags] COMMAND [flags] [args]", "A command line client for the Ally Invest API")
+	root.commands = []*command{
+		quotesCommand(),
+		optionsCommand(),
+		streamCommand(),
+		replayCommand(),
+		queryCommand(),
+		watchCommand(),
+		alertsCommand(),
+		accountsCommand(),
+		balancesCommand(),
+		historyCommand(),
+		chartCommand(),
+		pnlCommand(),
+		taxlotsCommand(),
+		gainsCommand(),
+		performanceCommand(),
+		dividendsCommand(),
+		screenCommand(),
+		ordersCommand(),
+		dcaCommand(),
+		sizeCommand(),
+		rebalanceCommand(),
+		watchlistsCommand(),
+		rateLimitCommand(),
+		rawCommand(),
+		serveCommand(),
+		dashboardCommand(),
+		authCommand(),
+		completionCommand(),
+		versionCommand(),
+	}
+	root.commands = append(root.commands, helpCommand(root), completeCommand(root))
+	root.footer = environmentHelp + "\n\n" + exitCodeHelp
+
+	showVersionFlag = root.flags.Bool("version", false, "Print version")
+	dryRunFlag = root.flags.Bool("dry-run", false, "Print order requests instead of sending them")
+	envFlag = root.flags.String("env", "dev", "API environment: live or dev (default from $ALLY_ENV or the config file's environment, if set)")
+	responseFormatFlag = root.flags.String("response-format", "json", "Response format to request from the API: json or xml")
+	credsFlag = root.flags.String("creds", "auto", "Credential source: auto, env, file, or keychain (system keyring)")
+	configFlag = root.flags.String("config", allyapi.DefaultConfigPath(), "Path to config file with defaults and file credentials")
+	profileFlag = root.flags.String("profile", "", "Name of a profile with its own credentials and defaults (default $ALLY_PROFILE)")
+	proxyFlag = root.flags.String("proxy", "", "Proxy URL for API requests (default from $HTTPS_PROXY)")
+	logLevelFlag = root.flags.String("log-level", "info", "Minimum level of log messages: debug, info, warn, or error")
+	quietFlag = root.flags.Bool("quiet", false, "Only log errors")
+	debugFlag = root.flags.Bool("debug", false, "Write requests, with credentials redacted, and responses to stderr")
+	noColorFlag = root.flags.Bool("no-color", false, "Don't color price changes in tables and the watch board (also set by $NO_COLOR)")
+	logFormatFlag = root.flags.String("log-format", "text", "Format of log messages on stderr: text or json")
+	breakerFlag = root.flags.Int("breaker", 5, "Stop making requests after this many fail in a row, other than for authorization (0 never stops)")
+	breakerCooldownFlag = root.flags.Duration("breaker-cooldown", 30*time.Second, "How long to stop making requests for once -breaker is reached")
+	return root
+}
+
+const environmentHelp = `Environment:
+  ALLY_CONSUMER_KEY, ALLY_CONSUMER_SECRET,
+  ALLY_ACCESS_TOKEN, ALLY_ACCESS_SECRET
+                credentials for -creds env, or auto when they are set
+  ALLY_PROFILE  profile, unless -profile is given
+  ALLY_ENV      API environment, unless -env is given
+  ALLY_ACCOUNT  account used when -account isn't given
+  NO_COLOR      turns off color, like -no-color, when set to anything
+
+These take precedence over the config file.`
+
+// Read the config file with the profile given by the global flags
+func loadConfig() (*allyapi.ConfigFile, error) {
+	return allyapi.LoadProfile(*configFlag, *profileFlag)
+}
+
+// Use defaults from the environment or the config file for global flags that
+// weren't given
+func applyConfigDefaults(fs *flag.FlagSet) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if !set["profile"] {
+		*profileFlag = os.Getenv("ALLY_PROFILE")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("error reading config file: %v", err)
+	}
+	if !set["env"] {
+		if env := os.Getenv("ALLY_ENV"); env != "" {
+			*envFlag = env
+		} else if cfg.Environment != "" {
+			*envFlag = cfg.Environment
+		}
+	}
+	defaultOutput = cfg.Output
+	defaultBuyingPowerCheck = cfg.BuyingPowerCheck
+	if cfg.AuditLog != "" {
+		auditLogPath = cfg.AuditLog
+	}
+	if cfg.DuplicateWindow != "" {
+		if duplicateWindow, err = time.ParseDuration(cfg.DuplicateWindow); err != nil {
+			return fmt.Errorf("invalid duplicate_window in config file: %v", err)
+		}
+	}
+	return nil
+}
+
+func printVersion() {
+	fmt.Println("allyapi version:", version)
+	os.Exit(0)
+}
+
+// Create an API client using the global flags, exiting if that fails. It
+// is only called by commands that use the API, so that the others work
+// without credentials.
+func newClient() *allyapi.Client {
+	client, err := tryNewClient()
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(exitCode(err))
+	}
+	return client
+}
+
+// Create an API client using the global flags
+func tryNewClient() (*allyapi.Client, error) {
+	env, err := allyapi.EnvironmentByName(*envFlag)
+	if err != nil {
+		return nil, usageError(err.Error())
+	}
+
+	opts := []allyapi.Option{
+		allyapi.WithEnvironment(env),
+		allyapi.WithContext(rootCtx),
+		allyapi.WithCredentialSource(*credsFlag, *configFlag),
+		allyapi.WithProfile(*profileFlag),
+		allyapi.WithFormat(*responseFormatFlag),
+	}
+	if *proxyFlag != "" {
+		proxyURL, err := url.Parse(*proxyFlag)
+		if err != nil {
+			return nil, usageError(fmt.Sprintf("invalid proxy URL: %v", err))
+		}
+		opts = append(opts, allyapi.WithProxy(proxyURL))
+	}
+	if *debugFlag {
+		opts = append(opts, allyapi.WithDebug(os.Stderr))
+	}
+
+	// The options are checked already, so failures are the credentials'
+	client, err := allyapi.NewClient(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errCredentials, err)
+	}
+	client.DryRun = *dryRunFlag
+	client.StateFile = filepath.Join(allyapi.DefaultStateDir(), "ratelimit.json")
+	client.AuditLog = auditLogPath
+	if *breakerFlag > 0 {
+		client.Breaker = allyapi.NewCircuitBreaker(*breakerFlag, *breakerCooldownFlag)
+	}
+	if err := client.LoadRateLimitState(); err != nil {
+		slog.Warn("unable to load rate limit state", "error", err)
+	}
+	return client, nil
+}
+
+// Sleep for d, returning false early if interrupted
+func sleep(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-rootCtx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// Send log messages, including those from the log package, to stderr in the
+// format and level given by the global flags
+func setupLogging() error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(*logLevelFlag)); err != nil {
+		return fmt.Errorf("invalid log level: %q", *logLevelFlag)
+	}
+	if *quietFlag && level < slog.LevelError {
+		level = slog.LevelError
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch *logFormatFlag {
+	case "text":
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return fmt.Errorf("invalid log format: %q", *logFormatFlag)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+func main() {
+	root := rootCommand()
+
+	root.flags.Parse(os.Args[1:])
+	if *showVersionFlag {
+		printVersion()
+	}
+	if err := setupLogging(); err != nil {
+		log.Print(err)
+		os.Exit(exitUsage)
+	}
+	if err := applyConfigDefaults(root.flags); err != nil {
+		slog.Error(err.Error())
+		os.Exit(exitUsage)
+	}
+
+	switch *responseFormatFlag {
+	case "json", "xml":
+	default:
+		slog.Error(fmt.Sprintf("invalid response format: %q", *responseFormatFlag))
+		os.Exit(exitUsage)
+	}
+
+	// Commands return once interrupted, so that deferred cleanup such as
+	// flushing sinks and saving the rate limit state still happens
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	rootCtx = ctx
+	err := root.dispatch(root.flags.Args())
+	interrupted := ctx.Err() != nil
+	stop()
+	if interrupted {
+		slog.Info("interrupted")
+		os.Exit(exitInterrupted)
+	}
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(exitCode(err))
+	}
+}
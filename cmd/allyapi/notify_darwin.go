@@ -0,0 +1,28 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Posts alerts to Notification Center
+type desktopNotifier struct{}
+
+func newDesktopNotifier() (notifier, error) {
+	return desktopNotifier{}, nil
+}
+
+var appleScriptEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+func (desktopNotifier) notify(title, message string) error {
+	script := fmt.Sprintf(`display notification "%v" with title "allyapi" subtitle "%v"`,
+		appleScriptEscaper.Replace(message), appleScriptEscaper.Replace(title))
+	if out, err := exec.Command("osascript", "-e", script).CombinedOutput(); err != nil {
+		return fmt.Errorf("osascript: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !darwin
+// +build !darwin
+
+package main
+
+import "errors"
+
+func newDesktopNotifier() (notifier, error) {
+	return nil, errors.New("desktop notifications are only supported on macOS")
+}
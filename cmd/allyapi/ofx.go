@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+)
+
+const ofxHeader = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+SECURITY:NONE
+ENCODING:USASCII
+CHARSET:1252
+COMPRESSION:NONE
+OLDFILEUID:NONE
+NEWFILEUID:NONE
+
+`
+
+var ofxEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405")
+}
+
+// A stable ID for a transaction, so that importing overlapping exports
+// doesn't duplicate it. n counts earlier identical transactions.
+func ofxFITID(t *allyapi.Transaction, n int) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%v|%v|%v|%v|%v|%v|%d", t.Date, t.Activity, t.Symbol, t.Amount, t.Transaction.Quantity, t.Desc, n)
+	return hex.EncodeToString(h.Sum(nil))[:20]
+}
+
+// Identify a security by CUSIP if the history has it, or else by ticker
+func ofxSecID(t *allyapi.Transaction, symbol string) string {
+	if cusip := t.Transaction.Security.Cusip; cusip != "" {
+		return "<SECID><UNIQUEID>" + ofxEscaper.Replace(cusip) + "<UNIQUEIDTYPE>CUSIP</SECID>"
+	}
+	return "<SECID><UNIQUEID>" + ofxEscaper.Replace(symbol) + "<UNIQUEIDTYPE>TICKER</SECID>"
+}
+
+// Write transactions as an OFX 1.0.2 investment statement
+func writeHistoryOFX(w io.Writer, account string, txs allyapi.Transactions) error {
+	now := time.Now()
+	start, end := now, now
+	for i := range txs {
+		if tm, err := txs[i].Time(); err == nil {
+			if tm.Before(start) {
+				start = tm
+			}
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprint(bw, ofxHeader)
+	fmt.Fprintln(bw, "<OFX>")
+	fmt.Fprintf(bw, "<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0<SEVERITY>INFO</STATUS><DTSERVER>%v<LANGUAGE>ENG<FI><ORG>Ally Invest</FI></SONRS></SIGNONMSGSRSV1>\n", ofxTime(now))
+	fmt.Fprintln(bw, "<INVSTMTMSGSRSV1><INVSTMTTRNRS><TRNUID>0<STATUS><CODE>0<SEVERITY>INFO</STATUS>")
+	fmt.Fprintf(bw, "<INVSTMTRS><DTASOF>%v<CURDEF>USD<INVACCTFROM><BROKERID>ally.com<ACCTID>%v</INVACCTFROM>\n", ofxTime(now), ofxEscaper.Replace(account))
+	fmt.Fprintf(bw, "<INVTRANLIST><DTSTART>%v<DTEND>%v\n", ofxTime(start), ofxTime(end))
+
+	type security struct{ secID, name, ticker string }
+	var securities []security
+	seen := make(map[string]bool)
+	ids := make(map[string]int)
+
+	for i := range txs {
+		t := &txs[i]
+		row := historyRow(t)
+		tm, err := t.Time()
+		if err != nil {
+			continue
+		}
+
+		key := ofxFITID(t, 0)
+		fitID := ofxFITID(t, ids[key])
+		ids[key]++
+
+		memo := ofxEscaper.Replace(transactionMemo(t))
+		invTran := fmt.Sprintf("<INVTRAN><FITID>%v<DTTRADE>%v<MEMO>%v</INVTRAN>", fitID, ofxTime(tm), memo)
+		symbol := row["symbol"]
+		secID := ofxSecID(t, symbol)
+		if symbol != "" && !seen[secID] {
+			seen[secID] = true
+			name := t.Transaction.Description
+			if name == "" {
+				name = symbol
+			}
+			securities = append(securities, security{secID, name, symbol})
+		}
+
+		switch row["side"] {
+		case "buy", "sell":
+			units := row["quantity"]
+			tag := "BUY"
+			if row["side"] == "sell" {
+				units = "-" + units
+				tag = "SELL"
+			}
+			fmt.Fprintf(bw, "<%[1]vSTOCK><INV%[1]v>%v%v<UNITS>%v<UNITPRICE>%v<COMMISSION>%v<TOTAL>%v<SUBACCTSEC>CASH<SUBACCTFUND>CASH</INV%[1]v><%[1]vTYPE>%[1]v</%[1]vSTOCK>\n",
+				tag, invTran, secID, units, row["price"], row["fees"], row["amount"])
+		case "dividend":
+			fmt.Fprintf(bw, "<INCOME>%v%v<INCOMETYPE>DIV<TOTAL>%v<SUBACCTSEC>CASH<SUBACCTFUND>CASH</INCOME>\n",
+				invTran, secID, row["amount"])
+		default:
+			typ := "CREDIT"
+			if t.Amount < 0 {
+				typ = "DEBIT"
+			}
+			name := ofxEscaper.Replace(t.Activity)
+			if name == "" {
+				name = "Transaction"
+			}
+			fmt.Fprintf(bw, "<INVBANKTRAN><STMTTRN><TRNTYPE>%v<DTPOSTED>%v<TRNAMT>%v<FITID>%v<NAME>%v<MEMO>%v</STMTTRN><SUBACCTFUND>CASH</INVBANKTRAN>\n",
+				typ, ofxTime(tm), row["amount"], fitID, name, memo)
+		}
+	}
+	fmt.Fprintln(bw, "</INVTRANLIST></INVSTMTRS></INVSTMTTRNRS></INVSTMTMSGSRSV1>")
+
+	if len(securities) > 0 {
+		fmt.Fprintln(bw, "<SECLISTMSGSRSV1><SECLIST>")
+		for _, s := range securities {
+			fmt.Fprintf(bw, "<STOCKINFO><SECINFO>%v<SECNAME>%v<TICKER>%v</SECINFO></STOCKINFO>\n",
+				s.secID, ofxEscaper.Replace(s.name), ofxEscaper.Replace(s.ticker))
+		}
+		fmt.Fprintln(bw, "</SECLIST></SECLISTMSGSRSV1>")
+	}
+	fmt.Fprintln(bw, "</OFX>")
+	return bw.Flush()
+}
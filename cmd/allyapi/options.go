@@ -0,0 +1,280 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+)
+
+var optionColumns = []string{"symbol", "underlying", "expiration", "type", "strike"}
+
+func optionRow(o allyapi.OptionSymbol) map[string]string {
+	return map[string]string{
+		"symbol":     o.String(),
+		"underlying": o.Underlying,
+		"expiration": o.Expiration.Format("2006-01-02"),
+		"type":       o.Type.String(),
+		"strike":     o.Strike.String(),
+	}
+}
+
+// Columns of table and CSV output of option quotes
+var optionQuoteColumns = []string{"symbol", "last", "bid", "ask", "vl", "oi", "iv", "delta", "gamma", "theta", "vega", "rho"}
+
+// Whether every symbol is an option symbol
+func allOptions(symbols []string) bool {
+	for _, s := range symbols {
+		if !allyapi.IsOptionSymbol(s) {
+			return false
+		}
+	}
+	return len(symbols) > 0
+}
+
+func optionQuoteRow(q *allyapi.OptionQuote) map[string]string {
+	greek := func(f float64) string { return strconv.FormatFloat(f, 'f', 4, 64) }
+	return map[string]string{
+		"symbol": q.Symbol,
+		"last":   q.Last.StringFixed(2),
+		"bid":    q.Bid.StringFixed(2),
+		"ask":    q.Ask.StringFixed(2),
+		"vl":     strconv.FormatInt(q.Volume, 10),
+		"oi":     strconv.FormatInt(q.OpenInterest, 10),
+		"iv":     strconv.FormatFloat(q.ImpliedVolatility*100, 'f', 2, 64) + "%",
+		"delta":  greek(q.Delta),
+		"gamma":  greek(q.Gamma),
+		"theta":  greek(q.Theta),
+		"vega":   greek(q.Vega),
+		"rho":    greek(q.Rho),
+	}
+}
+
+// Print option quotes with their greeks. JSON output and templates use
+// allyapi.OptionQuote rather than the raw quote fields.
+func (o *outputFlags) printOptionQuotes(resp *allyapi.APIResponse) error {
+	var quotes []allyapi.OptionQuote
+	if resp.Response.Quotes != nil {
+		for _, q := range resp.Response.Quotes.Quote {
+			oq, err := allyapi.NewOptionQuote(q)
+			if err != nil {
+				continue
+			}
+			quotes = append(quotes, *oq)
+		}
+	}
+	return o.printOptionQuoteList(quotes)
+}
+
+func (o *outputFlags) printOptionQuoteList(quotes []allyapi.OptionQuote) error {
+	switch {
+	case o.tmpl != nil:
+		items := make([]interface{}, len(quotes))
+		for i := range quotes {
+			items[i] = &quotes[i]
+		}
+		return writeTemplate(os.Stdout, o.tmpl, items...)
+	case o.format == "json":
+		if quotes == nil {
+			quotes = []allyapi.OptionQuote{}
+		}
+		b, err := json.MarshalIndent(quotes, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	case o.format == "ndjson":
+		enc := json.NewEncoder(os.Stdout)
+		for i := range quotes {
+			if err := enc.Encode(&quotes[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	rows := make([]map[string]string, len(quotes))
+	for i := range quotes {
+		rows[i] = optionQuoteRow(&quotes[i])
+	}
+	return o.printRows(optionQuoteColumns, rows)
+}
+
+// Parse a range of absolute deltas, e.g. 0.2-0.4
+func parseDeltaRange(s string) (lo, hi float64, err error) {
+	los, his, ok := strings.Cut(s, "-")
+	if ok {
+		lo, err = strconv.ParseFloat(los, 64)
+		if err == nil {
+			hi, err = strconv.ParseFloat(his, 64)
+		}
+	}
+	if !ok || err != nil || lo < 0 || hi > 1 || lo > hi {
+		return 0, 0, fmt.Errorf("invalid delta range %q: expected e.g. 0.2-0.4", s)
+	}
+	return lo, hi, nil
+}
+
+func optionsChainCommand() *command {
+	cmd := newCommand("chain", "options chain [flags] SYMBOL", "Show quotes and greeks for options on SYMBOL that expire on one date")
+	output := addOutputFlags(cmd.flags)
+	expFlag := cmd.flags.String("expiration", "", "Expiration date (default: the nearest one)")
+	typeFlag := cmd.flags.String("type", "both", "Options to show: call, put, or both")
+	delta := cmd.flags.String("delta", "", "Range of absolute deltas to show, e.g. 0.2-0.4")
+	otm := cmd.flags.Bool("otm", false, "Only show options that are out of the money")
+	minOI := cmd.flags.Int64("min-oi", 0, "Only show options with at least this much open interest")
+	cmd.completeFlags = map[string]func() []string{
+		"type": completeWords("call", "put", "both"),
+	}
+
+	cmd.run = func(args []string) error {
+		if len(args) != 1 {
+			cmd.printUsage()
+			return usageError("expected a symbol")
+		}
+		symbol := strings.ToUpper(args[0])
+		if err := output.validate(); err != nil {
+			return err
+		}
+
+		var types []allyapi.OptionType
+		switch *typeFlag {
+		case "call":
+			types = []allyapi.OptionType{allyapi.Call}
+		case "put":
+			types = []allyapi.OptionType{allyapi.Put}
+		case "both":
+			types = []allyapi.OptionType{allyapi.Call, allyapi.Put}
+		default:
+			return usageError(fmt.Sprintf("invalid option type %q", *typeFlag))
+		}
+
+		filter := allyapi.ChainFilter{OTM: *otm, MinOpenInterest: *minOI}
+		if *delta != "" {
+			var err error
+			if filter.MinDelta, filter.MaxDelta, err = parseDeltaRange(*delta); err != nil {
+				return usageError(err.Error())
+			}
+		}
+
+		client := newClient()
+		defer client.Wait()
+
+		var exp time.Time
+		if *expFlag != "" {
+			var err error
+			if exp, err = parseDate(*expFlag); err != nil {
+				return usageError(err.Error())
+			}
+		} else {
+			expirations, err := client.OptionExpirations(symbol)
+			if err != nil {
+				return fmt.Errorf("error getting expirations: %v", err)
+			}
+			today := time.Now().In(allyapi.MarketTime).Format("2006-01-02")
+			for _, e := range expirations {
+				if e.Format("2006-01-02") >= today {
+					exp = e
+					break
+				}
+			}
+			if exp.IsZero() {
+				return fmt.Errorf("no options on %v", symbol)
+			}
+		}
+
+		if filter.OTM {
+			resp, err := client.GetQuotes([]string{symbol}, []string{"symbol", "last"})
+			if err != nil {
+				return fmt.Errorf("error getting quote: %v", err)
+			}
+			if resp.Response.Quotes == nil || len(resp.Response.Quotes.Quote) == 0 {
+				return fmt.Errorf("%w: %v", errInvalidSymbol, symbol)
+			}
+			filter.Underlying = parseDecimal(resp.Response.Quotes.Quote[0]["last"])
+		}
+
+		var chain []allyapi.OptionQuote
+		for _, typ := range types {
+			quotes, err := client.OptionChain(symbol, exp, typ)
+			if err != nil {
+				return fmt.Errorf("error getting option chain: %v", err)
+			}
+			chain = append(chain, allyapi.FilterChain(quotes, filter)...)
+		}
+		return output.printOptionQuoteList(chain)
+	}
+	return cmd
+}
+
+func optionsSymbolCommand() *command {
+	cmd := newCommand("symbol", "options symbol UNDERLYING EXPIRATION call|put STRIKE", "Print the OCC symbol of an option, e.g. for AAPL 2025-01-17 call 200")
+	cmd.completeArgs = completeWords("call", "put")
+	cmd.run = func(args []string) error {
+		if len(args) != 4 {
+			cmd.printUsage()
+			return usageError("expected underlying, expiration, type, and strike")
+		}
+		exp, err := parseDate(args[1])
+		if err != nil {
+			return usageError(err.Error())
+		}
+		typ, err := allyapi.ParseOptionType(args[2])
+		if err != nil {
+			return usageError(err.Error())
+		}
+		strike, err := allyapi.ParseDecimal(args[3])
+		if err != nil || strike <= 0 {
+			return usageError(fmt.Sprintf("invalid strike %q", args[3]))
+		}
+
+		fmt.Println(allyapi.OptionSymbol{
+			Underlying: strings.ToUpper(args[0]),
+			Expiration: exp,
+			Type:       typ,
+			Strike:     strike,
+		})
+		return nil
+	}
+	return cmd
+}
+
+func optionsParseCommand() *command {
+	cmd := newCommand("parse", "options parse [flags] SYMBOL...", "Print the underlying, expiration, type, and strike of OCC option symbols")
+	output := addOutputFlags(cmd.flags)
+	cmd.run = func(args []string) error {
+		if len(args) == 0 {
+			cmd.printUsage()
+			return usageError("expected an option symbol")
+		}
+		if err := output.validate(); err != nil {
+			return err
+		}
+
+		var rows []map[string]string
+		for _, arg := range args {
+			o, err := allyapi.ParseOptionSymbol(arg)
+			if err != nil {
+				return usageError(err.Error())
+			}
+			rows = append(rows, optionRow(o))
+		}
+		return output.printRows(optionColumns, rows)
+	}
+	return cmd
+}
+
+func optionsCommand() *command {
+	cmd := newCommand("options", "options COMMAND [flags]", "Work with options and their symbols")
+	cmd.commands = []*command{
+		optionsChainCommand(),
+		optionsSymbolCommand(),
+		optionsParseCommand(),
+	}
+	return cmd
+}
@@ -0,0 +1,643 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+)
+
+func addOrderFlags(fs *flag.FlagSet) *allyapi.Order {
+	o := &allyapi.Order{}
+	fs.StringVar(&o.Account, "account", "", accountFlagUsage)
+	fs.StringVar(&o.Symbol, "symbol", "", "Symbol to trade")
+	fs.StringVar(&o.Side, "side", "buy", "Order side: buy, sell, sell_short, or buy_to_cover")
+	fs.StringVar(&o.Type, "type", "market", "Order type: market, limit, stop, stop_limit, or trailing_stop")
+	fs.IntVar(&o.Quantity, "qty", 0, "Number of shares")
+	fs.Var(&o.Price, "price", "Limit price")
+	fs.Var(&o.StopPrice, "stop", "Stop price")
+	fs.Var(&o.TrailAmount, "trail", "Dollar amount a trailing stop follows the price by")
+	fs.Float64Var(&o.TrailPercent, "trail-pct", 0, "Percent a trailing stop follows the price by")
+	fs.StringVar(&o.TimeInForce, "tif", "day", "Time in force: day, gtc (good til canceled), or moc (market on close)")
+	return o
+}
+
+// Buying power check from the config file, used when -buying-power isn't
+// given
+var defaultBuyingPowerCheck string
+
+// How long after placing an order an identical one is refused without -force
+var duplicateWindow = time.Minute
+
+// Flags for the checks made before placing an order
+type placeFlags struct {
+	mode        string
+	allowMargin bool
+	force       bool
+	fs          *flag.FlagSet
+}
+
+func addPlaceFlags(fs *flag.FlagSet) *placeFlags {
+	p := &placeFlags{fs: fs}
+	fs.StringVar(&p.mode, "buying-power", "off", "Check the order's cost against the account's cash first: off, warn, or refuse (default from the config file's buying_power_check, if set)")
+	fs.BoolVar(&p.allowMargin, "allow-margin", false, "Check against margin buying power instead of cash")
+	fs.BoolVar(&p.force, "force", false, "Place the order even if an identical one was just placed")
+	return p
+}
+
+// Refuse duplicate orders unless forced
+func (p *placeFlags) guard(client *allyapi.Client) {
+	if !p.force {
+		client.DuplicateWindow = duplicateWindow
+	}
+}
+
+// Run a buying power check, which only logs its failure in warn mode
+func (p *placeFlags) checkBuyingPower(check func(allowMargin bool) error) error {
+	given := false
+	p.fs.Visit(func(f *flag.Flag) { given = given || f.Name == "buying-power" })
+	mode := p.mode
+	if !given && defaultBuyingPowerCheck != "" {
+		mode = defaultBuyingPowerCheck
+	}
+
+	switch mode {
+	case "off":
+		return nil
+	case "warn", "refuse":
+	default:
+		return usageError(fmt.Sprintf("invalid buying power check: %q", mode))
+	}
+	err := check(p.allowMargin)
+	if err != nil && mode == "warn" {
+		slog.Warn("buying power check failed", "error", err)
+		return nil
+	}
+	return err
+}
+
+// Place an order, posting a notification to n, if not nil, once it is placed
+func placeOrder(o *allyapi.Order, pf *placeFlags, n notifier) error {
+	client := newClient()
+	defer client.Wait()
+	pf.guard(client)
+
+	account, err := defaultAccount(client, o.Account)
+	if err != nil {
+		return err
+	}
+	o.Account = account
+	if err := pf.checkBuyingPower(func(allowMargin bool) error { return client.CheckBuyingPower(o, allowMargin) }); err != nil {
+		return err
+	}
+	resp, err := client.PlaceOrder(o)
+	if errors.Is(err, allyapi.ErrDryRun) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error placing order: %w", err)
+	}
+
+	if n != nil {
+		if err := n.notify(orderNotification(o, resp)); err != nil {
+			slog.Warn("error sending notification", "error", err)
+		}
+	}
+	return printResponse(resp)
+}
+
+func previewOrder(o *allyapi.Order) error {
+	client := newClient()
+	defer client.Wait()
+
+	account, err := defaultAccount(client, o.Account)
+	if err != nil {
+		return err
+	}
+	o.Account = account
+	resp, err := client.PreviewOrder(o)
+	if errors.Is(err, allyapi.ErrDryRun) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error previewing order: %w", err)
+	}
+	return printResponse(resp)
+}
+
+func addNotifyFlag(fs *flag.FlagSet) func() (notifier, error) {
+	desktop := fs.Bool("notify", false, "Post a notification when the order is placed or filled (macOS)")
+	return func() (notifier, error) {
+		if !*desktop {
+			return nil, nil
+		}
+		return newDesktopNotifier()
+	}
+}
+
+func orderPlaceCommand() *command {
+	cmd := newCommand("place", "orders place [flags]", "Place an order")
+	o := addOrderFlags(cmd.flags)
+	pf := addPlaceFlags(cmd.flags)
+	notify := addNotifyFlag(cmd.flags)
+	cmd.run = func(args []string) error {
+		n, err := notify()
+		if err != nil {
+			return err
+		}
+		return placeOrder(o, pf, n)
+	}
+	return cmd
+}
+
+// FIXML order status for a filled order
+const orderStatusFilled = "2"
+
+// Describe a placed order for a notification
+func orderNotification(o *allyapi.Order, resp *allyapi.APIResponse) (title, message string) {
+	title = "Order placed"
+	if resp.Response.OrderStatus == orderStatusFilled {
+		title = "Order filled"
+	}
+	message = fmt.Sprintf("%v %v %v (%v)", strings.ReplaceAll(o.Side, "_", " "), o.Quantity, strings.ToUpper(o.Symbol), o.Type)
+	if resp.Response.ClientOrderID != "" {
+		message += ", order " + resp.Response.ClientOrderID
+	}
+	return title, message
+}
+
+func orderPreviewCommand() *command {
+	cmd := newCommand("preview", "orders preview [flags]", "Preview the cost and commission of an order without placing it")
+	o := addOrderFlags(cmd.flags)
+	cmd.run = func(args []string) error {
+		return previewOrder(o)
+	}
+	return cmd
+}
+
+// Orders to close the position opened by entry at a limit price takeProfit
+// and a stop price stopLoss, leaving out those that are zero. They have the
+// entry's time in force, unless it is market on close.
+func exitOrders(entry *allyapi.Order, takeProfit, stopLoss allyapi.Decimal) []allyapi.Order {
+	side := "sell"
+	if entry.Side == "sell_short" {
+		side = "buy_to_cover"
+	}
+	exit := allyapi.Order{Account: entry.Account, Symbol: entry.Symbol, Side: side, Quantity: entry.Quantity, TimeInForce: entry.TimeInForce}
+	if strings.EqualFold(exit.TimeInForce, "moc") {
+		exit.TimeInForce = "day"
+	}
+
+	var exits []allyapi.Order
+	if takeProfit > 0 {
+		o := exit
+		o.Type, o.Price = "limit", takeProfit
+		exits = append(exits, o)
+	}
+	if stopLoss > 0 {
+		o := exit
+		o.Type, o.StopPrice = "stop", stopLoss
+		exits = append(exits, o)
+	}
+	return exits
+}
+
+// Place or preview an order group. The first order's cost is checked against
+// buying power before placing it.
+func submitOrderGroup(g *allyapi.OrderGroup, pf *placeFlags, preview bool) error {
+	client := newClient()
+	defer client.Wait()
+	pf.guard(client)
+
+	account, err := defaultAccount(client, g.Account())
+	if err != nil {
+		return err
+	}
+	for i := range g.Orders {
+		g.Orders[i].Account = account
+	}
+	if !preview {
+		if err := pf.checkBuyingPower(func(allowMargin bool) error { return client.CheckBuyingPower(&g.Orders[0], allowMargin) }); err != nil {
+			return err
+		}
+	}
+
+	submit, action := client.PlaceOrderGroup, "placing"
+	if preview {
+		submit, action = client.PreviewOrderGroup, "previewing"
+	}
+	resp, err := submit(g)
+	if errors.Is(err, allyapi.ErrDryRun) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error %v orders: %w", action, err)
+	}
+	return printResponse(resp)
+}
+
+func addExitFlags(fs *flag.FlagSet) (takeProfit, stopLoss *allyapi.Decimal) {
+	takeProfit, stopLoss = new(allyapi.Decimal), new(allyapi.Decimal)
+	fs.Var(takeProfit, "take-profit", "Limit price at which to close the position")
+	fs.Var(stopLoss, "stop-loss", "Stop price at which to close the position")
+	return takeProfit, stopLoss
+}
+
+func orderBracketCommand() *command {
+	cmd := newCommand("bracket", "orders bracket [flags]", "Place an order that, once filled, places a profit target and stop loss")
+	cmd.footer = "With both -take-profit and -stop-loss, filling either cancels the other."
+	o := addOrderFlags(cmd.flags)
+	takeProfit, stopLoss := addExitFlags(cmd.flags)
+	pf := addPlaceFlags(cmd.flags)
+	preview := cmd.flags.Bool("preview", false, "Preview the orders instead of placing them")
+	cmd.run = func(args []string) error {
+		g := &allyapi.OrderGroup{Orders: append([]allyapi.Order{*o}, exitOrders(o, *takeProfit, *stopLoss)...)}
+		switch len(g.Orders) {
+		case 1:
+			return usageError("expected -take-profit, -stop-loss, or both")
+		case 2:
+			g.Kind = allyapi.OTO
+		default:
+			g.Kind = allyapi.OTOCO
+		}
+		return submitOrderGroup(g, pf, *preview)
+	}
+	return cmd
+}
+
+func orderOCOCommand() *command {
+	cmd := newCommand("oco", "orders oco [flags]", "Close a position at a profit target or stop loss, whichever comes first")
+	account := addAccountFlag(cmd.flags)
+	symbol := cmd.flags.String("symbol", "", "Symbol of the position")
+	qty := cmd.flags.Int("qty", 0, "Number of shares to close")
+	short := cmd.flags.Bool("short", false, "Close a short position")
+	takeProfit, stopLoss := addExitFlags(cmd.flags)
+	pf := addPlaceFlags(cmd.flags)
+	preview := cmd.flags.Bool("preview", false, "Preview the orders instead of placing them")
+	cmd.run = func(args []string) error {
+		if *takeProfit <= 0 || *stopLoss <= 0 {
+			return usageError("expected -take-profit and -stop-loss")
+		}
+		position := &allyapi.Order{Account: *account, Symbol: *symbol, Side: "buy", Quantity: *qty}
+		if *short {
+			position.Side = "sell_short"
+		}
+		g := &allyapi.OrderGroup{Kind: allyapi.OCO, Orders: exitOrders(position, *takeProfit, *stopLoss)}
+		return submitOrderGroup(g, pf, *preview)
+	}
+	return cmd
+}
+
+// Parse a leg given as SIDE:SYMBOL[:RATIO], e.g. sell:AAPL250117C00210000
+func parseLeg(s string) (allyapi.Leg, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return allyapi.Leg{}, fmt.Errorf("invalid leg %q: use SIDE:SYMBOL[:RATIO]", s)
+	}
+	leg := allyapi.Leg{Side: strings.ToLower(parts[0]), Symbol: strings.ToUpper(parts[1])}
+	if len(parts) == 3 {
+		ratio, err := strconv.Atoi(parts[2])
+		if err != nil || ratio < 1 {
+			return allyapi.Leg{}, fmt.Errorf("invalid leg %q: bad ratio", s)
+		}
+		leg.Ratio = ratio
+	}
+	return leg, nil
+}
+
+// Place or preview a multi-leg order
+func submitMultiLegOrder(m *allyapi.MultiLegOrder, pf *placeFlags, preview bool) error {
+	client := newClient()
+	defer client.Wait()
+	pf.guard(client)
+
+	account, err := defaultAccount(client, m.Account)
+	if err != nil {
+		return err
+	}
+	m.Account = account
+	if !preview {
+		if err := pf.checkBuyingPower(func(allowMargin bool) error { return client.CheckMultiLegBuyingPower(m, allowMargin) }); err != nil {
+			return err
+		}
+	}
+
+	submit, action := client.PlaceMultiLegOrder, "placing"
+	if preview {
+		submit, action = client.PreviewMultiLegOrder, "previewing"
+	}
+	resp, err := submit(m)
+	if errors.Is(err, allyapi.ErrDryRun) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error %v order: %w", action, err)
+	}
+	return printResponse(resp)
+}
+
+// Place a spread from leg specifications. Flags may come before or after the
+// legs.
+func orderSpreadCommand() *command {
+	cmd := newCommand("spread", "orders spread [flags] LEG LEG...", "Place a multi-leg option order, such as a vertical, calendar, or straddle")
+	cmd.footer = "Each LEG is SIDE:SYMBOL[:RATIO], where SIDE is buy, sell, sell_short, or\n" +
+		"buy_to_cover as for orders place, and RATIO is the contracts (or shares) of\n" +
+		"the leg per spread, 1 by default. For example, a bull call spread:\n" +
+		"  allyapi orders spread -price 3.10 buy:AAPL250117C00200000 sell_short:AAPL250117C00210000\n\n" +
+		"-price is the net price of one spread: positive for a debit, negative for a credit."
+	m := &allyapi.MultiLegOrder{}
+	cmd.flags.StringVar(&m.Account, "account", "", accountFlagUsage)
+	cmd.flags.StringVar(&m.Type, "type", "limit", "Order type: market or limit")
+	cmd.flags.IntVar(&m.Quantity, "qty", 1, "Number of spreads")
+	cmd.flags.Var(&m.Price, "price", "Net limit price of one spread, negative for a credit")
+	cmd.flags.StringVar(&m.TimeInForce, "tif", "day", "Time in force: day or gtc (good til canceled)")
+	pf := addPlaceFlags(cmd.flags)
+	preview := cmd.flags.Bool("preview", false, "Preview the order instead of placing it")
+
+	cmd.run = func(args []string) error {
+		var specs []string
+		for len(args) > 0 {
+			specs = append(specs, args[0])
+			cmd.flags.Parse(args[1:])
+			args = cmd.flags.Args()
+		}
+		if len(specs) < 2 {
+			cmd.printUsage()
+			return usageError("expected at least two legs")
+		}
+		m.Legs = nil
+		for _, spec := range specs {
+			leg, err := parseLeg(spec)
+			if err != nil {
+				return usageError(err.Error())
+			}
+			m.Legs = append(m.Legs, leg)
+		}
+		return submitMultiLegOrder(m, pf, *preview)
+	}
+	return cmd
+}
+
+var ladderColumns = []string{"n", "symbol", "side", "qty", "price", "value", "tif"}
+
+// Place a series of limit orders across a price range, after showing them
+func orderLadderCommand() *command {
+	cmd := newCommand("ladder", "orders ladder [flags]", "Place a series of limit orders across a price range")
+	cmd.footer = "For example, five buys of 20 shares each from $95 down to $90:\n" +
+		"  allyapi orders ladder -symbol AAPL -qty 20 -from 95 -to 90 -count 5\n\n" +
+		"The orders are only shown unless -place is given, and then asked about\n" +
+		"before they are placed unless -yes is also given."
+	output := addOutputFlags(cmd.flags)
+	o := &allyapi.Order{}
+	cmd.flags.StringVar(&o.Account, "account", "", accountFlagUsage)
+	cmd.flags.StringVar(&o.Symbol, "symbol", "", "Symbol to trade")
+	cmd.flags.StringVar(&o.Side, "side", "buy", "Order side: buy, sell, sell_short, or buy_to_cover")
+	cmd.flags.IntVar(&o.Quantity, "qty", 0, "Number of shares in each order")
+	cmd.flags.StringVar(&o.TimeInForce, "tif", "day", "Time in force: day or gtc (good til canceled)")
+	var from, to allyapi.Decimal
+	cmd.flags.Var(&from, "from", "Limit price of the first order")
+	cmd.flags.Var(&to, "to", "Limit price of the last order")
+	count := cmd.flags.Int("count", 5, "Number of orders")
+	place := cmd.flags.Bool("place", false, "Place the orders after showing them")
+	yes := cmd.flags.Bool("yes", false, "With -place, place the orders without asking")
+	pf := addPlaceFlags(cmd.flags)
+
+	cmd.run = func(args []string) error {
+		if o.Symbol == "" || o.Quantity <= 0 {
+			return usageError("expected -symbol and -qty")
+		}
+		if err := output.validate(); err != nil {
+			return err
+		}
+		o.Symbol = strings.ToUpper(o.Symbol)
+		orders, err := allyapi.Ladder(o, from, to, *count)
+		if err != nil {
+			return usageError(err.Error())
+		}
+
+		rows := make([]map[string]string, len(orders))
+		var total allyapi.Decimal
+		for i, lo := range orders {
+			value := lo.Price.MulInt(int64(lo.Quantity) * allyapi.Multiplier(lo.Symbol))
+			total += value
+			rows[i] = map[string]string{
+				"n":      strconv.Itoa(i + 1),
+				"symbol": lo.Symbol,
+				"side":   lo.Side,
+				"qty":    strconv.Itoa(lo.Quantity),
+				"price":  lo.Price.StringFixed(2),
+				"value":  value.StringFixed(2),
+				"tif":    lo.TimeInForce,
+			}
+		}
+		if err := output.printRows(ladderColumns, rows); err != nil {
+			return err
+		}
+		if !*place {
+			return nil
+		}
+
+		client := newClient()
+		defer client.Wait()
+		pf.guard(client)
+
+		account, err := defaultAccount(client, o.Account)
+		if err != nil {
+			return err
+		}
+		for i := range orders {
+			orders[i].Account = account
+		}
+		if err := pf.checkBuyingPower(func(allowMargin bool) error { return client.CheckOrdersBuyingPower(orders, allowMargin) }); err != nil {
+			return err
+		}
+		if !*yes && !*dryRunFlag {
+			answer, err := prompt(fmt.Sprintf("Place these %v orders, %v in all? [y/N]", len(orders), total.StringFixed(2)))
+			if err != nil && err != io.EOF {
+				return err
+			}
+			if !strings.HasPrefix(strings.ToLower(answer), "y") {
+				return nil
+			}
+		}
+
+		// Stop at the first failure, so that what was placed is clear
+		for i := range orders {
+			lo := &orders[i]
+			resp, err := client.PlaceOrder(lo)
+			if errors.Is(err, allyapi.ErrDryRun) {
+				continue
+			} else if err != nil {
+				return fmt.Errorf("error placing order %v of %v, at %v: %w", i+1, len(orders), lo.Price.StringFixed(2), err)
+			}
+			fmt.Printf("Placed %v %v %v at %v, order %v\n", strings.ReplaceAll(lo.Side, "_", " "), lo.Quantity, lo.Symbol, lo.Price.StringFixed(2), resp.Response.ClientOrderID)
+		}
+		return nil
+	}
+	return cmd
+}
+
+var orderTemplateColumns = []string{"name", "account", "symbol", "side", "type", "qty", "price", "stop", "trail", "tif"}
+
+func loadOrderTemplates() (map[string]allyapi.Order, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error loading config file: %v", err)
+	}
+	return cfg.Templates, nil
+}
+
+func orderTemplatesCommand() *command {
+	cmd := newCommand("templates", "orders templates [flags]", "List the order templates in the config file")
+	output := addOutputFlags(cmd.flags)
+	cmd.run = func(args []string) error {
+		if err := output.validate(); err != nil {
+			return err
+		}
+		templates, err := loadOrderTemplates()
+		if err != nil {
+			return err
+		}
+
+		names := make([]string, 0, len(templates))
+		for name := range templates {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var rows []map[string]string
+		for _, name := range names {
+			t := templates[name]
+			row := map[string]string{
+				"name":    name,
+				"account": t.Account,
+				"symbol":  strings.ToUpper(t.Symbol),
+				"side":    t.Side,
+				"type":    t.Type,
+				"tif":     t.TimeInForce,
+			}
+			if t.Quantity != 0 {
+				row["qty"] = strconv.Itoa(t.Quantity)
+			}
+			if t.Price != 0 {
+				row["price"] = t.Price.StringFixed(2)
+			}
+			if t.StopPrice != 0 {
+				row["stop"] = t.StopPrice.StringFixed(2)
+			}
+			if t.TrailAmount != 0 {
+				row["trail"] = t.TrailAmount.StringFixed(2)
+			} else if t.TrailPercent != 0 {
+				row["trail"] = strconv.FormatFloat(t.TrailPercent, 'f', -1, 64) + "%"
+			}
+			rows = append(rows, row)
+		}
+		return output.printRows(orderTemplateColumns, rows)
+	}
+	return cmd
+}
+
+// Place or preview an order from a template. Order flags may come before or
+// after the template name.
+func orderFromTemplateCommand() *command {
+	cmd := newCommand("from-template", "orders from-template [flags] NAME [flags]", "Place an order from a template in the config file")
+	o := addOrderFlags(cmd.flags)
+	preview := cmd.flags.Bool("preview", false, "Preview the order instead of placing it")
+	pf := addPlaceFlags(cmd.flags)
+	notify := addNotifyFlag(cmd.flags)
+	cmd.completeArgs = func() []string {
+		templates, _ := loadOrderTemplates()
+		var names []string
+		for name := range templates {
+			names = append(names, name)
+		}
+		return names
+	}
+
+	cmd.run = func(args []string) error {
+		if len(args) == 0 {
+			cmd.printUsage()
+			return usageError("expected a template name")
+		}
+		name := args[0]
+		cmd.flags.Parse(args[1:])
+		if cmd.flags.NArg() > 0 {
+			return usageError(fmt.Sprintf("unexpected arguments: %v", strings.Join(cmd.flags.Args(), " ")))
+		}
+
+		templates, err := loadOrderTemplates()
+		if err != nil {
+			return err
+		}
+		t, ok := templates[name]
+		if !ok {
+			return usageError(fmt.Sprintf("unknown order template: %q", name))
+		}
+
+		// Flags given override the template, and flag defaults fill in the
+		// side and type if it has none
+		flags := *o
+		*o = t
+		cmd.flags.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "account":
+				o.Account = flags.Account
+			case "symbol":
+				o.Symbol = flags.Symbol
+			case "side":
+				o.Side = flags.Side
+			case "type":
+				o.Type = flags.Type
+			case "qty":
+				o.Quantity = flags.Quantity
+			case "price":
+				o.Price = flags.Price
+			case "stop":
+				o.StopPrice = flags.StopPrice
+			case "trail":
+				o.TrailAmount = flags.TrailAmount
+			case "trail-pct":
+				o.TrailPercent = flags.TrailPercent
+			case "tif":
+				o.TimeInForce = flags.TimeInForce
+			}
+		})
+		if o.Side == "" {
+			o.Side = flags.Side
+		}
+		if o.Type == "" {
+			o.Type = flags.Type
+		}
+
+		if *preview {
+			return previewOrder(o)
+		}
+		n, err := notify()
+		if err != nil {
+			return err
+		}
+		return placeOrder(o, pf, n)
+	}
+	return cmd
+}
+
+func ordersCommand() *command {
+	cmd := newCommand("orders", "orders COMMAND [flags]", "Preview and place orders")
+	cmd.commands = []*command{
+		orderPreviewCommand(),
+		orderPlaceCommand(),
+		orderBracketCommand(),
+		orderOCOCommand(),
+		orderSpreadCommand(),
+		orderLadderCommand(),
+		orderStatusCommand(),
+		orderWatchCommand(),
+		orderLogCommand(),
+		orderFromTemplateCommand(),
+		orderTemplatesCommand(),
+	}
+	return cmd
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+)
+
+var orderStatusColumns = []string{"id", "time", "symbol", "side", "type", "qty", "price", "status", "filled", "avg_price", "remaining"}
+
+func orderStatusRow(o *allyapi.OrderStatus) map[string]string {
+	row := map[string]string{
+		"id":        o.ID,
+		"symbol":    o.Symbol,
+		"side":      o.Side,
+		"type":      o.Type,
+		"qty":       strconv.Itoa(o.Quantity),
+		"status":    o.Status,
+		"filled":    strconv.Itoa(o.Filled),
+		"remaining": strconv.Itoa(o.Remaining),
+	}
+	if !o.Time.IsZero() {
+		row["time"] = o.Time.Format("2006-01-02 15:04:05")
+	}
+	if o.Price != 0 {
+		row["price"] = o.Price.StringFixed(2)
+	} else if o.StopPrice != 0 {
+		row["price"] = o.StopPrice.StringFixed(2)
+	}
+	if o.AvgPrice != 0 {
+		row["avg_price"] = o.AvgPrice.StringFixed(2)
+	}
+	return row
+}
+
+func orderStatusCommand() *command {
+	cmd := newCommand("status", "orders status [flags] [ID...]", "Show the status and fills of an account's recent orders")
+	output := addOutputFlags(cmd.flags)
+	account := addAccountFlag(cmd.flags)
+	cmd.run = func(args []string) error {
+		if err := output.validate(); err != nil {
+			return err
+		}
+
+		client := newClient()
+		defer client.Wait()
+
+		acct, err := defaultAccount(client, *account)
+		if err != nil {
+			return err
+		}
+		orders, err := client.Orders(acct)
+		if err != nil {
+			return fmt.Errorf("error getting orders: %v", err)
+		}
+
+		ids := make(map[string]bool)
+		for _, id := range args {
+			ids[id] = true
+		}
+		var rows []map[string]string
+		for i := range orders {
+			if len(ids) == 0 || ids[orders[i].ID] {
+				rows = append(rows, orderStatusRow(&orders[i]))
+			}
+		}
+		if len(args) > 0 && len(rows) == 0 {
+			return fmt.Errorf("no orders found: %v", strings.Join(args, ", "))
+		}
+		return output.printRows(orderStatusColumns, rows)
+	}
+	return cmd
+}
+
+// Order statuses that are notified when an order changes to them
+var notifiedOrderStatuses = map[string]string{
+	allyapi.StatusPartiallyFilled: "Order partially filled",
+	allyapi.StatusFilled:          "Order filled",
+	allyapi.StatusRejected:        "Order rejected",
+	allyapi.StatusCanceled:        "Order canceled",
+	allyapi.StatusExpired:         "Order expired",
+}
+
+// A change in an order's status, as posted to webhooks and printed with
+// -json
+type orderEvent struct {
+	Time  time.Time           `json:"time"`
+	Event string              `json:"event"`
+	Order allyapi.OrderStatus `json:"order"`
+}
+
+// Describe an order event for a notification
+func (e *orderEvent) describe() (title, message string) {
+	o := e.Order
+	message = fmt.Sprintf("%v %v %v (%v)", strings.ReplaceAll(o.Side, "_", " "), o.Quantity, o.Symbol, o.Type)
+	if o.Filled > 0 {
+		message += fmt.Sprintf(", %v of %v filled", o.Filled, o.Quantity)
+		if o.AvgPrice != 0 {
+			message += " at an average of " + o.AvgPrice.StringFixed(2)
+		}
+	}
+	return notifiedOrderStatuses[e.Event], message + ", order " + o.ID
+}
+
+// Posts order events as JSON
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func newWebhookNotifier(url string) *webhookNotifier {
+	return &webhookNotifier{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (n *webhookNotifier) post(e *orderEvent) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook returned %v: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// Polls an account's orders and sends an event when one fills, partially
+// fills, or stops working
+type orderWatcher struct {
+	notifiers []notifier
+	webhooks  []*webhookNotifier
+	json      bool
+
+	// Last status of each order seen
+	last map[string]allyapi.OrderStatus
+}
+
+// Remember the orders' statuses without sending events, so that only later
+// changes are
+func (w *orderWatcher) seed(orders []allyapi.OrderStatus) {
+	for _, o := range orders {
+		w.last[o.ID] = o
+	}
+}
+
+// Send an event for each order whose status changed to a notified one
+func (w *orderWatcher) update(orders []allyapi.OrderStatus) {
+	for _, o := range orders {
+		prev, seen := w.last[o.ID]
+		w.last[o.ID] = o
+		if seen && prev.Status == o.Status && prev.Filled == o.Filled {
+			continue
+		}
+		if _, ok := notifiedOrderStatuses[o.Status]; ok {
+			w.send(&orderEvent{Time: time.Now(), Event: o.Status, Order: o})
+		}
+	}
+}
+
+func (w *orderWatcher) send(e *orderEvent) {
+	if w.json {
+		json.NewEncoder(os.Stdout).Encode(e)
+	}
+	title, message := e.describe()
+	for _, n := range w.notifiers {
+		if err := n.notify(title, message); err != nil {
+			slog.Warn("error sending notification", "error", err)
+		}
+	}
+	for _, n := range w.webhooks {
+		if err := n.post(e); err != nil {
+			slog.Warn("error posting to webhook", "url", n.url, "error", err)
+		}
+	}
+}
+
+func orderWatchCommand() *command {
+	cmd := newCommand("watch", "orders watch [flags] ID | -all", "Notify when an order fills, partially fills, or is rejected")
+	cmd.footer = "With an order ID, polls until the order fills or stops working. With -all,\n" +
+		"runs until interrupted, notifying of changes to any order in the account.\n\n" +
+		"Webhooks receive each event as JSON, e.g.\n" +
+		"  {\"time\": \"...\", \"event\": \"filled\", \"order\": {\"id\": \"SVI-1\", \"symbol\": \"AAPL\", ...}}"
+	account := addAccountFlag(cmd.flags)
+	all := cmd.flags.Bool("all", false, "Watch every order in the account until interrupted")
+	poll := cmd.flags.Duration("poll", 10*time.Second, "Interval between checks of the order status")
+	asJSON := cmd.flags.Bool("json", false, "Print events as JSON lines instead of text")
+	desktop := cmd.flags.Bool("notify", false, "Also post events to Notification Center (macOS)")
+	var webhooks stringsFlag
+	cmd.flags.Var(&webhooks, "webhook", "URL to POST each event to as JSON; may be repeated")
+
+	cmd.run = func(args []string) error {
+		var id string
+		switch {
+		case *all && len(args) == 0:
+		case !*all && len(args) == 1:
+			id = args[0]
+		default:
+			cmd.printUsage()
+			return usageError("expected an order ID or -all")
+		}
+		if *poll <= 0 {
+			return usageError("-poll must be positive")
+		}
+
+		w := &orderWatcher{json: *asJSON, last: make(map[string]allyapi.OrderStatus)}
+		if !*asJSON {
+			w.notifiers = append(w.notifiers, printNotifier{})
+		}
+		if *desktop {
+			n, err := newDesktopNotifier()
+			if err != nil {
+				return err
+			}
+			w.notifiers = append(w.notifiers, n)
+		}
+		for _, url := range webhooks {
+			w.webhooks = append(w.webhooks, newWebhookNotifier(url))
+		}
+
+		client := newClient()
+		defer client.Wait()
+
+		acct, err := defaultAccount(client, *account)
+		if err != nil {
+			return err
+		}
+
+		// Orders already filled or rejected aren't news when watching them
+		// all, but are when asking after one
+		orders, err := client.Orders(acct)
+		if err != nil {
+			return fmt.Errorf("error getting orders: %v", err)
+		}
+		if *all {
+			w.seed(orders)
+		}
+
+		warned := false
+		for {
+			if id != "" {
+				orders = ordersWithID(orders, id)
+				if len(orders) == 0 && !warned {
+					slog.Warn("order not found; waiting for it to be listed", "id", id)
+					warned = true
+				}
+			}
+			w.update(orders)
+			if id != "" && len(orders) > 0 && orders[0].Done() {
+				return nil
+			}
+
+			for {
+				if !sleep(*poll) {
+					return nil
+				}
+				if orders, err = client.Orders(acct); err == nil {
+					break
+				}
+				slog.Error("error getting orders", "error", err)
+			}
+		}
+	}
+	return cmd
+}
+
+// The orders with an ID
+func ordersWithID(orders []allyapi.OrderStatus, id string) []allyapi.OrderStatus {
+	var matched []allyapi.OrderStatus
+	for _, o := range orders {
+		if o.ID == id {
+			matched = append(matched, o)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/n8henrie/allyapi"
+	"golang.org/x/term"
+)
+
+// Default columns for table and CSV output of quotes
+var quoteColumns = []string{"symbol", "name", "last", "chg", "pchg", "bid", "ask", "vl"}
+
+// Output format from the config file, used when -output isn't given
+var defaultOutput string
+
+// Output flags shared by commands that print results
+type outputFlags struct {
+	format   string
+	template string
+	tmpl     *template.Template
+	fs       *flag.FlagSet
+
+	columns string
+	sortBy  string
+	desc    bool
+	where   stringsFlag
+	filters []rowFilter
+}
+
+func addOutputFlags(fs *flag.FlagSet) *outputFlags {
+	o := &outputFlags{fs: fs}
+	fs.StringVar(&o.format, "output", "json", "Output format: table, csv, json, or ndjson (default from the config file's output, if set)")
+	fs.StringVar(&o.template, "format", "", "Go template used to render each item, e.g. '{{.symbol}} {{.last}}'")
+	fs.StringVar(&o.columns, "columns", "", "Comma-separated fields to show, in order, e.g. symbol,last,bid,ask,volume")
+	fs.StringVar(&o.sortBy, "sort", "", "Sort rows by this field, e.g. pct_change")
+	fs.BoolVar(&o.desc, "desc", false, "Sort in descending order")
+	fs.Var(&o.where, "where", "Only show rows matching a condition such as 'volume>1000000' or 'symbol=AAPL'; may be repeated")
+	return o
+}
+
+// Check the output format and parse the template, if any
+func (o *outputFlags) validate() error {
+	given := false
+	o.fs.Visit(func(f *flag.Flag) { given = given || f.Name == "output" })
+	if !given && defaultOutput != "" {
+		o.format = defaultOutput
+	}
+
+	switch o.format {
+	case "table", "csv", "json", "ndjson":
+	default:
+		return fmt.Errorf("invalid output format: %q", o.format)
+	}
+
+	for _, w := range o.where {
+		f, err := parseRowFilter(w)
+		if err != nil {
+			return err
+		}
+		o.filters = append(o.filters, f)
+	}
+
+	if o.template != "" {
+		tmpl, err := parseFormat(o.template)
+		if err != nil {
+			return fmt.Errorf("invalid format template: %v", err)
+		}
+		o.tmpl = tmpl
+	}
+	return nil
+}
+
+// Write rows with the given columns as an aligned table, as CSV, or as one
+// JSON object per line; see printRows for JSON arrays
+func writeRows(w io.Writer, format string, columns []string, rows []map[string]string) error {
+	switch format {
+	case "table":
+		return writeTable(w, columns, rows, colorTable(w, columns))
+	case "csv":
+		return writeCSV(w, columns, rows)
+	case "ndjson":
+		return writeNDJSON(w, rows)
+	default:
+		return fmt.Errorf("unsupported output format: %q", format)
+	}
+}
+
+// Quote columns colored by the change since the previous close
+var changeColumns = map[string]bool{"last": true, "chg": true, "pchg": true}
+
+// Whether a column, or the field it is an alias of, is colored
+func changeColumn(c string) bool {
+	return changeColumns[c] || changeColumns[fieldAliases[c]]
+}
+
+// Direction of a quote's change since the previous close: 1 for up, -1 for
+// down, or 0
+func changeDirection(row map[string]string) int {
+	switch row["chg_sign"] {
+	case "u":
+		return 1
+	case "d":
+		return -1
+	case "e":
+		return 0
+	}
+	switch chg := parseFloat(row["chg"]); {
+	case chg > 0:
+		return 1
+	case chg < 0:
+		return -1
+	}
+	return 0
+}
+
+// Whether to color a table of the given columns written to w, which is only
+// done for quotes on a terminal
+func colorTable(w io.Writer, columns []string) bool {
+	f, ok := w.(*os.File)
+	if !ok || !colorEnabled() || !term.IsTerminal(int(f.Fd())) {
+		return false
+	}
+	for _, c := range columns {
+		switch c {
+		case "chg", "pchg", "change", "pct_change":
+			return true
+		}
+	}
+	return false
+}
+
+// Write an aligned table, coloring quote columns by the change since the
+// previous close if color is set. Fields are looked up through their aliases,
+// so rows may have more fields than the columns shown.
+func writeTable(w io.Writer, columns []string, rows []map[string]string, color bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	// Every cell of a colored column, including the header, gets escape
+	// codes of the same length so that the columns still line up
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = strings.ToUpper(c)
+		if color && changeColumn(c) {
+			header[i] = ansiDefault + header[i] + ansiReset
+		}
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+
+	for _, row := range rows {
+		dir := changeDirection(row)
+		fields := make([]string, len(columns))
+		for i, c := range columns {
+			fields[i], _ = rowValue(row, c)
+			if color && changeColumn(c) {
+				code := ansiDefault
+				switch {
+				case dir > 0:
+					code = ansiGreen
+				case dir < 0:
+					code = ansiRed
+				}
+				fields[i] = code + fields[i] + ansiReset
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(fields, "\t"))
+	}
+	return tw.Flush()
+}
+
+func writeCSV(w io.Writer, columns []string, rows []map[string]string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		fields := make([]string, len(columns))
+		for i, c := range columns {
+			fields[i] = row[c]
+		}
+		if err := cw.Write(fields); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeNDJSON(w io.Writer, rows []map[string]string) error {
+	enc := json.NewEncoder(w)
+	for _, row := range rows {
+		if err := enc.Encode(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// Parse a user-supplied --format template
+func parseFormat(format string) (*template.Template, error) {
+	return template.New("format").Funcs(templateFuncs).Parse(format)
+}
+
+// Render each item through the template, one per line, skipping items for
+// which it renders nothing
+func writeTemplate(w io.Writer, tmpl *template.Template, items ...interface{}) error {
+	var b strings.Builder
+	for _, item := range items {
+		b.Reset()
+		if err := tmpl.Execute(&b, item); err != nil {
+			return err
+		}
+		if b.Len() > 0 {
+			if _, err := fmt.Fprintln(w, b.String()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Print quotes in the selected output format. Tables and CSV show the given
+// columns, or quoteColumns if there are none.
+func (o *outputFlags) printQuotes(columns []string, resp *allyapi.APIResponse) error {
+	// Sorted or filtered quotes are printed as rows, since the response
+	// can't hold them
+	if o.format == "json" && o.tmpl == nil && o.columns == "" && o.sortBy == "" && len(o.filters) == 0 {
+		return printResponse(resp)
+	}
+
+	var rows []map[string]string
+	if resp.Response.Quotes != nil {
+		rows = resp.Response.Quotes.Quote
+	}
+	if len(columns) == 0 {
+		columns = quoteColumns
+	}
+	return o.printRows(columns, rows)
+}
+
+// Print rows in the selected output format after filtering and sorting them
+// as given by -where and -sort, and with only the fields given by -columns
+func (o *outputFlags) printRows(columns []string, rows []map[string]string) error {
+	columns, rows = o.shapeRows(columns, rows)
+	return printRows(o.format, o.tmpl, columns, rows)
+}
+
+// Filter, sort, and pick the columns of rows as the flags ask
+func (o *outputFlags) shapeRows(columns []string, rows []map[string]string) ([]string, []map[string]string) {
+	rows = filterRows(rows, o.filters)
+	if o.sortBy != "" {
+		sortRows(rows, o.sortBy, o.desc)
+	}
+	if o.columns != "" {
+		columns = parseColumns(o.columns)
+
+		// Tables only show the columns, and need the fields left out, such
+		// as chg_sign, to color rows by
+		if o.format != "table" || o.tmpl != nil {
+			rows = selectColumns(rows, columns)
+		}
+	}
+	return columns, rows
+}
+
+// Print rows in the given output format, or through tmpl if it is not nil;
+// JSON output is an indented array
+func printRows(format string, tmpl *template.Template, columns []string, rows []map[string]string) error {
+	if tmpl != nil {
+		items := make([]interface{}, len(rows))
+		for i, row := range rows {
+			items[i] = row
+		}
+		return writeTemplate(os.Stdout, tmpl, items...)
+	}
+	if format == "json" {
+		if rows == nil {
+			rows = []map[string]string{}
+		}
+		b, err := json.MarshalIndent(rows, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+	return writeRows(os.Stdout, format, columns, rows)
+}
+
+func printResponse(m *allyapi.APIResponse) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+// Print a response as compact JSON on a single line
+func printResponseLine(m *allyapi.APIResponse) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestColumnsKeepColor(t *testing.T) {
+	rows := []map[string]string{
+		{"symbol": "AAPL", "last": "172.62", "chg": "1.20", "chg_sign": "u", "pchg": "0.70 %"},
+		{"symbol": "MSFT", "last": "401.10", "chg": "-2.05", "chg_sign": "d", "pchg": "0.51 %"},
+	}
+	o := &outputFlags{format: "table", columns: "symbol,pct_change"}
+	columns, rows := o.shapeRows(quoteColumns, rows)
+
+	var b bytes.Buffer
+	if err := writeTable(&b, columns, rows, true); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 3 || strings.Contains(lines[0], "LAST") {
+		t.Fatalf("table:\n%v", b.String())
+	}
+	if !strings.Contains(lines[1], ansiGreen+"0.70 %"+ansiReset) {
+		t.Errorf("AAPL isn't green: %q", lines[1])
+	}
+	if !strings.Contains(lines[2], ansiRed+"0.51 %"+ansiReset) {
+		t.Errorf("MSFT isn't red: %q", lines[2])
+	}
+
+	// Other formats only get the columns asked for
+	o.format = "ndjson"
+	if _, rows := o.shapeRows(quoteColumns, rows); len(rows[0]) != 2 || rows[0]["pct_change"] != "0.70 %" {
+		t.Errorf("selected %v", rows[0])
+	}
+}
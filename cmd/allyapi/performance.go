@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/n8henrie/allyapi"
+)
+
+var (
+	performanceColumns      = []string{"from", "to", "start_value", "end_value", "net_flows", "return", "benchmark", "benchmark_return", "excess"}
+	dailyPerformanceColumns = []string{"date", "value", "flow", "return", "benchmark_close", "benchmark_return"}
+)
+
+func formatReturn(r float64) string {
+	return strconv.FormatFloat(r*100, 'f', 2, 64) + "%"
+}
+
+func performanceCommand() *command {
+	cmd := newCommand("performance", "performance [flags]", "Compare the account's time-weighted return to a benchmark's")
+	cmd.footer = "The account's value on each trading day is worked back from its holdings and\n" +
+		"cash now through its history, and valued at daily closes. Deposits,\n" +
+		"withdrawals, and transfers are left out of the return, which is chained\n" +
+		"from each day's to the next. The benchmark's return is its change in price\n" +
+		"over the same days."
+	output := addOutputFlags(cmd.flags)
+	account := addAccountFlag(cmd.flags)
+	fromFlag := cmd.flags.String("from", "1y", "Start of the period, e.g. 2024-01-01, ytd, or 6m")
+	toFlag := cmd.flags.String("to", "", "End of the period (default today)")
+	benchmark := cmd.flags.String("benchmark", "SPY", "Symbol to compare the return to")
+	daily := cmd.flags.Bool("daily", false, "Show the value and cumulative returns on each day")
+
+	cmd.run = func(args []string) error {
+		from, to, err := parseDateRange(*fromFlag, *toFlag)
+		if err != nil {
+			return err
+		}
+		if from.IsZero() {
+			return usageError("expected -from")
+		}
+		if err := output.validate(); err != nil {
+			return err
+		}
+
+		client := newClient()
+		defer client.Wait()
+
+		id, err := defaultAccount(client, *account)
+		if err != nil {
+			return err
+		}
+		p, err := client.Performance(id, from, to, *benchmark)
+		if err != nil {
+			return err
+		}
+
+		if !*daily {
+			return output.printRows(performanceColumns, []map[string]string{{
+				"from":             p.From.Format("2006-01-02"),
+				"to":               p.To.Format("2006-01-02"),
+				"start_value":      p.StartValue.StringFixed(2),
+				"end_value":        p.EndValue.StringFixed(2),
+				"net_flows":        p.NetFlows.StringFixed(2),
+				"return":           formatReturn(p.Return),
+				"benchmark":        p.Benchmark,
+				"benchmark_return": formatReturn(p.BenchmarkReturn),
+				"excess":           formatReturn(p.Return - p.BenchmarkReturn),
+			}})
+		}
+
+		rows := make([]map[string]string, len(p.Values))
+		for i, v := range p.Values {
+			bench := p.BenchmarkCloses[i]
+			rows[i] = map[string]string{
+				"date":             v.Date.Format("2006-01-02"),
+				"value":            v.Value.StringFixed(2),
+				"flow":             v.Flow.StringFixed(2),
+				"return":           formatReturn(allyapi.TimeWeightedReturn(p.Values[:i+1])),
+				"benchmark_close":  bench.StringFixed(2),
+				"benchmark_return": formatReturn(bench.Float64()/p.BenchmarkCloses[0].Float64() - 1),
+			}
+		}
+		return output.printRows(dailyPerformanceColumns, rows)
+	}
+	return cmd
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/n8henrie/allyapi"
+)
+
+var pnlColumns = []string{"symbol", "qty", "cost", "last", "value", "gain", "gain_pct", "day_chg", "weight"}
+
+// A position valued at the latest quote
+type position struct {
+	symbol                 string
+	qty                    float64
+	cost, last, chg        allyapi.Decimal
+	value, gain, dayChange allyapi.Decimal
+}
+
+func formatMoney(d allyapi.Decimal) string {
+	return d.StringFixed(2)
+}
+
+// Parse a price or amount from the API, treating invalid values as zero
+func parseDecimal(s string) allyapi.Decimal {
+	d, _ := allyapi.ParseDecimal(s)
+	return d
+}
+
+func formatPercent(num, denom float64) string {
+	if denom == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(num/denom*100, 'f', 2, 64) + "%"
+}
+
+// Value holdings at the latest quotes
+func valuePositions(client *allyapi.Client, holdings allyapi.Holdings) ([]position, error) {
+	var positions []position
+	var symbols []string
+	for _, h := range holdings {
+		sym := strings.ToUpper(h.Instrument.Sym)
+		positions = append(positions, position{
+			symbol: sym,
+			qty:    parseFloat(h.Qty),
+			cost:   h.CostBasis,
+			last:   h.Price,
+		})
+		symbols = append(symbols, sym)
+	}
+	if len(symbols) == 0 {
+		return nil, nil
+	}
+
+	resp, err := client.GetQuotes(symbols, []string{"symbol", "last", "chg"})
+	if err != nil {
+		return nil, fmt.Errorf("error getting quotes: %v", err)
+	}
+	quotes := make(map[string]map[string]string)
+	if resp.Response.Quotes != nil {
+		for _, q := range resp.Response.Quotes.Quote {
+			quotes[q["symbol"]] = q
+		}
+	}
+
+	for i := range positions {
+		p := &positions[i]
+		if q, ok := quotes[p.symbol]; ok {
+			p.last = parseDecimal(q["last"])
+			p.chg = parseDecimal(q["chg"])
+		}
+		// Option quantities are in contracts, and prices per share
+		qty := allyapi.NewDecimal(p.qty).MulInt(allyapi.Multiplier(p.symbol))
+		p.value = p.last.Mul(qty)
+		p.gain = p.value - p.cost
+		p.dayChange = p.chg.Mul(qty)
+	}
+	return positions, nil
+}
+
+func pnlCommand() *command {
+	cmd := newCommand("pnl", "pnl [flags]", "Show unrealized gain and loss, day change, and weight of each position")
+	output := addOutputFlags(cmd.flags)
+	account := addAccountFlag(cmd.flags)
+
+	cmd.run = func(args []string) error {
+		if err := output.validate(); err != nil {
+			return err
+		}
+
+		client := newClient()
+		defer client.Wait()
+
+		id, err := defaultAccount(client, *account)
+		if err != nil {
+			return err
+		}
+		holdings, err := client.Holdings(id)
+		if err != nil {
+			return fmt.Errorf("error getting holdings: %v", err)
+		}
+		positions, err := valuePositions(client, holdings)
+		if err != nil {
+			return err
+		}
+
+		var total position
+		for _, p := range positions {
+			total.cost += p.cost
+			total.value += p.value
+			total.gain += p.gain
+			total.dayChange += p.dayChange
+		}
+
+		var rows []map[string]string
+		for _, p := range append(positions, total) {
+			row := map[string]string{
+				"symbol":   p.symbol,
+				"cost":     p.cost.StringFixed(2),
+				"value":    p.value.StringFixed(2),
+				"gain":     p.gain.StringFixed(2),
+				"gain_pct": formatPercent(p.gain.Float64(), p.cost.Float64()),
+				"day_chg":  p.dayChange.StringFixed(2),
+				"weight":   formatPercent(p.value.Float64(), total.value.Float64()),
+			}
+			if p.symbol == "" {
+				row["symbol"] = "TOTAL"
+			} else {
+				row["qty"] = strconv.FormatFloat(p.qty, 'f', -1, 64)
+				row["last"] = p.last.StringFixed(2)
+			}
+			rows = append(rows, row)
+		}
+		return output.printRows(pnlColumns, rows)
+	}
+	return cmd
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/n8henrie/allyapi"
+	"github.com/n8henrie/allyapi/allytest"
+)
+
+func holding(symbol, qty, cost string) allyapi.Holding {
+	var h allyapi.Holding
+	h.Instrument.Sym, h.Qty, h.CostBasis = symbol, qty, parseDecimal(cost)
+	return h
+}
+
+func TestValuePositions(t *testing.T) {
+	srv := allytest.NewServer()
+	defer srv.Close()
+	const call = "AAPL250117C00200000"
+	srv.Quotes["AAPL"] = map[string]string{"symbol": "AAPL", "last": "40.00", "chg": "0.50"}
+	srv.Quotes[call] = map[string]string{"symbol": call, "last": "3.20", "chg": "-0.10"}
+
+	// Two contracts bought at 2.50 a share cost $500
+	positions, err := valuePositions(srv.Client(), allyapi.Holdings{
+		holding("AAPL", "10", "350.00"),
+		holding(call, "2", "500.00"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ value, gain, dayChange string }{
+		{"400", "50", "5"},
+		{"640", "140", "-20"},
+	}
+	if len(positions) != len(want) {
+		t.Fatalf("got %d positions, want %d", len(positions), len(want))
+	}
+	for i, w := range want {
+		p := positions[i]
+		if p.value.String() != w.value || p.gain.String() != w.gain || p.dayChange.String() != w.dayChange {
+			t.Errorf("%v: value %v, gain %v, day change %v; want %+v", p.symbol, p.value, p.gain, p.dayChange, w)
+		}
+	}
+}
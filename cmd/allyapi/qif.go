@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/n8henrie/allyapi"
+)
+
+// QIF investment action for a transaction
+func qifAction(t *allyapi.Transaction) string {
+	switch historyRow(t)["side"] {
+	case "buy":
+		return "Buy"
+	case "sell":
+		return "Sell"
+	case "dividend":
+		return "Div"
+	case "interest":
+		return "IntInc"
+	}
+	if t.Amount < 0 {
+		return "XOut"
+	}
+	return "XIn"
+}
+
+// Write transactions as a QIF investment account
+func writeHistoryQIF(w io.Writer, txs allyapi.Transactions) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "!Type:Invst")
+	for i := range txs {
+		t := &txs[i]
+		row := historyRow(t)
+
+		if tm, err := t.Time(); err == nil {
+			fmt.Fprintf(bw, "D%v\n", tm.Format("01/02/2006"))
+		}
+		action := qifAction(t)
+		fmt.Fprintf(bw, "N%v\n", action)
+		if row["symbol"] != "" {
+			fmt.Fprintf(bw, "Y%v\n", row["symbol"])
+		}
+		if row["quantity"] != "" {
+			fmt.Fprintf(bw, "I%v\n", row["price"])
+			fmt.Fprintf(bw, "Q%v\n", row["quantity"])
+		}
+		if fees := parseFloat(row["fees"]); fees != 0 {
+			fmt.Fprintf(bw, "O%v\n", row["fees"])
+		}
+		amount := t.Amount.Abs().StringFixed(2)
+		fmt.Fprintf(bw, "T%v\n", amount)
+		if action == "XIn" || action == "XOut" {
+			fmt.Fprintf(bw, "$%v\n", amount)
+		}
+		if memo := transactionMemo(t); memo != "" {
+			fmt.Fprintf(bw, "M%v\n", memo)
+		}
+		fmt.Fprintln(bw, "^")
+	}
+	return bw.Flush()
+}
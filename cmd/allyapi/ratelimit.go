@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+func rateLimitCommand() *command {
+	cmd := newCommand("ratelimit", "ratelimit", "Show the API rate limit as of the last request")
+	cmd.run = func(args []string) error {
+		client := newClient()
+
+		used, remaining, limit, expires := client.RateLimit()
+		if expires.IsZero() || time.Now().After(expires) {
+			fmt.Println("No rate limit information for the current period")
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "Used:\t%v\n", used)
+		fmt.Fprintf(tw, "Remaining:\t%v\n", remaining)
+		fmt.Fprintf(tw, "Limit:\t%v\n", limit)
+		fmt.Fprintf(tw, "Resets:\t%v\n", expires.Format(time.RFC1123))
+		return tw.Flush()
+	}
+	return cmd
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/n8henrie/allyapi"
+)
+
+// Values of a flag that may be given more than once
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ", ")
+}
+
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+func rawCommand() *command {
+	cmd := newCommand("raw", "raw [flags] METHOD ENDPOINT", "Send a signed request to any endpoint and print the response")
+	cmd.footer = "ENDPOINT is relative to the API's base URL and includes the format, e.g.\n" +
+		"  allyapi raw GET /market/ext/quotes.json -d symbols=AAPL,MSFT"
+	var data stringsFlag
+	cmd.flags.Var(&data, "d", "Parameter as KEY=VALUE, sent in the query or form body; may be repeated")
+
+	cmd.run = func(args []string) error {
+		// Allow flags after the method and endpoint
+		var positional []string
+		for len(args) > 0 {
+			positional = append(positional, args[0])
+			cmd.flags.Parse(args[1:])
+			args = cmd.flags.Args()
+		}
+		if len(positional) != 2 {
+			cmd.printUsage()
+			return usageError("expected a method and an endpoint")
+		}
+		method, endpoint := strings.ToUpper(positional[0]), positional[1]
+		if !strings.HasPrefix(endpoint, "/") {
+			endpoint = "/" + endpoint
+		}
+
+		values := url.Values{}
+		for _, d := range data {
+			k, v, ok := strings.Cut(d, "=")
+			if !ok {
+				return usageError(fmt.Sprintf("invalid parameter %q: use KEY=VALUE", d))
+			}
+			values.Add(k, v)
+		}
+
+		client := newClient()
+		defer client.Wait()
+
+		body, err := client.Raw(method, endpoint, values)
+		if errors.Is(err, allyapi.ErrDryRun) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("error sending request: %w", err)
+		}
+		os.Stdout.Write(body)
+		if len(body) > 0 && body[len(body)-1] != '\n' {
+			fmt.Println()
+		}
+		return nil
+	}
+	return cmd
+}
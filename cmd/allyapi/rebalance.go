@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/n8henrie/allyapi"
+)
+
+var rebalanceColumns = []string{"symbol", "held", "price", "value", "pct", "target_pct", "side", "qty", "trade_value"}
+
+func rebalanceRow(t *allyapi.RebalanceTrade) map[string]string {
+	return map[string]string{
+		"symbol":      t.Symbol,
+		"held":        strconv.Itoa(t.Held),
+		"price":       t.Price.StringFixed(2),
+		"value":       t.Value.StringFixed(2),
+		"pct":         strconv.FormatFloat(t.Percent, 'f', 2, 64),
+		"target_pct":  strconv.FormatFloat(t.TargetPct, 'f', 2, 64),
+		"side":        t.Side,
+		"qty":         strconv.Itoa(t.Quantity),
+		"trade_value": t.TradeValue.StringFixed(2),
+	}
+}
+
+func rebalanceCommand() *command {
+	cmd := newCommand("rebalance", "rebalance [flags]", "Propose the trades that return holdings to a target allocation")
+	cmd.footer = "The target is the percentage of the portfolio in each symbol, from the\n" +
+		"config file's allocation, e.g.\n" +
+		"  [allocation]\n" +
+		"  VTI = 60\n" +
+		"  BND = 30\n\n" +
+		"The portfolio is the cash available and the holdings of those symbols;\n" +
+		"other holdings are left alone, and what isn't allocated is kept in cash.\n" +
+		"The trades are only shown unless -place is given, and then asked about\n" +
+		"before they are placed as market orders, sells first, unless -yes is also\n" +
+		"given."
+	output := addOutputFlags(cmd.flags)
+	account := addAccountFlag(cmd.flags)
+	threshold := cmd.flags.Float64("threshold", 1, "Leave symbols within this many percentage points of their targets")
+	place := cmd.flags.Bool("place", false, "Place the trades after showing them")
+	yes := cmd.flags.Bool("yes", false, "With -place, place the trades without asking")
+	pf := addPlaceFlags(cmd.flags)
+
+	cmd.run = func(args []string) error {
+		if *threshold < 0 {
+			return usageError("-threshold must not be negative")
+		}
+		if err := output.validate(); err != nil {
+			return err
+		}
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("error loading config file: %v", err)
+		}
+		if err := cfg.Allocation.Validate(); err != nil {
+			return fmt.Errorf("%v; see allyapi help rebalance", err)
+		}
+
+		client := newClient()
+		defer client.Wait()
+		pf.guard(client)
+
+		acct, err := defaultAccount(client, *account)
+		if err != nil {
+			return err
+		}
+		trades, err := client.Rebalance(acct, cfg.Allocation, *threshold)
+		if err != nil {
+			return err
+		}
+		if len(trades) == 0 {
+			fmt.Fprintln(os.Stderr, "Holdings are already at their target allocation")
+			return nil
+		}
+
+		rows := make([]map[string]string, len(trades))
+		for i := range trades {
+			rows[i] = rebalanceRow(&trades[i])
+		}
+		if err := output.printRows(rebalanceColumns, rows); err != nil {
+			return err
+		}
+		if !*place {
+			return nil
+		}
+
+		if !*yes && !*dryRunFlag {
+			answer, err := prompt(fmt.Sprintf("Place these %v orders? [y/N]", len(trades)))
+			if err != nil && err != io.EOF {
+				return err
+			}
+			if !strings.HasPrefix(strings.ToLower(answer), "y") {
+				return nil
+			}
+		}
+
+		// Stop at the first failure, so that buys aren't placed without the
+		// sells that fund them
+		for _, t := range trades {
+			o := &allyapi.Order{Account: acct, Symbol: t.Symbol, Side: t.Side, Type: "market", Quantity: t.Quantity, TimeInForce: "day"}
+			if err := pf.checkBuyingPower(func(allowMargin bool) error { return client.CheckBuyingPower(o, allowMargin) }); err != nil {
+				return err
+			}
+			resp, err := client.PlaceOrder(o)
+			if errors.Is(err, allyapi.ErrDryRun) {
+				continue
+			} else if err != nil {
+				return fmt.Errorf("error placing order to %v %v %v: %w", o.Side, o.Quantity, o.Symbol, err)
+			}
+			fmt.Printf("Placed %v %v %v, order %v\n", o.Side, o.Quantity, o.Symbol, resp.Response.ClientOrderID)
+		}
+		return nil
+	}
+	return cmd
+}
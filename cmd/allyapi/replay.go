@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/n8henrie/allyapi"
+)
+
+// Records streamed messages to a file for the replay command
+type recordSink struct {
+	f        *os.File
+	recorder *allyapi.StreamRecorder
+}
+
+func newRecordSink(path string) (*recordSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &recordSink{f: f, recorder: allyapi.NewStreamRecorder(f)}, nil
+}
+
+func (s *recordSink) send(m *allyapi.APIResponse) error {
+	return s.recorder.Record(m)
+}
+
+func (s *recordSink) close() error {
+	return s.f.Close()
+}
+
+func replayCommand() *command {
+	cmd := newCommand("replay", "replay [flags] FILE", "Replay quotes and trades recorded with stream -record")
+	cmd.footer = "Messages are printed as stream prints them, spaced as they arrived. -speed 10\n" +
+		"replays them ten times as fast, and -speed 0 without waiting."
+	output := addOutputFlags(cmd.flags)
+	printFlags := addStreamPrintFlags(cmd.flags)
+	speed := cmd.flags.Float64("speed", 1, "Replay this many times faster than recorded (0 replays without waiting)")
+
+	cmd.run = func(args []string) error {
+		if len(args) != 1 {
+			cmd.printUsage()
+			return usageError("expected a recording")
+		}
+		if *speed < 0 {
+			return usageError("-speed must not be negative")
+		}
+		if err := output.validate(); err != nil {
+			return err
+		}
+
+		handle, done, err := printFlags.handler(output, false)
+		if err != nil {
+			return err
+		}
+		defer done()
+
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := allyapi.Replay(rootCtx, f, *speed, handle); err != nil {
+			return fmt.Errorf("error replaying %v: %v", args[0], err)
+		}
+		return nil
+	}
+	return cmd
+}
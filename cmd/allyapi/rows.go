@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Friendlier names for quote fields, used when rows don't have a field of
+// that name
+var fieldAliases = map[string]string{
+	"change":     "chg",
+	"pct_change": "pchg",
+	"price":      "last",
+	"volume":     "vl",
+}
+
+// A value of a row's field, resolving aliases
+func rowValue(row map[string]string, field string) (string, bool) {
+	if v, ok := row[field]; ok {
+		return v, true
+	}
+	if alias, ok := fieldAliases[field]; ok {
+		v, ok := row[alias]
+		return v, ok
+	}
+	return "", false
+}
+
+// Parse a number as shown in rows, e.g. "$1,234.50" or "-0.52%"
+func parseRowNumber(s string) (float64, bool) {
+	s = strings.NewReplacer("$", "", ",", "", "%", "").Replace(strings.TrimSpace(s))
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil
+}
+
+// Compare two values numerically if both are numbers, or else as
+// case-insensitive strings
+func compareValues(a, b string) int {
+	if x, ok := parseRowNumber(a); ok {
+		if y, ok := parseRowNumber(b); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// A condition on a field of a row, e.g. volume>1000000
+type rowFilter struct {
+	field, op, value string
+}
+
+// Operators of row filters, longest first so that >= isn't read as >
+var rowFilterOps = []string{">=", "<=", "!=", "==", ">", "<", "=", "~"}
+
+func parseRowFilter(s string) (rowFilter, error) {
+	i := strings.IndexAny(s, "<>=!~")
+	if i <= 0 {
+		return rowFilter{}, fmt.Errorf("invalid condition %q: expected FIELD OP VALUE, e.g. volume>1000000", s)
+	}
+	for _, op := range rowFilterOps {
+		if strings.HasPrefix(s[i:], op) {
+			return rowFilter{
+				field: strings.TrimSpace(s[:i]),
+				op:    op,
+				value: strings.TrimSpace(s[i+len(op):]),
+			}, nil
+		}
+	}
+	return rowFilter{}, fmt.Errorf("invalid operator in condition %q; use one of %v", s, strings.Join(rowFilterOps, " "))
+}
+
+// Whether the row meets the condition. Rows without the field never do.
+func (f rowFilter) match(row map[string]string) bool {
+	v, ok := rowValue(row, f.field)
+	if !ok {
+		return false
+	}
+	if f.op == "~" {
+		return strings.Contains(strings.ToLower(v), strings.ToLower(f.value))
+	}
+	c := compareValues(v, f.value)
+	switch f.op {
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case "!=":
+		return c != 0
+	}
+	return c == 0
+}
+
+// Rows that meet all of the conditions
+func filterRows(rows []map[string]string, filters []rowFilter) []map[string]string {
+	if len(filters) == 0 {
+		return rows
+	}
+	var matched []map[string]string
+rows:
+	for _, row := range rows {
+		for _, f := range filters {
+			if !f.match(row) {
+				continue rows
+			}
+		}
+		matched = append(matched, row)
+	}
+	return matched
+}
+
+// Sort rows by a field, keeping rows without it last
+func sortRows(rows []map[string]string, field string, desc bool) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, aok := rowValue(rows[i], field)
+		b, bok := rowValue(rows[j], field)
+		if !aok || !bok {
+			return aok && !bok
+		}
+		if desc {
+			return compareValues(a, b) > 0
+		}
+		return compareValues(a, b) < 0
+	})
+}
+
+// Split a comma-separated list of columns
+func parseColumns(list string) []string {
+	var columns []string
+	for _, c := range strings.Split(list, ",") {
+		if c = strings.ToLower(strings.TrimSpace(c)); c != "" {
+			columns = append(columns, c)
+		}
+	}
+	return columns
+}
+
+// Copies of rows with only the given fields, resolving aliases
+func selectColumns(rows []map[string]string, columns []string) []map[string]string {
+	selected := make([]map[string]string, len(rows))
+	for i, row := range rows {
+		selected[i] = make(map[string]string, len(columns))
+		for _, c := range columns {
+			if v, ok := rowValue(row, c); ok {
+				selected[i][c] = v
+			}
+		}
+	}
+	return selected
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+)
+
+var coveredCallColumns = []string{"symbol", "contracts", "last", "option", "expiration", "days", "strike", "premium", "income", "yield", "annualized", "protection", "if_called"}
+
+// A call that could be sold against shares held
+type coveredCall struct {
+	pos        position
+	contracts  int
+	call       allyapi.OptionQuote
+	days       int
+	yield      float64
+	annualized float64
+}
+
+// Find calls on positions of at least 100 shares that expire within
+// minDays to maxDays and are at least minOTM percent out of the money
+func screenCoveredCalls(client *allyapi.Client, positions []position, minDays, maxDays int, minOTM float64) ([]coveredCall, error) {
+	now := time.Now()
+	var calls []coveredCall
+	for _, p := range positions {
+		contracts := int(p.qty / allyapi.ContractSize)
+		if contracts < 1 || p.last <= 0 || allyapi.IsOptionSymbol(p.symbol) {
+			continue
+		}
+
+		expirations, err := client.OptionExpirations(p.symbol)
+		if err != nil {
+			return nil, fmt.Errorf("error getting expirations of %v: %v", p.symbol, err)
+		}
+		for _, exp := range expirations {
+			days := int(math.Ceil(exp.Sub(now).Hours() / 24))
+			if days < minDays || days > maxDays {
+				continue
+			}
+			chain, err := client.OptionChain(p.symbol, exp, allyapi.Call)
+			if err != nil {
+				return nil, fmt.Errorf("error getting %v option chain: %v", p.symbol, err)
+			}
+			for _, c := range chain {
+				if c.Bid <= 0 || c.Strike < p.last.Mul(allyapi.NewDecimal(1+minOTM/100)) {
+					continue
+				}
+				// Return on the net cost of the shares after the premium
+				yield := c.Bid.Float64() / (p.last - c.Bid).Float64()
+				calls = append(calls, coveredCall{
+					pos:        p,
+					contracts:  contracts,
+					call:       c,
+					days:       days,
+					yield:      yield,
+					annualized: yield * 365 / float64(days),
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(calls, func(i, j int) bool {
+		if calls[i].pos.symbol != calls[j].pos.symbol {
+			return calls[i].pos.symbol < calls[j].pos.symbol
+		}
+		return calls[i].annualized > calls[j].annualized
+	})
+	return calls, nil
+}
+
+func coveredCallRow(c coveredCall) map[string]string {
+	last, premium := c.pos.last, c.call.Bid
+	return map[string]string{
+		"symbol":     c.pos.symbol,
+		"contracts":  strconv.Itoa(c.contracts),
+		"last":       last.StringFixed(2),
+		"option":     c.call.Symbol,
+		"expiration": c.call.Expiration.Format("2006-01-02"),
+		"days":       strconv.Itoa(c.days),
+		"strike":     c.call.Strike.String(),
+		"premium":    premium.StringFixed(2),
+		"income":     premium.MulInt(100 * int64(c.contracts)).StringFixed(2),
+		"yield":      formatPercent(c.yield, 1),
+		"annualized": formatPercent(c.annualized, 1),
+		"protection": formatPercent(premium.Float64(), last.Float64()),
+		"if_called":  formatPercent((c.call.Strike - last + premium).Float64(), (last - premium).Float64()),
+	}
+}
+
+func screenCoveredCallsCommand() *command {
+	cmd := newCommand("covered-calls", "screen covered-calls [flags]", "List calls to sell against positions of 100 shares or more")
+	cmd.footer = "Premium is the bid. Yield is the premium over the net cost of the shares, and\n" +
+		"protection is how far the price can fall before the premium is lost."
+	output := addOutputFlags(cmd.flags)
+	account := addAccountFlag(cmd.flags)
+	minDays := cmd.flags.Int("min-days", 7, "Minimum days to expiration")
+	maxDays := cmd.flags.Int("max-days", 60, "Maximum days to expiration")
+	minOTM := cmd.flags.Float64("min-otm", 0, "Minimum percent the strike is above the last price")
+
+	cmd.run = func(args []string) error {
+		if err := output.validate(); err != nil {
+			return err
+		}
+
+		client := newClient()
+		defer client.Wait()
+
+		id, err := defaultAccount(client, *account)
+		if err != nil {
+			return err
+		}
+		holdings, err := client.Holdings(id)
+		if err != nil {
+			return fmt.Errorf("error getting holdings: %v", err)
+		}
+		positions, err := valuePositions(client, holdings)
+		if err != nil {
+			return err
+		}
+		calls, err := screenCoveredCalls(client, positions, *minDays, *maxDays, *minOTM)
+		if err != nil {
+			return err
+		}
+
+		var rows []map[string]string
+		for _, c := range calls {
+			rows = append(rows, coveredCallRow(c))
+		}
+		return output.printRows(coveredCallColumns, rows)
+	}
+	return cmd
+}
+
+func screenCommand() *command {
+	cmd := newCommand("screen", "screen COMMAND [flags]", "Screen for trading opportunities")
+	cmd.commands = []*command{
+		screenCoveredCallsCommand(),
+	}
+	return cmd
+}
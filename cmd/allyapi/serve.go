@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+	"github.com/n8henrie/allyapi/allyapipb"
+	"google.golang.org/grpc"
+)
+
+// Serves a local HTTP API backed by one client, so that scripts share its
+// session and rate limit
+type apiServer struct {
+	client  *allyapi.Client
+	account string
+
+	// Host name of the address listened on, which requests may also be
+	// addressed to besides localhost and IP addresses
+	host string
+
+	// The stream session shared by clients of /events
+	events eventHub
+}
+
+func (s *apiServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/quotes", s.handleQuotes)
+	mux.HandleFunc("/positions", s.handlePositions)
+	mux.HandleFunc("/orders", s.handleOrders)
+	mux.HandleFunc("/events", s.handleEvents)
+	return s.checkHost(mux)
+}
+
+// Reject requests addressed to other host names, so that a web page whose
+// name has been rebound to a local address can't use the API
+func (s *apiServer) checkHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+		if net.ParseIP(host) == nil && !strings.EqualFold(host, "localhost") && !strings.EqualFold(host, s.host) {
+			http.Error(w, "unrecognized host", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Report whether a request that changes something was made by a script or a
+// page of this server, rather than by another site's page in a browser.
+// Browsers only send JSON cross-origin after a preflight request, which this
+// server never allows, and send the Origin of the page with it.
+func sameOrigin(w http.ResponseWriter, r *http.Request) bool {
+	if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mt != "application/json" {
+		http.Error(w, "expected Content-Type: application/json", http.StatusUnsupportedMediaType)
+		return false
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if u, err := url.Parse(origin); err != nil || !strings.EqualFold(u.Host, r.Host) {
+			http.Error(w, "cross-origin requests not allowed", http.StatusForbidden)
+			return false
+		}
+	}
+	return true
+}
+
+func writeJSONResponse(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// Respond with an error, using the status that best matches its cause
+func writeError(w http.ResponseWriter, err error) {
+	var apiErr *allyapi.APIError
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, allyapi.ErrRateLimited):
+		status = http.StatusTooManyRequests
+	case errors.Is(err, allyapi.ErrCircuitOpen):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, allyapi.ErrDuplicateOrder):
+		status = http.StatusConflict
+	case errors.Is(err, allyapi.ErrUnauthorized), errors.As(err, &apiErr):
+		status = http.StatusBadGateway
+	}
+	writeJSONResponse(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeBadRequest(w http.ResponseWriter, msg string) {
+	writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": msg})
+}
+
+// GET /quotes?symbols=A,B,@WATCHLIST[&fields=last,bid,ask]
+func (s *apiServer) handleQuotes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	symbols, err := expandWatchlists(parseSymbols(r.URL.Query()["symbols"]))
+	if err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
+	if len(symbols) == 0 {
+		writeBadRequest(w, "no symbols given")
+		return
+	}
+	resp, err := s.client.GetQuotes(symbols, parseFields(r.URL.Query().Get("fields")))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	quotes := allyapi.QuoteArray{}
+	if resp.Response.Quotes != nil {
+		quotes = resp.Response.Quotes.Quote
+	}
+	writeJSONResponse(w, http.StatusOK, quotes)
+}
+
+// GET /positions[?account=ID]
+func (s *apiServer) handlePositions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	account := r.URL.Query().Get("account")
+	if account == "" {
+		account = s.account
+	}
+	holdings, err := s.client.Holdings(account)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if holdings == nil {
+		holdings = allyapi.Holdings{}
+	}
+	writeJSONResponse(w, http.StatusOK, holdings)
+}
+
+// POST /orders[?preview=true] with an order as JSON, e.g.
+// {"symbol": "AAPL", "side": "buy", "type": "limit", "quantity": 10, "price": "150.25"}
+// Other fields are account, stop_price, trail_amount, trail_percent, and tif,
+// and force, which places an order identical to one just placed.
+func (s *apiServer) handleOrders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !sameOrigin(w, r) {
+		return
+	}
+	// Fields the order doesn't have are an error rather than ignored, so
+	// that a misspelled stop price can't leave a stop order without one
+	var o struct {
+		allyapi.Order
+		Force bool `json:"force"`
+	}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&o); err != nil {
+		writeBadRequest(w, fmt.Sprintf("invalid order: %v", err))
+		return
+	}
+	if o.Account == "" {
+		o.Account = s.account
+	}
+
+	submit := s.client.PlaceOrder
+	if preview := r.URL.Query().Get("preview"); preview == "true" || preview == "1" {
+		submit = s.client.PreviewOrder
+	} else if o.Force {
+		submit = s.client.PlaceDuplicateOrder
+	}
+	resp, err := submit(&o.Order)
+	switch {
+	case errors.Is(err, allyapi.ErrDryRun):
+		writeJSONResponse(w, http.StatusAccepted, map[string]bool{"dry_run": true})
+	case err != nil:
+		writeError(w, err)
+	default:
+		writeJSONResponse(w, http.StatusOK, resp.Response.OrderResponse)
+	}
+}
+
+// Host name of a listen address, e.g. "myhost" for "myhost:8765"
+func listenHost(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+func serveCommand() *command {
+	cmd := newCommand("serve", "serve [flags]", "Serve a local HTTP or gRPC API that shares one client and rate limit")
+	cmd.footer = "Endpoints:\n" +
+		"  GET  /quotes?symbols=A,B[&fields=last,bid]  quotes as a JSON array\n" +
+		"  GET  /positions[?account=ID]               holdings as a JSON array\n" +
+		"  POST /orders[?preview=true]                place or preview a JSON order, e.g.\n" +
+		"       {\"symbol\": \"AAPL\", \"side\": \"buy\", \"type\": \"limit\", \"quantity\": 10, \"price\": \"150.25\"}\n" +
+		"       with optional account, stop_price, trail_amount, trail_percent, tif,\n" +
+		"       and force\n" +
+		"  GET  /events?symbols=A,B                   stream quotes and trades as server-sent events\n\n" +
+		"The gRPC service is described by allyapipb/allyapi.proto in the source.\n\n" +
+		"Neither API has authentication of its own, so only listen on addresses\n" +
+		"that untrusted users can't reach. HTTP requests must be addressed to\n" +
+		"localhost, an IP address, or the host name of -addr, and orders must be\n" +
+		"sent as application/json from the same origin, so that web pages can't\n" +
+		"use the API.\n\n" +
+		"Orders identical to one placed within the last minute, or the config\n" +
+		"file's duplicate_window, are refused unless force is set."
+	addr := cmd.flags.String("addr", "127.0.0.1:8765", "Address to serve the HTTP API on, or empty for none")
+	grpcAddr := cmd.flags.String("grpc", "", "Address to serve the gRPC API on, e.g. 127.0.0.1:8766")
+	account := addAccountFlag(cmd.flags)
+
+	cmd.run = func(args []string) error {
+		if *addr == "" && *grpcAddr == "" {
+			return usageError("expected -addr, -grpc, or both")
+		}
+
+		client := newClient()
+		defer client.Wait()
+		client.DuplicateWindow = duplicateWindow
+
+		id, err := defaultAccount(client, *account)
+		if err != nil {
+			return err
+		}
+
+		// Both servers stop once interrupted; requests to the API in flight
+		// are cancelled along with it
+		errs := make(chan error, 2)
+		servers := 0
+		if *addr != "" {
+			l, err := net.Listen("tcp", *addr)
+			if err != nil {
+				return err
+			}
+			s := &apiServer{client: client, account: id, host: listenHost(*addr)}
+			srv := &http.Server{Handler: s.handler(), ReadHeaderTimeout: 10 * time.Second}
+			go func() {
+				<-rootCtx.Done()
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				srv.Shutdown(ctx)
+			}()
+			go func() {
+				if err := srv.Serve(l); err != http.ErrServerClosed {
+					errs <- err
+					return
+				}
+				errs <- nil
+			}()
+			servers++
+			slog.Info("serving HTTP API", "addr", l.Addr().String(), "account", id)
+		}
+		if *grpcAddr != "" {
+			l, err := net.Listen("tcp", *grpcAddr)
+			if err != nil {
+				return err
+			}
+			srv := grpc.NewServer()
+			allyapipb.RegisterAllyServer(srv, &grpcServer{client: client, account: id})
+			go func() {
+				<-rootCtx.Done()
+				srv.GracefulStop()
+			}()
+			go func() { errs <- srv.Serve(l) }()
+			servers++
+			slog.Info("serving gRPC API", "addr", l.Addr().String(), "account", id)
+		}
+
+		for ; servers > 0; servers-- {
+			if err := <-errs; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return cmd
+}
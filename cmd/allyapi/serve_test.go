@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+	"github.com/n8henrie/allyapi/allytest"
+)
+
+func TestServeRejectsForeignRequests(t *testing.T) {
+	// Requests that pass the checks are answered by the handler, which fails
+	// without a client here, so any other response is from the checks
+	s := &apiServer{host: "myhost"}
+	h := s.handler()
+
+	for _, tt := range []struct {
+		name        string
+		host        string
+		contentType string
+		origin      string
+		body        string
+		want        int
+	}{
+		{"rebound host", "evil.example:8765", "application/json", "", "{", http.StatusForbidden},
+		{"text/plain", "127.0.0.1:8765", "text/plain", "", "{", http.StatusUnsupportedMediaType},
+		{"no content type", "localhost:8765", "", "", "{", http.StatusUnsupportedMediaType},
+		{"cross origin", "127.0.0.1:8765", "application/json", "http://evil.example", "{", http.StatusForbidden},
+		{"invalid json", "127.0.0.1:8765", "application/json; charset=utf-8", "", "{", http.StatusBadRequest},
+		{"same origin", "myhost:8765", "application/json", "http://myhost:8765", "{", http.StatusBadRequest},
+		{"unknown field", "[::1]:8765", "application/json", "", `{"symbol": "AAPL", "trail_pct": 5}`, http.StatusBadRequest},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(tt.body))
+		r.Host = tt.host
+		if tt.contentType != "" {
+			r.Header.Set("Content-Type", tt.contentType)
+		}
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%v: status %v, want %v", tt.name, w.Code, tt.want)
+		}
+	}
+}
+
+func TestOrderJSON(t *testing.T) {
+	var o allyapi.Order
+	err := json.Unmarshal([]byte(`{"symbol": "AAPL", "side": "sell", "type": "trailing_stop", "quantity": 5,
+		"stop_price": "140.5", "trail_amount": "2.25", "trail_percent": 1.5, "tif": "gtc"}`), &o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := allyapi.Order{Symbol: "AAPL", Side: "sell", Type: "trailing_stop", Quantity: 5,
+		StopPrice: allyapi.NewDecimal(140.5), TrailAmount: allyapi.NewDecimal(2.25), TrailPercent: 1.5, TimeInForce: "gtc"}
+	if o != want {
+		t.Errorf("order = %+v, want %+v", o, want)
+	}
+}
+
+func TestServeRefusesDuplicateOrders(t *testing.T) {
+	api := allytest.NewServer()
+	defer api.Close()
+	client := api.Client()
+	client.AuditLog = filepath.Join(t.TempDir(), "orders.log")
+	client.DuplicateWindow = time.Minute
+	h := (&apiServer{client: client, account: allytest.AccountID}).handler()
+
+	post := func(body string) int {
+		r := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+		r.Host = "127.0.0.1:8765"
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+	const order = `{"symbol": "AAPL", "side": "buy", "type": "limit", "quantity": 10, "price": "150.25"`
+	for _, tt := range []struct {
+		name string
+		body string
+		want int
+	}{
+		{"first", order + "}", http.StatusOK},
+		{"repeated", order + "}", http.StatusConflict},
+		{"forced", order + `, "force": true}`, http.StatusOK},
+	} {
+		if code := post(tt.body); code != tt.want {
+			t.Errorf("%v order: status %v, want %v", tt.name, code, tt.want)
+		}
+	}
+	if n := len(api.Requests()); n != 2 {
+		t.Errorf("sent %d orders, want 2", n)
+	}
+}
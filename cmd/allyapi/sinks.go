@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/n8henrie/allyapi"
+)
+
+// A destination for streamed messages in addition to stdout
+type sink interface {
+	send(m *allyapi.APIResponse) error
+	close() error
+}
+
+// Pass each message to handle and then to every sink. A failing sink is
+// logged rather than ending the stream.
+func fanOut(handle func(*allyapi.APIResponse) error, sinks []sink) func(*allyapi.APIResponse) error {
+	if len(sinks) == 0 {
+		return handle
+	}
+	return func(m *allyapi.APIResponse) error {
+		if err := handle(m); err != nil {
+			return err
+		}
+		for _, s := range sinks {
+			if err := s.send(m); err != nil {
+				slog.Warn("error sending to sink", "sink", fmt.Sprintf("%T", s), "error", err)
+			}
+		}
+		return nil
+	}
+}
+
+func closeSinks(sinks []sink) {
+	for _, s := range sinks {
+		if err := s.close(); err != nil {
+			slog.Warn("error closing sink", "sink", fmt.Sprintf("%T", s), "error", err)
+		}
+	}
+}
+
+// Symbol of a streamed quote or trade, or empty for other messages
+func streamSymbol(m *allyapi.APIResponse) string {
+	switch {
+	case m.Quote != nil:
+		return m.Quote.Symbol
+	case m.Trade != nil:
+		return m.Trade.Symbol
+	}
+	return ""
+}
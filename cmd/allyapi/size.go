@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/n8henrie/allyapi"
+)
+
+var sizeColumns = []string{"symbol", "side", "capital", "risk_pct", "risk", "per_share", "qty", "cost"}
+
+func sizeCommand() *command {
+	cmd := newCommand("size", "size [flags]", "Size a position by the capital risked between its entry and stop")
+	cmd.footer = "The quantity risks -risk percent of the account's cash, or its buying power\n" +
+		"with -allow-margin, on the move from -entry to -stop, e.g. 1% of $10,000 is\n" +
+		"20 shares entering at 100 with a stop at 95. A stop above the entry sizes a\n" +
+		"short position.\n\n" +
+		"With -place or -preview, the entry is a limit order that, once filled,\n" +
+		"places the stop loss, and a profit target too with -take-profit."
+	output := addOutputFlags(cmd.flags)
+	account := addAccountFlag(cmd.flags)
+	symbol := cmd.flags.String("symbol", "", "Symbol to trade")
+	var entry, stop, capital allyapi.Decimal
+	cmd.flags.Var(&entry, "entry", "Entry price")
+	cmd.flags.Var(&stop, "stop", "Stop loss price")
+	cmd.flags.Var(&capital, "capital", "Capital to size against instead of the account's")
+	risk := cmd.flags.Float64("risk", 1, "Percent of capital to risk")
+	tif := cmd.flags.String("tif", "day", "Time in force: day or gtc (good til canceled)")
+	takeProfit := new(allyapi.Decimal)
+	cmd.flags.Var(takeProfit, "take-profit", "With -place, limit price at which to close the position")
+	place := cmd.flags.Bool("place", false, "Place the entry and stop loss orders")
+	preview := cmd.flags.Bool("preview", false, "Preview the entry and stop loss orders")
+	pf := addPlaceFlags(cmd.flags)
+
+	cmd.run = func(args []string) error {
+		if entry <= 0 || stop <= 0 {
+			return usageError("expected -entry and -stop")
+		}
+		if (*place || *preview) && *symbol == "" {
+			return usageError("-place and -preview require -symbol")
+		}
+		if err := output.validate(); err != nil {
+			return err
+		}
+
+		acct := *account
+		if capital == 0 {
+			client := newClient()
+			defer client.Wait()
+			var err error
+			if acct, err = defaultAccount(client, acct); err != nil {
+				return err
+			}
+			if capital, err = client.AvailableBuyingPower(acct, false, pf.allowMargin); err != nil {
+				return err
+			}
+		}
+		qty, err := allyapi.PositionSize(capital, *risk, entry, stop)
+		if err != nil {
+			return usageError(err.Error())
+		}
+
+		side := "buy"
+		if stop > entry {
+			side = "sell_short"
+		}
+		perShare := (entry - stop).Abs()
+		err = output.printRows(sizeColumns, []map[string]string{{
+			"symbol":    strings.ToUpper(*symbol),
+			"side":      side,
+			"capital":   capital.StringFixed(2),
+			"risk_pct":  strconv.FormatFloat(*risk, 'f', -1, 64),
+			"risk":      perShare.MulInt(int64(qty)).StringFixed(2),
+			"per_share": perShare.StringFixed(2),
+			"qty":       strconv.Itoa(qty),
+			"cost":      entry.MulInt(int64(qty)).StringFixed(2),
+		}})
+		if err != nil {
+			return err
+		}
+		if qty == 0 {
+			return fmt.Errorf("risking %v%% of %v doesn't cover one share's risk of %v", *risk, capital.StringFixed(2), perShare.StringFixed(2))
+		}
+		if !*place && !*preview {
+			return nil
+		}
+
+		o := &allyapi.Order{Account: acct, Symbol: strings.ToUpper(*symbol), Side: side, Type: "limit", Quantity: qty, Price: entry, TimeInForce: *tif}
+		g := &allyapi.OrderGroup{Kind: allyapi.OTO, Orders: append([]allyapi.Order{*o}, exitOrders(o, *takeProfit, stop)...)}
+		if len(g.Orders) > 2 {
+			g.Kind = allyapi.OTOCO
+		}
+		return submitOrderGroup(g, pf, *preview)
+	}
+	return cmd
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+)
+
+// How often an idle event stream sends a comment, so that proxies don't
+// close it
+const sseKeepAlive = 15 * time.Second
+
+// GET /events?symbols=A,B,@WATCHLIST streams quotes and trades as server-sent
+// events: quote and trade events with the streamed message as JSON data, and
+// status events with {"status": ..., "error": ...}. Clients share one
+// streaming connection to the API, which streams the symbols of all of them.
+func (s *apiServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	symbols, err := expandWatchlists(parseSymbols(r.URL.Query()["symbols"]))
+	if err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
+	if len(symbols) == 0 {
+		writeBadRequest(w, "no symbols given")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	sub := s.events.subscribe(s.client, symbols)
+	defer s.events.unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case m, ok := <-sub.ch:
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, &m); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// Messages buffered for each client of the event stream; clients that fall
+// further behind are disconnected
+const sseBuffer = 64
+
+// An eventHub fans the messages of one stream session out to the clients of
+// the event stream, each of which gets the quotes and trades of its own
+// symbols and every status message. The session starts with the first client
+// and streams the symbols of the clients connected, reconnecting as they
+// change.
+type eventHub struct {
+	mu      sync.Mutex
+	session *allyapi.StreamSession
+	subs    map[*eventSub]bool
+	// Number of clients streaming each symbol
+	refs map[string]int
+}
+
+type eventSub struct {
+	symbols map[string]bool
+	ch      chan allyapi.StreamMessage
+}
+
+func (h *eventHub) subscribe(client *allyapi.Client, symbols []string) *eventSub {
+	sub := &eventSub{symbols: make(map[string]bool), ch: make(chan allyapi.StreamMessage, sseBuffer)}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.session == nil {
+		h.subs = make(map[*eventSub]bool)
+		h.refs = make(map[string]int)
+	}
+	h.subs[sub] = true
+
+	var added []string
+	for _, sym := range symbols {
+		sym = strings.ToUpper(sym)
+		if sub.symbols[sym] {
+			continue
+		}
+		sub.symbols[sym] = true
+		if h.refs[sym]++; h.refs[sym] == 1 {
+			added = append(added, sym)
+		}
+	}
+	if h.session == nil {
+		h.session = client.NewStreamSession(rootCtx, added...)
+		go h.fanOut(h.session)
+	} else {
+		h.session.Subscribe(added...)
+	}
+	return sub
+}
+
+func (h *eventHub) unsubscribe(sub *eventSub) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.subs[sub] {
+		return
+	}
+	h.remove(sub)
+}
+
+// Remove a client, and the symbols no other client streams; h.mu must be
+// held
+func (h *eventHub) remove(sub *eventSub) {
+	delete(h.subs, sub)
+	close(sub.ch)
+	var removed []string
+	for sym := range sub.symbols {
+		if h.refs[sym]--; h.refs[sym] == 0 {
+			delete(h.refs, sym)
+			removed = append(removed, sym)
+		}
+	}
+	if h.session != nil {
+		h.session.Unsubscribe(removed...)
+	}
+}
+
+// Send the session's messages to the clients until it ends, which ends their
+// event streams, so that the next client starts a new session
+func (h *eventHub) fanOut(session *allyapi.StreamSession) {
+	for m := range session.Messages() {
+		symbol := ""
+		switch m.Event {
+		case allyapi.QuoteEvent:
+			symbol = m.Quote.Symbol
+		case allyapi.TradeEvent:
+			symbol = m.Trade.Symbol
+		}
+		h.mu.Lock()
+		for sub := range h.subs {
+			if symbol != "" && !sub.symbols[strings.ToUpper(symbol)] {
+				continue
+			}
+			select {
+			case sub.ch <- m:
+			default:
+				h.remove(sub)
+			}
+		}
+		h.mu.Unlock()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.session = nil
+	for sub := range h.subs {
+		h.remove(sub)
+	}
+}
+
+// Write a stream message as a server-sent event named for its type
+func writeEvent(w http.ResponseWriter, m *allyapi.StreamMessage) error {
+	var data interface{}
+	switch m.Event {
+	case allyapi.QuoteEvent:
+		data = m.Quote
+	case allyapi.TradeEvent:
+		data = m.Trade
+	default:
+		status := map[string]string{"status": m.Status}
+		if m.Err != nil {
+			status["error"] = m.Err.Error()
+		}
+		data = status
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %v\ndata: %s\n\n", m.Event, b)
+	return err
+}
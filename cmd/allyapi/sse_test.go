@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/n8henrie/allyapi/allytest"
+)
+
+// Read the next quote or trade event from an event stream, returning its
+// symbol
+func nextEvent(t *testing.T, sc *bufio.Scanner) string {
+	t.Helper()
+	event := ""
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: ") && (event == "quote" || event == "trade"):
+			var data struct{ Symbol string }
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &data); err != nil {
+				t.Fatal(err)
+			}
+			return data.Symbol
+		}
+	}
+	t.Fatalf("event stream ended: %v", sc.Err())
+	return ""
+}
+
+func TestEventsShareStream(t *testing.T) {
+	// Closed after the clients, which close in cleanups
+	api := allytest.NewServer()
+	t.Cleanup(api.Close)
+	api.StreamInterval = 20 * time.Millisecond
+
+	var streams int32
+	handler := api.Config.Handler
+	api.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/stream/") {
+			atomic.AddInt32(&streams, 1)
+		}
+		handler.ServeHTTP(w, r)
+	})
+
+	s := &apiServer{client: api.Client(), account: allytest.AccountID}
+	srv := httptest.NewServer(s.handler())
+	t.Cleanup(srv.Close)
+
+	subscribe := func(symbols string) *bufio.Scanner {
+		t.Helper()
+		resp, err := http.Get(srv.URL + "/events?symbols=" + symbols)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status %v", resp.StatusCode)
+		}
+		return bufio.NewScanner(resp.Body)
+	}
+
+	a := subscribe("AAPL")
+	if sym := nextEvent(t, a); sym != "AAPL" {
+		t.Fatalf("first client got %v, want AAPL", sym)
+	}
+	b := subscribe("aapl")
+	if sym := nextEvent(t, b); sym != "AAPL" {
+		t.Fatalf("second client got %v, want AAPL", sym)
+	}
+	if n := atomic.LoadInt32(&streams); n != 1 {
+		t.Errorf("clients of the same symbol connected %d streams, want 1", n)
+	}
+
+	// A client of another symbol widens the shared stream, but the others
+	// still only get their own symbol
+	c := subscribe("MSFT")
+	if sym := nextEvent(t, c); sym != "MSFT" {
+		t.Fatalf("third client got %v, want MSFT", sym)
+	}
+	for i := 0; i < 4; i++ {
+		if sym := nextEvent(t, a); sym != "AAPL" {
+			t.Fatalf("first client got %v, want only AAPL", sym)
+		}
+	}
+	if n := atomic.LoadInt32(&streams); n != 2 {
+		t.Errorf("connected %d streams, want 2", n)
+	}
+}
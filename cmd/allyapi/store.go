@@ -0,0 +1,257 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+	_ "modernc.org/sqlite"
+)
+
+// Schema of the database written by -store. Each row is one streamed quote or
+// trade, or one quote from the quotes command:
+//
+//	time    Unix time of the tick in seconds
+//	symbol  Upper case ticker symbol
+//	type    "quote" (bid/ask), "trade", or "snapshot" (from the quotes command)
+//	last    Last trade price; trades and snapshots only
+//	bid     Bid price; quotes and snapshots only
+//	ask     Ask price; quotes and snapshots only
+//	volume  Cumulative volume for the day; trades and snapshots only
+//	data    The message or quote as JSON, with any fields not in a column
+const storeSchema = `
+CREATE TABLE IF NOT EXISTS ticks (
+	id     INTEGER PRIMARY KEY,
+	time   INTEGER NOT NULL,
+	symbol TEXT NOT NULL,
+	type   TEXT NOT NULL,
+	last   REAL,
+	bid    REAL,
+	ask    REAL,
+	volume INTEGER,
+	data   TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS ticks_symbol_time ON ticks (symbol, time);
+`
+
+// Columns returned by the query command
+var storeColumns = []string{"time", "symbol", "type", "last", "bid", "ask", "volume"}
+
+// Appends quotes and trades to a SQLite database
+type storeSink struct {
+	db *sql.DB
+}
+
+func openStore(path string) (*storeSink, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(storeSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating %v: %v", path, err)
+	}
+	return &storeSink{db: db}, nil
+}
+
+// Convert a value to a nullable column, treating zero and unparsable values
+// as missing
+func nullFloat(f float64) interface{} {
+	if f == 0 {
+		return nil
+	}
+	return f
+}
+
+func nullFloatString(s string) interface{} {
+	f, _ := strconv.ParseFloat(s, 64)
+	return nullFloat(f)
+}
+
+func (s *storeSink) insert(t time.Time, symbol, typ string, last, bid, ask, volume, data interface{}) error {
+	_, err := s.db.Exec(
+		`INSERT INTO ticks (time, symbol, type, last, bid, ask, volume, data) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.Unix(), strings.ToUpper(symbol), typ, last, bid, ask, volume, data,
+	)
+	return err
+}
+
+func (s *storeSink) send(m *allyapi.APIResponse) error {
+	var data []byte
+	var err error
+	switch {
+	case m.Quote != nil:
+		if data, err = json.Marshal(m.Quote); err != nil {
+			return err
+		}
+		return s.insert(timeOrNow(m.Quote.Time()), m.Quote.Symbol, "quote",
+			nil, nullFloat(m.Quote.Bid.Float64()), nullFloat(m.Quote.Ask.Float64()), nil, string(data))
+	case m.Trade != nil:
+		if data, err = json.Marshal(m.Trade); err != nil {
+			return err
+		}
+		return s.insert(timeOrNow(m.Trade.Time()), m.Trade.Symbol, "trade",
+			nullFloat(m.Trade.Last.Float64()), nil, nil, m.Trade.Vl, string(data))
+	}
+	return nil
+}
+
+// Store a snapshot of quotes from the quotes command
+func (s *storeSink) writeQuotes(quotes allyapi.QuoteArray) error {
+	now := time.Now()
+	for _, q := range quotes {
+		data, err := json.Marshal(q)
+		if err != nil {
+			return err
+		}
+		var volume interface{}
+		if v, err := strconv.ParseInt(q["vl"], 10, 64); err == nil {
+			volume = v
+		}
+		err = s.insert(now, q["symbol"], "snapshot",
+			nullFloatString(q["last"]), nullFloatString(q["bid"]), nullFloatString(q["ask"]), volume, string(data))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *storeSink) close() error {
+	return s.db.Close()
+}
+
+// Parse a time given as RFC 3339, a date, or a duration before now
+func parseTimeFlag(s string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use RFC 3339, YYYY-MM-DD, or a duration like 2h", s)
+}
+
+// Return stored ticks matching the filters, oldest first. Times are
+// formatted as RFC 3339 in the local time zone.
+func (s *storeSink) query(symbols []string, typ string, since, until time.Time, limit int) ([]map[string]string, error) {
+	var where []string
+	var args []interface{}
+	if len(symbols) > 0 {
+		where = append(where, "symbol IN (?"+strings.Repeat(", ?", len(symbols)-1)+")")
+		for _, sym := range symbols {
+			args = append(args, sym)
+		}
+	}
+	if typ != "" {
+		where = append(where, "type = ?")
+		args = append(args, typ)
+	}
+	if !since.IsZero() {
+		where = append(where, "time >= ?")
+		args = append(args, since.Unix())
+	}
+	if !until.IsZero() {
+		where = append(where, "time < ?")
+		args = append(args, until.Unix())
+	}
+
+	q := "SELECT time, symbol, type, last, bid, ask, volume FROM ticks"
+	if len(where) > 0 {
+		q += " WHERE " + strings.Join(where, " AND ")
+	}
+	q += " ORDER BY time, id"
+	if limit > 0 {
+		// Keep the most recent rows while still printing oldest first
+		q = "SELECT * FROM (" + strings.Replace(q, "ORDER BY time, id", "ORDER BY time DESC, id DESC", 1) +
+			" LIMIT ?) ORDER BY time"
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []map[string]string
+	for rows.Next() {
+		var t int64
+		var symbol, typ string
+		var last, bid, ask sql.NullFloat64
+		var volume sql.NullInt64
+		if err := rows.Scan(&t, &symbol, &typ, &last, &bid, &ask, &volume); err != nil {
+			return nil, err
+		}
+
+		row := map[string]string{
+			"time":   time.Unix(t, 0).Format(time.RFC3339),
+			"symbol": symbol,
+			"type":   typ,
+		}
+		for name, v := range map[string]sql.NullFloat64{"last": last, "bid": bid, "ask": ask} {
+			if v.Valid {
+				row[name] = strconv.FormatFloat(v.Float64, 'f', -1, 64)
+			}
+		}
+		if volume.Valid {
+			row["volume"] = strconv.FormatInt(volume.Int64, 10)
+		}
+		results = append(results, row)
+	}
+	return results, rows.Err()
+}
+
+func queryCommand() *command {
+	cmd := newCommand("query", "query [flags] [SYMBOL...]", "Show quotes and trades saved with -store")
+	output := addOutputFlags(cmd.flags)
+	storePath := cmd.flags.String("store", "quotes.db", "SQLite database written by -store")
+	typ := cmd.flags.String("kind", "", "Only show this type of row: quote, trade, or snapshot")
+	sinceFlag := cmd.flags.String("since", "", "Only show rows at or after this time (RFC 3339, YYYY-MM-DD, or a duration ago like 2h)")
+	untilFlag := cmd.flags.String("until", "", "Only show rows before this time")
+	limit := cmd.flags.Int("limit", 0, "Show at most this many of the most recent rows (0 for all)")
+
+	cmd.run = func(args []string) error {
+		if err := output.validate(); err != nil {
+			return err
+		}
+
+		var since, until time.Time
+		var err error
+		if *sinceFlag != "" {
+			if since, err = parseTimeFlag(*sinceFlag); err != nil {
+				return err
+			}
+		}
+		if *untilFlag != "" {
+			if until, err = parseTimeFlag(*untilFlag); err != nil {
+				return err
+			}
+		}
+
+		symbols, err := expandWatchlists(parseSymbols(args))
+		if err != nil {
+			return err
+		}
+
+		store, err := openStore(*storePath)
+		if err != nil {
+			return err
+		}
+		defer store.close()
+
+		rows, err := store.query(symbols, *typ, since, until, *limit)
+		if err != nil {
+			return fmt.Errorf("error querying %v: %v", *storePath, err)
+		}
+		return output.printRows(storeColumns, rows)
+	}
+	return cmd
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+)
+
+var (
+	openLotColumns     = []string{"symbol", "acquired", "qty", "cost_per_share", "cost", "term"}
+	realizedLotColumns = []string{"symbol", "acquired", "sold", "qty", "cost", "proceeds", "gain", "term"}
+)
+
+// Shares bought in a single trade, some of which may since have been sold
+type lot struct {
+	symbol   string
+	acquired time.Time
+	qty      allyapi.Decimal
+
+	// Cost of the shares, including commission and fees
+	cost allyapi.Decimal
+}
+
+// Shares from a lot that were sold
+type realizedLot struct {
+	lot
+	sold     time.Time
+	proceeds allyapi.Decimal
+}
+
+// Positions held for more than a year are long term
+func holdingTerm(acquired, until time.Time) string {
+	if until.After(acquired.AddDate(1, 0, 0)) {
+		return "long"
+	}
+	return "short"
+}
+
+// Net cash of a trade, from its amount or, failing that, its price, quantity,
+// and fees
+func tradeAmount(t *allyapi.Transaction, qty allyapi.Decimal) allyapi.Decimal {
+	if t.Amount != 0 {
+		return t.Amount
+	}
+	d := t.Transaction
+	return -qty.Mul(d.Price) - d.Commission - d.Fee - d.SecFee
+}
+
+// The part of amount that n of total shares account for
+func share(amount, n, total allyapi.Decimal) allyapi.Decimal {
+	if n == total {
+		return amount
+	}
+	return amount.Mul(n).Div(total)
+}
+
+// Match sales to the earliest purchases of each symbol (first in, first out)
+// and return the remaining open lots and the realized ones
+func matchLots(txs allyapi.Transactions) (open []lot, realized []realizedLot) {
+	var trades []allyapi.Transaction
+	for _, t := range txs {
+		if strings.EqualFold(t.Activity, "trade") {
+			trades = append(trades, t)
+		}
+	}
+	sort.SliceStable(trades, func(i, j int) bool {
+		ti, _ := trades[i].Time()
+		tj, _ := trades[j].Time()
+		return ti.Before(tj)
+	})
+
+	lots := make(map[string][]lot)
+	var symbols []string
+	for i := range trades {
+		t := &trades[i]
+		when, err := t.Time()
+		if err != nil {
+			continue
+		}
+		sym := strings.ToUpper(t.Symbol)
+		if sym == "" {
+			sym = strings.ToUpper(t.Transaction.Security.Sym)
+		}
+		qty := parseDecimal(t.Transaction.Quantity)
+		amount := tradeAmount(t, qty)
+
+		if _, ok := lots[sym]; !ok {
+			symbols = append(symbols, sym)
+		}
+		if qty > 0 {
+			lots[sym] = append(lots[sym], lot{symbol: sym, acquired: when, qty: qty, cost: -amount})
+			continue
+		}
+
+		// Sell from the oldest lots first, splitting the cost and proceeds
+		// by shares so that the parts add up to the whole
+		remaining := -qty
+		for remaining > 0 && len(lots[sym]) > 0 {
+			l := &lots[sym][0]
+			n := min(l.qty, remaining)
+			sold := lot{symbol: sym, acquired: l.acquired, qty: n, cost: share(l.cost, n, l.qty)}
+			proceeds := share(amount, n, remaining)
+			realized = append(realized, realizedLot{lot: sold, sold: when, proceeds: proceeds})
+
+			l.qty -= n
+			l.cost -= sold.cost
+			amount -= proceeds
+			remaining -= n
+			if l.qty <= 0 {
+				lots[sym] = lots[sym][1:]
+			}
+		}
+	}
+
+	for _, sym := range symbols {
+		open = append(open, lots[sym]...)
+	}
+	return open, realized
+}
+
+func taxlotsCommand() *command {
+	cmd := newCommand("taxlots", "taxlots [flags]", "Show open tax lots, or realized gains for a tax year")
+	output := addOutputFlags(cmd.flags)
+	account := addAccountFlag(cmd.flags)
+	pageSize := addPageSizeFlag(cmd.flags)
+	year := cmd.flags.Int("year", 0, "Show lots sold in this tax year instead of open lots")
+
+	cmd.run = func(args []string) error {
+		if err := output.validate(); err != nil {
+			return err
+		}
+
+		client := newClient()
+		defer client.Wait()
+
+		id, err := defaultAccount(client, *account)
+		if err != nil {
+			return err
+		}
+		txs, err := getHistory(client, id, "trade", *pageSize)
+		if err != nil {
+			return err
+		}
+		open, realized := matchLots(txs)
+
+		const date = "2006-01-02"
+		var rows []map[string]string
+		if *year == 0 {
+			now := time.Now()
+			for _, l := range open {
+				rows = append(rows, map[string]string{
+					"symbol":         l.symbol,
+					"acquired":       l.acquired.Format(date),
+					"qty":            l.qty.String(),
+					"cost_per_share": l.cost.Div(l.qty).StringFixed(4),
+					"cost":           formatMoney(l.cost),
+					"term":           holdingTerm(l.acquired, now),
+				})
+			}
+			return output.printRows(openLotColumns, rows)
+		}
+
+		var total allyapi.Decimal
+		for _, l := range realized {
+			if l.sold.Year() != *year {
+				continue
+			}
+			gain := l.proceeds - l.cost
+			total += gain
+			rows = append(rows, map[string]string{
+				"symbol":   l.symbol,
+				"acquired": l.acquired.Format(date),
+				"sold":     l.sold.Format(date),
+				"qty":      l.qty.String(),
+				"cost":     formatMoney(l.cost),
+				"proceeds": formatMoney(l.proceeds),
+				"gain":     formatMoney(gain),
+				"term":     holdingTerm(l.acquired, l.sold),
+			})
+		}
+		if output.format == "table" || output.format == "csv" {
+			rows = append(rows, map[string]string{"symbol": "TOTAL", "gain": formatMoney(total)})
+		}
+		return output.printRows(realizedLotColumns, rows)
+	}
+	return cmd
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/n8henrie/allyapi"
+)
+
+func trade(date, symbol, qty, amount string) allyapi.Transaction {
+	t := allyapi.Transaction{Activity: "Trade", Date: date, Symbol: symbol, Amount: parseDecimal(amount)}
+	t.Transaction.Quantity = qty
+	return t
+}
+
+func TestMatchLots(t *testing.T) {
+	// Three shares bought for $100 cost $33.333333 and change each; selling
+	// two of them and then the last must realize the whole cost
+	open, realized := matchLots(allyapi.Transactions{
+		trade("2023-01-10T00:00:00-05:00", "AAPL", "3", "-100"),
+		trade("2023-06-01T00:00:00-04:00", "MSFT", "10", "-2500.10"),
+		trade("2024-03-01T00:00:00-05:00", "AAPL", "-2", "90"),
+		trade("2024-03-02T00:00:00-05:00", "AAPL", "-1", "50"),
+		trade("2024-04-01T00:00:00-04:00", "MSFT", "-4", "1200"),
+	})
+
+	if len(open) != 1 || open[0].symbol != "MSFT" || open[0].qty.String() != "6" || open[0].cost.String() != "1500.06" {
+		t.Errorf("open = %+v, want 6 MSFT costing 1500.06", open)
+	}
+
+	want := []struct{ symbol, qty, cost, proceeds string }{
+		{"AAPL", "2", "66.666667", "90"},
+		{"AAPL", "1", "33.333333", "50"},
+		{"MSFT", "4", "1000.04", "1200"},
+	}
+	if len(realized) != len(want) {
+		t.Fatalf("got %d realized lots, want %d", len(realized), len(want))
+	}
+	var cost allyapi.Decimal
+	for i, w := range want {
+		l := realized[i]
+		if l.symbol != w.symbol || l.qty.String() != w.qty || l.cost.String() != w.cost || l.proceeds.String() != w.proceeds {
+			t.Errorf("lot %d = %v %v cost %v proceeds %v, want %+v", i, l.symbol, l.qty, l.cost, l.proceeds, w)
+		}
+		if l.symbol == "AAPL" {
+			cost += l.cost
+		}
+	}
+	if cost.String() != "100" {
+		t.Errorf("AAPL lots cost %v in total, want 100", cost)
+	}
+}
+
+func TestMatchLotsSplitsSales(t *testing.T) {
+	// One sale from two lots splits its proceeds between them by shares
+	_, realized := matchLots(allyapi.Transactions{
+		trade("2023-01-10T00:00:00-05:00", "VTI", "1", "-200"),
+		trade("2023-02-10T00:00:00-05:00", "VTI", "2", "-420"),
+		trade("2024-03-01T00:00:00-05:00", "VTI", "-3", "700"),
+	})
+	if len(realized) != 2 {
+		t.Fatalf("got %d realized lots, want 2", len(realized))
+	}
+	if realized[0].proceeds.String() != "233.333333" || realized[1].proceeds.String() != "466.666667" {
+		t.Errorf("proceeds = %v and %v, want 233.333333 and 466.666667", realized[0].proceeds, realized[1].proceeds)
+	}
+	if term := holdingTerm(realized[0].acquired, realized[0].sold); term != "long" {
+		t.Errorf("term = %v, want long", term)
+	}
+}
+
+func TestTradeAmount(t *testing.T) {
+	tx := trade("2024-03-01T00:00:00-05:00", "AAPL", "10", "")
+	tx.Transaction.Price, tx.Transaction.Commission, tx.Transaction.SecFee = parseDecimal("150.25"), parseDecimal("4.95"), parseDecimal("0.01")
+	if got := tradeAmount(&tx, parseDecimal("10")); got.String() != "-1507.46" {
+		t.Errorf("amount = %v, want -1507.46", got)
+	}
+}
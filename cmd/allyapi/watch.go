@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+	"golang.org/x/term"
+)
+
+const (
+	ansiClear   = "\x1b[H\x1b[2J"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiDefault = "\x1b[39m"
+	ansiReset   = "\x1b[0m"
+)
+
+// Whether to color output, unless turned off by -no-color or $NO_COLOR
+func colorEnabled() bool {
+	return !*noColorFlag && os.Getenv("NO_COLOR") == ""
+}
+
+type boardRow struct {
+	last, prevClose, bid, ask allyapi.Decimal
+	volume                    int
+
+	// Direction of the last price move: 1 for up, -1 for down
+	tick int
+}
+
+// A quote board for the watch command, updated from the stream
+type board struct {
+	mu      sync.Mutex
+	symbols []string
+	rows    map[string]*boardRow
+	status  string
+}
+
+func newBoard(symbols []string) *board {
+	b := &board{symbols: symbols, rows: make(map[string]*boardRow)}
+	for _, s := range symbols {
+		b.rows[s] = &boardRow{}
+	}
+	return b
+}
+
+func parseFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+// Quote fields used to seed the board
+var watchFields = []string{"symbol", "last", "pcls", "bid", "ask", "vl"}
+
+// Fill in the board from a snapshot of quotes
+func (b *board) seed(quotes allyapi.QuoteArray) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, q := range quotes {
+		row, ok := b.rows[q["symbol"]]
+		if !ok {
+			continue
+		}
+		row.last = parseDecimal(q["last"])
+		row.prevClose = parseDecimal(q["pcls"])
+		row.bid = parseDecimal(q["bid"])
+		row.ask = parseDecimal(q["ask"])
+		row.volume, _ = strconv.Atoi(q["vl"])
+	}
+}
+
+func (b *board) update(m allyapi.StreamMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch m.Event {
+	case allyapi.StatusEvent:
+		b.status = m.Status
+	case allyapi.QuoteEvent:
+		if row, ok := b.rows[m.Quote.Symbol]; ok {
+			row.bid = m.Quote.Bid
+			row.ask = m.Quote.Ask
+		}
+	case allyapi.TradeEvent:
+		if row, ok := b.rows[m.Trade.Symbol]; ok {
+			last := m.Trade.Last
+			switch {
+			case last > row.last:
+				row.tick = 1
+			case last < row.last:
+				row.tick = -1
+			}
+			row.last = last
+			row.volume = m.Trade.Vl
+		}
+	}
+}
+
+func colorize(s string, direction int) string {
+	if !colorEnabled() {
+		return s
+	}
+	switch {
+	case direction > 0:
+		return ansiGreen + s + ansiReset
+	case direction < 0:
+		return ansiRed + s + ansiReset
+	}
+	return s
+}
+
+func (b *board) render(w io.Writer) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	lines := []string{
+		fmt.Sprintf("%-8s %10s %10s %8s %10s %10s %12s", "SYMBOL", "LAST", "CHANGE", "PCT", "BID", "ASK", "VOLUME"),
+	}
+	for _, s := range b.symbols {
+		row := b.rows[s]
+		var change allyapi.Decimal
+		var pct float64
+		if row.prevClose != 0 {
+			change = row.last - row.prevClose
+			pct = change.Float64() / row.prevClose.Float64() * 100
+		}
+		changeDir := 0
+		switch {
+		case change > 0:
+			changeDir = 1
+		case change < 0:
+			changeDir = -1
+		}
+		changeText := change.StringFixed(2)
+		if change >= 0 {
+			changeText = "+" + changeText
+		}
+
+		lines = append(lines, fmt.Sprintf("%-8s %s %s %s %10s %10s %12d",
+			s,
+			colorize(fmt.Sprintf("%10s", row.last.StringFixed(2)), row.tick),
+			colorize(fmt.Sprintf("%10s", changeText), changeDir),
+			colorize(fmt.Sprintf("%+7.2f%%", pct), changeDir),
+			row.bid.StringFixed(2),
+			row.ask.StringFixed(2),
+			row.volume,
+		))
+	}
+	lines = append(lines, "", fmt.Sprintf("%v  status: %v  (press q to quit)", time.Now().Format("15:04:05"), b.status))
+
+	// Raw terminal mode needs explicit carriage returns
+	fmt.Fprint(w, ansiClear+strings.Join(lines, "\r\n")+"\r\n")
+}
+
+// Wait for q or Ctrl-C on a raw terminal
+func waitForQuit(quit chan<- struct{}) {
+	buf := make([]byte, 1)
+	for {
+		if _, err := os.Stdin.Read(buf); err != nil {
+			return
+		}
+		switch buf[0] {
+		case 'q', 'Q', 3:
+			close(quit)
+			return
+		}
+	}
+}
+
+func watchCommand() *command {
+	cmd := newCommand("watch", "watch [flags] SYMBOL...", "Show a live quote board for one or more symbols")
+	interval := cmd.flags.Duration("refresh", 500*time.Millisecond, "Screen refresh interval")
+	symbolFlags := addSymbolFlags(cmd.flags)
+
+	cmd.run = func(args []string) error {
+		symbols, err := symbolFlags.symbols(args)
+		if err != nil {
+			return err
+		}
+
+		client := newClient()
+		b := newBoard(symbols)
+
+		quotes, err := client.GetQuotes(symbols, watchFields)
+		if err != nil {
+			return fmt.Errorf("error getting quotes: %v", err)
+		}
+		if quotes.Response.Quotes != nil {
+			b.seed(quotes.Response.Quotes.Quote)
+		}
+
+		quit := make(chan struct{})
+		fd := int(os.Stdin.Fd())
+		if term.IsTerminal(fd) {
+			state, err := term.MakeRaw(fd)
+			if err != nil {
+				return err
+			}
+			defer term.Restore(fd, state)
+			go waitForQuit(quit)
+		}
+
+		// Quitting closes the stream along with the command
+		ctx, cancel := context.WithCancel(rootCtx)
+		defer cancel()
+		messages, err := client.Stream(ctx, symbols)
+		if err != nil {
+			return fmt.Errorf("error streaming quotes: %v", err)
+		}
+
+		ticker := time.NewTicker(*interval)
+		defer ticker.Stop()
+		for {
+			b.render(os.Stdout)
+			select {
+			case <-quit:
+				return nil
+			case <-rootCtx.Done():
+				return nil
+			case m, ok := <-messages:
+				if !ok {
+					return nil
+				}
+				if m.Event == allyapi.StatusEvent && m.Status == allyapi.StreamClosed {
+					if m.Err != nil {
+						return fmt.Errorf("error streaming quotes: %v", m.Err)
+					}
+					return nil
+				}
+				b.update(m)
+				continue
+			case <-ticker.C:
+			}
+		}
+	}
+	return cmd
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/n8henrie/allyapi"
+)
+
+// Symbols in the watchlist with the given name in the config file, or else
+// the one saved with Ally
+func watchlistSymbols(name string) ([]string, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %v", err)
+	}
+	if symbols, ok := cfg.Watchlists[name]; ok {
+		return symbols, nil
+	}
+
+	client := newClient()
+	defer client.Wait()
+	symbols, err := client.Watchlist(name)
+	if err != nil {
+		return nil, fmt.Errorf("error getting watchlist %v: %v", name, err)
+	}
+	return symbols, nil
+}
+
+func watchlistsCommand() *command {
+	cmd := newCommand("watchlists", "watchlists [NAME]", "List saved watchlists, or the symbols in watchlist NAME")
+	cmd.footer = "Watchlists in the config file, e.g.\n" +
+		"  [watchlists]\n" +
+		"  tech = \"AAPL,MSFT,NVDA\"\n" +
+		"take precedence over those saved with Ally of the same name. Either\n" +
+		"kind can be given as @NAME wherever symbols are, e.g. allyapi quotes @tech"
+	cmd.completeArgs = completeWatchlists
+	cmd.commands = []*command{
+		watchlistsPullCommand(),
+		watchlistsPushCommand(),
+	}
+	cmd.run = func(args []string) error {
+		if len(args) > 0 {
+			symbols, err := watchlistSymbols(args[0])
+			if err != nil {
+				return err
+			}
+			for _, symbol := range symbols {
+				fmt.Println(symbol)
+			}
+			return nil
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("error reading config file: %v", err)
+		}
+		var names []string
+		for name := range cfg.Watchlists {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		client := newClient()
+		defer client.Wait()
+
+		saved, err := client.Watchlists()
+		if err != nil {
+			return fmt.Errorf("error getting watchlists: %v", err)
+		}
+		for _, name := range saved {
+			if _, ok := cfg.Watchlists[name]; !ok {
+				names = append(names, name)
+			}
+		}
+
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// Symbols in to that aren't in from, and those in from that aren't in to
+func diffSymbols(from, to []string) (added, removed []string) {
+	in := func(symbols []string, s string) bool {
+		for _, sym := range symbols {
+			if sym == s {
+				return true
+			}
+		}
+		return false
+	}
+	for _, s := range to {
+		if !in(from, s) {
+			added = append(added, s)
+		}
+	}
+	for _, s := range from {
+		if !in(to, s) {
+			removed = append(removed, s)
+		}
+	}
+	return added, removed
+}
+
+// A change to one watchlist
+type watchlistChange struct {
+	name           string
+	symbols        []string
+	added, removed []string
+	create         bool
+}
+
+// Compare watchlists by name, returning the changes that make dst match src
+func diffWatchlists(names []string, src, dst map[string][]string) []watchlistChange {
+	var changes []watchlistChange
+	for _, name := range names {
+		old, ok := dst[name]
+		added, removed := diffSymbols(old, src[name])
+		if ok && len(added) == 0 && len(removed) == 0 {
+			continue
+		}
+		changes = append(changes, watchlistChange{name: name, symbols: src[name], added: added, removed: removed, create: !ok})
+	}
+	return changes
+}
+
+// Print the changes as a diff and ask whether to make them, unless yes is
+// set. In dry run mode, they are only printed.
+func confirmWatchlistChanges(changes []watchlistChange, where string, yes bool) (bool, error) {
+	if len(changes) == 0 {
+		fmt.Fprintln(os.Stderr, "Watchlists are already in sync")
+		return false, nil
+	}
+	for _, c := range changes {
+		if c.create {
+			fmt.Printf("%v (new)\n", c.name)
+		} else {
+			fmt.Println(c.name)
+		}
+		for _, s := range c.added {
+			fmt.Printf("  + %v\n", s)
+		}
+		for _, s := range c.removed {
+			fmt.Printf("  - %v\n", s)
+		}
+	}
+	if *dryRunFlag {
+		return false, nil
+	}
+	if yes {
+		return true, nil
+	}
+	answer, err := prompt(fmt.Sprintf("Apply these changes to %v? [y/N]", where))
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	return strings.HasPrefix(strings.ToLower(answer), "y"), nil
+}
+
+// Watchlists saved with Ally, or just those named
+func savedWatchlists(client *allyapi.Client, names []string) (map[string][]string, []string, error) {
+	if len(names) == 0 {
+		var err error
+		if names, err = client.Watchlists(); err != nil {
+			return nil, nil, fmt.Errorf("error getting watchlists: %v", err)
+		}
+	}
+	saved := make(map[string][]string)
+	for _, name := range names {
+		symbols, err := client.Watchlist(name)
+		var apiErr *allyapi.APIError
+		if errors.As(err, &apiErr) {
+			// Not saved yet
+			continue
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("error getting watchlist %v: %v", name, err)
+		}
+		saved[name] = symbols
+	}
+	return saved, names, nil
+}
+
+func watchlistsPullCommand() *command {
+	cmd := newCommand("pull", "watchlists pull [flags] [NAME...]", "Copy watchlists saved with Ally, or just those named, into the config file")
+	cmd.footer = "The changes are shown first. Saving them rewrites the config file, which\n" +
+		"drops its comments."
+	yes := cmd.flags.Bool("yes", false, "Make the changes without asking")
+	cmd.completeArgs = completeWatchlists
+	cmd.run = func(args []string) error {
+		// The file is written back, so it's read without applying -profile
+		cfg, err := allyapi.LoadConfigFile(*configFlag)
+		if err != nil {
+			return fmt.Errorf("error reading config file: %v", err)
+		}
+
+		client := newClient()
+		defer client.Wait()
+
+		saved, names, err := savedWatchlists(client, args)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if _, ok := saved[name]; !ok {
+				return fmt.Errorf("no watchlist %v saved with Ally", name)
+			}
+		}
+		local := make(map[string][]string)
+		for name, symbols := range cfg.Watchlists {
+			local[name] = symbols
+		}
+
+		changes := diffWatchlists(names, saved, local)
+		ok, err := confirmWatchlistChanges(changes, *configFlag, *yes)
+		if err != nil || !ok {
+			return err
+		}
+		if cfg.Watchlists == nil {
+			cfg.Watchlists = make(map[string]allyapi.SymbolList)
+		}
+		for _, c := range changes {
+			cfg.Watchlists[c.name] = c.symbols
+		}
+		if err := cfg.Save(*configFlag); err != nil {
+			return fmt.Errorf("error writing config file: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "Updated %v watchlists in %v\n", len(changes), *configFlag)
+		return nil
+	}
+	return cmd
+}
+
+func watchlistsPushCommand() *command {
+	cmd := newCommand("push", "watchlists push [flags] [NAME...]", "Save watchlists from the config file, or just those named, with Ally")
+	cmd.footer = "The changes are shown first. Symbols that aren't in the config file's\n" +
+		"watchlist are removed from Ally's."
+	yes := cmd.flags.Bool("yes", false, "Make the changes without asking")
+	cmd.completeArgs = completeWatchlists
+	cmd.run = func(args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("error reading config file: %v", err)
+		}
+		local := make(map[string][]string)
+		for name, symbols := range cfg.Watchlists {
+			local[name] = symbols
+		}
+		names := args
+		if len(names) == 0 {
+			for name := range local {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+		}
+		for _, name := range names {
+			if _, ok := local[name]; !ok {
+				return fmt.Errorf("no watchlist %v in %v", name, *configFlag)
+			}
+		}
+
+		client := newClient()
+		defer client.Wait()
+
+		saved, _, err := savedWatchlists(client, names)
+		if err != nil {
+			return err
+		}
+
+		changes := diffWatchlists(names, local, saved)
+		ok, err := confirmWatchlistChanges(changes, "Ally's watchlists", *yes)
+		if err != nil || !ok {
+			return err
+		}
+		for _, c := range changes {
+			if c.create {
+				err = client.CreateWatchlist(c.name, c.symbols)
+			} else if len(c.added) > 0 {
+				err = client.AddWatchlistSymbols(c.name, c.added)
+			}
+			for _, s := range c.removed {
+				if err != nil {
+					break
+				}
+				err = client.RemoveWatchlistSymbol(c.name, s)
+			}
+			if err != nil {
+				return fmt.Errorf("error updating watchlist %v: %v", c.name, err)
+			}
+		}
+		fmt.Fprintf(os.Stderr, "Updated %v watchlists with Ally\n", len(changes))
+		return nil
+	}
+	return cmd
+}
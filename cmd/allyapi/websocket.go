@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/n8henrie/allyapi"
+	"golang.org/x/net/websocket"
+)
+
+// Messages buffered per WebSocket client before new ones are dropped
+const wsClientBuffer = 256
+
+// Re-broadcasts streamed messages as JSON to connected WebSocket clients
+type wsHub struct {
+	mu       sync.Mutex
+	clients  map[chan []byte]struct{}
+	listener net.Listener
+}
+
+// Listen for WebSocket clients on addr at any path
+func newWSHub(addr string) (*wsHub, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	h := &wsHub{clients: make(map[chan []byte]struct{}), listener: l}
+
+	// A Server without a handshake function accepts clients from any
+	// origin, including non-browser clients that send none
+	srv := &http.Server{Handler: websocket.Server{Handler: h.serve}}
+	go func() {
+		if err := srv.Serve(l); err != nil && err != http.ErrServerClosed {
+			slog.Error("websocket server stopped", "error", err)
+		}
+	}()
+	slog.Info("serving websocket", "addr", l.Addr().String())
+	return h, nil
+}
+
+func (h *wsHub) serve(ws *websocket.Conn) {
+	ch := make(chan []byte, wsClientBuffer)
+	h.mu.Lock()
+	h.clients[ch] = struct{}{}
+	h.mu.Unlock()
+
+	defer func() {
+		h.mu.Lock()
+		delete(h.clients, ch)
+		h.mu.Unlock()
+		ws.Close()
+	}()
+
+	// Clients don't send anything meaningful; reading notices when they go
+	// away
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var discard []byte
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+	}()
+
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := websocket.Message.Send(ws, string(msg)); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+func (h *wsHub) send(m *allyapi.APIResponse) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.clients {
+		// Drop messages for clients that can't keep up rather than
+		// holding up the stream
+		select {
+		case ch <- b:
+		default:
+		}
+	}
+	return nil
+}
+
+func (h *wsHub) close() error {
+	h.mu.Lock()
+	for ch := range h.clients {
+		close(ch)
+		delete(h.clients, ch)
+	}
+	h.mu.Unlock()
+	return h.listener.Close()
+}
@@ -0,0 +1,427 @@
+package allyapi
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+const keychainService = "TradeKing"
+
+// ErrUnknownProfile is returned for a profile that isn't in the config file
+var ErrUnknownProfile = errors.New("unknown profile")
+
+// Credentials are the OAuth consumer and access tokens for the API
+type Credentials struct {
+	ConsumerKey    string `toml:"consumer_key"`
+	ConsumerSecret string `toml:"consumer_secret"`
+	AccessToken    string `toml:"access_token"`
+	AccessSecret   string `toml:"access_secret"`
+}
+
+// ConfigFile is the layout of the config file
+type ConfigFile struct {
+	Credentials Credentials `toml:"credentials"`
+
+	// Account used when none is given
+	DefaultAccount string `toml:"default_account,omitempty"`
+
+	// Nicknames keyed by account ID
+	Accounts map[string]string `toml:"accounts,omitempty"`
+
+	// Price alert rules, e.g. "AAPL last > 200"
+	Alerts []string `toml:"alerts,omitempty"`
+
+	// Named orders, which may leave out fields to be given when placed
+	Templates map[string]Order `toml:"templates,omitempty"`
+
+	// Named recurring buys; see DCAPlan
+	DCA map[string]DCAPlan `toml:"dca,omitempty"`
+
+	// Target percentages of the portfolio by symbol, for rebalancing
+	Allocation Allocation `toml:"allocation,omitempty"`
+
+	// Defaults for the command line's -env and -output flags
+	Environment string `toml:"environment,omitempty"`
+	Output      string `toml:"output,omitempty"`
+
+	// Default for the command line's -buying-power flag: off, warn, or
+	// refuse
+	BuyingPowerCheck string `toml:"buying_power_check,omitempty"`
+
+	// File that order requests are logged to, instead of orders.log in the
+	// state directory
+	AuditLog string `toml:"audit_log,omitempty"`
+
+	// How long after placing an order the command line refuses to place an
+	// identical one without -force, e.g. "5m", or "0" to allow it
+	DuplicateWindow string `toml:"duplicate_window,omitempty"`
+
+	// Keychain service that credentials are stored under, instead of
+	// "TradeKing"
+	KeychainService string `toml:"keychain_service,omitempty"`
+
+	// Names of the keychain items (accounts, in macOS's terms) keyed by
+	// credential, e.g. consumer_key = "Consumer Key", for those that aren't
+	// stored under the credential's own name
+	KeychainItems map[string]string `toml:"keychain_items,omitempty"`
+
+	// Named lists of symbols, which the command line accepts as @NAME
+	Watchlists map[string]SymbolList `toml:"watchlists,omitempty"`
+
+	// Other logins, selected by name; see ForProfile
+	Profiles map[string]Profile `toml:"profiles,omitempty"`
+}
+
+// SymbolList is a list of symbols, given in the config file as an array or a
+// comma-separated string, e.g. tech = "AAPL,MSFT,NVDA"
+type SymbolList []string
+
+// UnmarshalTOML accepts a string or an array of strings, splitting each on
+// commas
+func (sl *SymbolList) UnmarshalTOML(v interface{}) error {
+	var items []interface{}
+	switch v := v.(type) {
+	case string:
+		items = []interface{}{v}
+	case []interface{}:
+		items = v
+	default:
+		return fmt.Errorf("expected a string or an array of symbols, not %T", v)
+	}
+
+	*sl = nil
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return fmt.Errorf("expected a symbol, not %T", item)
+		}
+		for _, sym := range strings.Split(s, ",") {
+			if sym = strings.TrimSpace(sym); sym != "" {
+				*sl = append(*sl, strings.ToUpper(sym))
+			}
+		}
+	}
+	return nil
+}
+
+// Profile is a named set of credentials and defaults in the config file, so
+// that one config can manage several logins
+type Profile struct {
+	Credentials     Credentials `toml:"credentials"`
+	DefaultAccount  string      `toml:"default_account,omitempty"`
+	Environment     string      `toml:"environment,omitempty"`
+	KeychainService string      `toml:"keychain_service,omitempty"`
+}
+
+// ForProfile returns a copy of the config with the credentials and defaults
+// of the named profile in place of the top-level ones. Unless the profile
+// names one, its keychain service is the top-level one with the profile's
+// name appended, e.g. "TradeKing-live", so a profile whose credentials are
+// only in the keychain needs only an empty [profiles.live] table. An empty
+// name returns the config as is, and one not in the config
+// ErrUnknownProfile.
+func (cfg *ConfigFile) ForProfile(name string) (*ConfigFile, error) {
+	if name == "" {
+		return cfg, nil
+	}
+	p, ok := cfg.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q; add a [profiles.%v] table to the config file, empty if its credentials are only in the keychain", ErrUnknownProfile, name, name)
+	}
+
+	c := *cfg
+	c.Credentials = p.Credentials
+	if p.DefaultAccount != "" {
+		c.DefaultAccount = p.DefaultAccount
+	}
+	if p.Environment != "" {
+		c.Environment = p.Environment
+	}
+	c.KeychainService = p.KeychainService
+	if c.KeychainService == "" {
+		c.KeychainService = cfg.keychainService() + "-" + name
+	}
+	return &c, nil
+}
+
+// Keychain service that credentials are stored under
+func (cfg *ConfigFile) keychainService() string {
+	if cfg.KeychainService != "" {
+		return cfg.KeychainService
+	}
+	return keychainService
+}
+
+// Name of the keychain item that holds the credential named key
+func (cfg *ConfigFile) keychainItem(key string) string {
+	if item := cfg.KeychainItems[key]; item != "" {
+		return item
+	}
+	return key
+}
+
+// AccountID returns the ID of the account with the given ID or nickname. An
+// empty name means the default account, if any.
+func (cfg *ConfigFile) AccountID(name string) string {
+	if name == "" {
+		name = cfg.DefaultAccount
+	}
+	for id, nickname := range cfg.Accounts {
+		if nickname != "" && strings.EqualFold(nickname, name) {
+			return id
+		}
+	}
+	return name
+}
+
+// LoadConfigFile reads the config file at path. A missing file is treated as
+// an empty one.
+func LoadConfigFile(path string) (*ConfigFile, error) {
+	var cfg ConfigFile
+	if _, err := toml.DecodeFile(path, &cfg); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Save writes the config to the file at path, readable only by the user
+// since it may hold credentials. Comments in the file are not kept.
+func (cfg *ConfigFile) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := toml.NewEncoder(f).Encode(cfg); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Change the credentials of the named profile, adding it if need be
+func (cfg *ConfigFile) updateCredentials(profile string, update func(*Credentials)) {
+	if profile == "" {
+		update(&cfg.Credentials)
+		return
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = make(map[string]Profile)
+	}
+	p := cfg.Profiles[profile]
+	update(&p.Credentials)
+	cfg.Profiles[profile] = p
+}
+
+// LoadProfile reads the config file at path and selects the named profile;
+// see ForProfile
+func LoadProfile(path, profile string) (*ConfigFile, error) {
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.ForProfile(profile)
+}
+
+var credentialEnvVars = []string{
+	"ALLY_CONSUMER_KEY",
+	"ALLY_CONSUMER_SECRET",
+	"ALLY_ACCESS_TOKEN",
+	"ALLY_ACCESS_SECRET",
+}
+
+// Names of the credentials, in the same order as credentials.fields, which
+// are also the default names of their keychain items
+var keychainAccounts = []string{
+	"consumer_key",
+	"consumer_secret",
+	"access_token",
+	"access_secret",
+}
+
+// CredentialNames returns the names of the credentials, as in the config file,
+// which StoreCredential accepts
+func CredentialNames() []string {
+	return append([]string(nil), keychainAccounts...)
+}
+
+func (c *Credentials) fields() []*string {
+	return []*string{&c.ConsumerKey, &c.ConsumerSecret, &c.AccessToken, &c.AccessSecret}
+}
+
+// Validate checks that no credentials are missing
+func (c *Credentials) Validate() error {
+	for i, f := range c.fields() {
+		if *f == "" {
+			return fmt.Errorf("missing %v", keychainAccounts[i])
+		}
+	}
+	return nil
+}
+
+// DefaultConfigPath returns the path to config.toml in $XDG_CONFIG_HOME, or
+// ~/.config if it is not set
+func DefaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "allyapi", "config.toml")
+}
+
+func credsFromEnv() (*Credentials, error) {
+	var creds Credentials
+	for i, f := range creds.fields() {
+		v := os.Getenv(credentialEnvVars[i])
+		if v == "" {
+			return nil, fmt.Errorf("environment variable %v is not set", credentialEnvVars[i])
+		}
+		*f = v
+	}
+	return &creds, nil
+}
+
+func credsFromFile(cfg *ConfigFile, path string) (*Credentials, error) {
+	if err := cfg.Credentials.Validate(); err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	return &cfg.Credentials, nil
+}
+
+func credsFromKeychain(cfg *ConfigFile) (*Credentials, error) {
+	var creds Credentials
+	service := cfg.keychainService()
+	for i, f := range creds.fields() {
+		item := cfg.keychainItem(keychainAccounts[i])
+		v, err := getCredsFromKeychain(service, item)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", item, err)
+		}
+		*f = v
+	}
+	return &creds, nil
+}
+
+// LoadCredentials loads credentials from the given source: "env", "file",
+// "keychain", or
+// "auto", which tries each of them in that order, skipping a config file
+// without credentials. "keychain" uses the platform's secure credential
+// store: Keychain on macOS, the Secret Service on Linux, or the Credential
+// Manager on Windows, under the service and item names given by the config
+// file's keychain_service and keychain_items.
+func LoadCredentials(source, path string) (*Credentials, error) {
+	return LoadProfileCredentials(source, path, "")
+}
+
+// LoadProfileCredentials is like LoadCredentials, but loads the credentials
+// of the named profile from the config file or the keychain
+func LoadProfileCredentials(source, path, profile string) (*Credentials, error) {
+	if source == "env" {
+		return credsFromEnv()
+	}
+	cfg, err := LoadProfile(path, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	switch source {
+	case "file":
+		return credsFromFile(cfg, path)
+	case "keychain", "keyring":
+		return credsFromKeychain(cfg)
+	case "auto":
+		if os.Getenv(credentialEnvVars[0]) != "" {
+			return credsFromEnv()
+		}
+		// The config file may hold only other settings
+		if cfg.Credentials != (Credentials{}) {
+			return credsFromFile(cfg, path)
+		}
+		return credsFromKeychain(cfg)
+	default:
+		return nil, fmt.Errorf("unknown credential source %q", source)
+	}
+}
+
+// StoreCredentials saves credentials to the given source. "auto" saves to the
+// keychain.
+func StoreCredentials(source, path string, creds *Credentials) error {
+	return StoreProfileCredentials(source, path, "", creds)
+}
+
+// StoreProfileCredentials is like StoreCredentials, but saves the credentials
+// of the named profile, adding it to the config file if need be
+func StoreProfileCredentials(source, path, profile string, creds *Credentials) error {
+	switch source {
+	case "file":
+		cfg, err := LoadConfigFile(path)
+		if err != nil {
+			return err
+		}
+		cfg.updateCredentials(profile, func(c *Credentials) { *c = *creds })
+		return cfg.Save(path)
+	case "keychain", "keyring", "auto":
+		cfg, err := LoadProfile(path, profile)
+		if err != nil {
+			return err
+		}
+		service := cfg.keychainService()
+		for i, f := range creds.fields() {
+			item := cfg.keychainItem(keychainAccounts[i])
+			if err := setCredsInKeychain(service, item, *f); err != nil {
+				return fmt.Errorf("%v: %v", item, err)
+			}
+		}
+		return nil
+	case "env":
+		return errors.New("cannot store credentials in the environment; use -creds file or -creds keychain")
+	default:
+		return fmt.Errorf("unknown credential source %q", source)
+	}
+}
+
+// StoreCredential saves one credential of the named profile, given by its
+// name in the config file, e.g. "consumer_key", to the given source. "auto"
+// saves to the keychain.
+func StoreCredential(source, path, profile, key, value string) error {
+	i := -1
+	for j, k := range keychainAccounts {
+		if k == key {
+			i = j
+		}
+	}
+	if i < 0 {
+		return fmt.Errorf("unknown credential %q; expected one of %v", key, strings.Join(keychainAccounts, ", "))
+	}
+
+	switch source {
+	case "file":
+		cfg, err := LoadConfigFile(path)
+		if err != nil {
+			return err
+		}
+		cfg.updateCredentials(profile, func(c *Credentials) { *c.fields()[i] = value })
+		return cfg.Save(path)
+	case "keychain", "keyring", "auto":
+		cfg, err := LoadProfile(path, profile)
+		if err != nil {
+			return err
+		}
+		return setCredsInKeychain(cfg.keychainService(), cfg.keychainItem(key), value)
+	case "env":
+		return errors.New("cannot store credentials in the environment; use -creds file or -creds keychain")
+	default:
+		return fmt.Errorf("unknown credential source %q", source)
+	}
+}
@@ -0,0 +1,54 @@
+package allyapi
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestForProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	err := os.WriteFile(path, []byte(`default_account = "111"
+
+[credentials]
+consumer_key = "top"
+
+[profiles.paper]
+default_account = "222"
+keychain_service = "Paper"
+
+[profiles.paper.credentials]
+consumer_key = "paper"
+
+[profiles.live]
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		profile, account, key, service string
+	}{
+		{"", "111", "top", "TradeKing"},
+		{"paper", "222", "paper", "Paper"},
+		{"live", "111", "", "TradeKing-live"},
+	} {
+		cfg, err := LoadProfile(path, tt.profile)
+		if err != nil {
+			t.Errorf("%q: %v", tt.profile, err)
+			continue
+		}
+		if cfg.DefaultAccount != tt.account || cfg.Credentials.ConsumerKey != tt.key || cfg.keychainService() != tt.service {
+			t.Errorf("%q: account %q, consumer key %q, service %q; want %q, %q, %q", tt.profile,
+				cfg.DefaultAccount, cfg.Credentials.ConsumerKey, cfg.keychainService(), tt.account, tt.key, tt.service)
+		}
+	}
+
+	if _, err := LoadProfile(path, "lvie"); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("got %v for a misspelled profile, want ErrUnknownProfile", err)
+	}
+	if err := StoreCredential("keychain", path, "lvie", "consumer_key", "x"); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("got %v storing to a misspelled profile, want ErrUnknownProfile", err)
+	}
+}
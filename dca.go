@@ -0,0 +1,178 @@
+package allyapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DCAPlan is a recurring buy of a symbol, for dollar-cost averaging, as
+// configured in the config file, e.g.
+//
+//	[dca.vti]
+//	symbol = "VTI"
+//	amount = 250
+//	schedule = "weekly"
+//
+// Each buy is of Quantity shares or, if it is zero, as many whole shares as
+// Amount buys at the ask.
+type DCAPlan struct {
+	Account  string  `toml:"account,omitempty"`
+	Symbol   string  `toml:"symbol"`
+	Amount   Decimal `toml:"amount,omitempty"`
+	Quantity int     `toml:"quantity,omitempty"`
+
+	// How often to buy: daily, weekly, biweekly, or monthly
+	Schedule string `toml:"schedule"`
+
+	// "market", the default, or "limit" at the ask plus LimitPercent
+	Type         string  `toml:"type,omitempty"`
+	LimitPercent float64 `toml:"limit_pct,omitempty"`
+}
+
+// Validate reports whether the plan is complete
+func (p *DCAPlan) Validate() error {
+	switch {
+	case p.Symbol == "":
+		return errors.New("no symbol")
+	case p.Amount <= 0 && p.Quantity <= 0:
+		return errors.New("no amount or quantity")
+	case p.Amount > 0 && p.Quantity > 0:
+		return errors.New("both amount and quantity given")
+	case p.Type != "" && p.Type != "market" && p.Type != "limit":
+		return fmt.Errorf("invalid order type %q: use market or limit", p.Type)
+	}
+	_, err := p.Next(time.Time{})
+	return err
+}
+
+// Next returns the day the buy after one on last is due, at market
+// midnight. Nothing bought yet is due now.
+func (p *DCAPlan) Next(last time.Time) (time.Time, error) {
+	var days, months int
+	switch strings.ToLower(p.Schedule) {
+	case "daily":
+		days = 1
+	case "weekly":
+		days = 7
+	case "biweekly":
+		days = 14
+	case "monthly":
+		months = 1
+	default:
+		return time.Time{}, fmt.Errorf("invalid schedule %q: use daily, weekly, biweekly, or monthly", p.Schedule)
+	}
+	if last.IsZero() {
+		return time.Time{}, nil
+	}
+	last = last.In(MarketTime)
+	day := last.Day() + days
+	if months > 0 {
+		// Buys late in the month come at the end of shorter months
+		end := time.Date(last.Year(), last.Month()+time.Month(months)+1, 0, 0, 0, 0, 0, MarketTime)
+		day = min(day, end.Day())
+	}
+	return time.Date(last.Year(), last.Month()+time.Month(months), day, 0, 0, 0, 0, MarketTime), nil
+}
+
+// Due reports whether a buy is due at now after one on last. Buys are only
+// due on weekdays, so that market orders aren't left waiting for the open
+// over a weekend.
+func (p *DCAPlan) Due(last, now time.Time) (bool, error) {
+	next, err := p.Next(last)
+	if err != nil {
+		return false, err
+	}
+	now = now.In(MarketTime)
+	if now.Weekday() == time.Saturday || now.Weekday() == time.Sunday {
+		return false, nil
+	}
+	return !now.Before(next), nil
+}
+
+// DCAOrder returns the order for the plan's next buy in account, priced
+// from a current quote. It is an error if the amount buys no shares.
+func (ac *Client) DCAOrder(p *DCAPlan, account string) (*Order, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	o := &Order{Account: account, Symbol: strings.ToUpper(p.Symbol), Side: "buy", Type: "market", Quantity: p.Quantity, TimeInForce: "day"}
+	if p.Amount <= 0 && p.Type != "limit" {
+		return o, nil
+	}
+
+	ask, err := ac.askPrice(o.Symbol)
+	if err != nil {
+		return nil, fmt.Errorf("error getting quote: %v", err)
+	}
+	if ask <= 0 {
+		return nil, fmt.Errorf("no price for %v", o.Symbol)
+	}
+	price := ask
+	if p.Type == "limit" {
+		o.Type = "limit"
+		o.Price = ask.Mul(NewDecimal(1 + p.LimitPercent/100)).Round(2)
+		price = o.Price
+	}
+	if p.Amount > 0 {
+		o.Quantity = int(p.Amount / price)
+		if o.Quantity == 0 {
+			return nil, fmt.Errorf("%v doesn't buy a share of %v at %v", p.Amount.StringFixed(2), o.Symbol, price.StringFixed(2))
+		}
+	}
+	return o, nil
+}
+
+// DCARun is the record of a plan's buys
+type DCARun struct {
+	// When the last buy was placed, and its order
+	Last    time.Time `json:"last"`
+	OrderID string    `json:"order_id,omitempty"`
+
+	// Buys placed and their estimated cost in total
+	Buys     int     `json:"buys"`
+	Invested Decimal `json:"invested"`
+
+	// When a buy was about to be placed, until it is recorded. A buy still
+	// pending may have been placed without being recorded.
+	Pending *time.Time `json:"pending,omitempty"`
+}
+
+// DCAState is the record of each plan's buys, by name
+type DCAState map[string]DCARun
+
+// LoadDCAState reads the state saved at path. A missing file has none.
+func LoadDCAState(path string) (DCAState, error) {
+	state := make(DCAState)
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &state); err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	return state, nil
+}
+
+// Save writes the state to path, replacing the file whole so that an
+// interrupted write doesn't lose the record of earlier buys
+func (s DCAState) Save(path string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
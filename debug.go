@@ -0,0 +1,90 @@
+package allyapi
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"regexp"
+	"sync"
+)
+
+// WithDebug writes each request, with its credentials redacted, and each
+// response to w as they are sent and received. Response bodies are written
+// as they are read, so streams appear message by message.
+func WithDebug(w io.Writer) Option {
+	return func(ac *Client) {
+		ac.debug = w
+	}
+}
+
+// OAuth parameters that would let someone reuse the credentials. The
+// signature is only good for one request but is redacted anyway.
+var secretParams = regexp.MustCompile(`(oauth_(?:consumer_key|token|signature)=")[^"]*"`)
+
+func redact(dump []byte) []byte {
+	return secretParams.ReplaceAll(dump, []byte(`${1}REDACTED"`))
+}
+
+// Writes requests and responses passing through to the next transport
+type debugTransport struct {
+	next http.RoundTripper
+
+	// Held while writing, so that concurrent requests don't interleave
+	// mid-line
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (t *debugTransport) write(b []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.w.Write(b)
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	dump, err := httputil.DumpRequestOut(req, true)
+	if err != nil {
+		return nil, err
+	}
+	t.write(append(append([]byte("> "), redact(dump)...), "\n\n"...))
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		t.write([]byte(fmt.Sprintf("< error: %v\n\n", err)))
+		return nil, err
+	}
+
+	// Show the body as the client sees it
+	if err := decompress(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	head, err := httputil.DumpResponse(resp, false)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	t.write(append([]byte("< "), head...))
+	resp.Body = &debugBody{ReadCloser: resp.Body, t: t}
+	return resp, nil
+}
+
+// Writes a response body as it is read
+type debugBody struct {
+	io.ReadCloser
+	t   *debugTransport
+	eof bool
+}
+
+func (b *debugBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.t.write(p[:n])
+	}
+	if err == io.EOF && !b.eof {
+		b.eof = true
+		b.t.write([]byte("\n\n"))
+	}
+	return n, err
+}
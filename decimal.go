@@ -0,0 +1,172 @@
+package allyapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Decimal is an exact amount of money or a price, in millionths. Use
+// ParseDecimal or NewDecimal rather than converting integers directly.
+type Decimal int64
+
+// Millionths in one
+const decimalScale = 1000000
+
+// NewDecimal returns f rounded to the nearest millionth
+func NewDecimal(f float64) Decimal {
+	return Decimal(math.Round(f * decimalScale))
+}
+
+// DecimalFromInt returns the decimal equal to i
+func DecimalFromInt(i int64) Decimal {
+	return Decimal(i * decimalScale)
+}
+
+// ParseDecimal parses a decimal number such as "123.45" or "-0.5", rounding
+// to the nearest millionth. Empty strings and "na" are zero.
+func ParseDecimal(s string) (Decimal, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "na") {
+		return 0, nil
+	}
+
+	r, ok := new(big.Rat).SetString(strings.TrimPrefix(s, "$"))
+	if !ok || strings.Contains(s, "/") {
+		return 0, fmt.Errorf("invalid decimal %q", s)
+	}
+	r.Mul(r, big.NewRat(decimalScale, 1))
+	return Decimal(roundRat(r)), nil
+}
+
+// Round a rational to the nearest integer, with halves away from zero
+func roundRat(r *big.Rat) int64 {
+	num, denom := new(big.Int).Set(r.Num()), r.Denom()
+	half := new(big.Int).Rsh(denom, 1)
+	if num.Sign() < 0 {
+		num.Sub(num, half)
+	} else {
+		num.Add(num, half)
+	}
+	return num.Quo(num, denom).Int64()
+}
+
+// Mul returns d * e, rounded to the nearest millionth
+func (d Decimal) Mul(e Decimal) Decimal {
+	r := new(big.Rat).SetFrac(new(big.Int).Mul(big.NewInt(int64(d)), big.NewInt(int64(e))), big.NewInt(decimalScale))
+	return Decimal(roundRat(r))
+}
+
+// MulInt returns d * n
+func (d Decimal) MulInt(n int64) Decimal {
+	return d * Decimal(n)
+}
+
+// Div returns d / e, rounded to the nearest millionth, or 0 if e is 0
+func (d Decimal) Div(e Decimal) Decimal {
+	if e == 0 {
+		return 0
+	}
+	r := new(big.Rat).SetFrac(new(big.Int).Mul(big.NewInt(int64(d)), big.NewInt(decimalScale)), big.NewInt(int64(e)))
+	return Decimal(roundRat(r))
+}
+
+// Abs returns the absolute value of d
+func (d Decimal) Abs() Decimal {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// Float64 returns d as a float, for ratios and display
+func (d Decimal) Float64() float64 {
+	return float64(d) / decimalScale
+}
+
+// Round returns d rounded to places decimal places, with halves away from
+// zero
+func (d Decimal) Round(places int) Decimal {
+	if places >= 6 {
+		return d
+	}
+	unit := Decimal(math.Pow10(6 - places))
+	half := unit / 2
+	if d < 0 {
+		return (d - half) / unit * unit
+	}
+	return (d + half) / unit * unit
+}
+
+// StringFixed formats d rounded to places decimal places, e.g. "12.30" for
+// two places
+func (d Decimal) StringFixed(places int) string {
+	if places > 6 {
+		places = 6
+	}
+	d = d.Round(places)
+
+	sign := ""
+	if d < 0 {
+		sign = "-"
+	}
+	abs := uint64(d.Abs())
+	whole := strconv.FormatUint(abs/decimalScale, 10)
+	if places <= 0 {
+		return sign + whole
+	}
+	frac := fmt.Sprintf("%06d", abs%decimalScale)[:places]
+	return sign + whole + "." + frac
+}
+
+// String formats d without trailing zeros, e.g. "12.3"
+func (d Decimal) String() string {
+	s := d.StringFixed(6)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// Set parses a decimal, so that a *Decimal can be used as a flag.Value
+func (d *Decimal) Set(s string) error {
+	v, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// MarshalText formats d as with String
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText parses a decimal as with ParseDecimal
+func (d *Decimal) UnmarshalText(text []byte) error {
+	return d.Set(string(text))
+}
+
+// MarshalJSON formats d as a quoted string, like the API does, so that it
+// isn't read back as a float
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.String() + `"`), nil
+}
+
+// UnmarshalJSON accepts either a number or a quoted string
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var s string
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	} else {
+		s = string(data)
+	}
+	return d.Set(s)
+}
@@ -0,0 +1,126 @@
+package allyapi
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want Decimal
+	}{
+		{"123.45", 123450000},
+		{"-0.5", -500000},
+		{"$19.99", 19990000},
+		{" 7 ", 7000000},
+		{"", 0},
+		{"NA", 0},
+		{"0.0000005", 1},
+		{"-0.0000005", -1},
+		{"1e2", 100000000},
+	} {
+		got, err := ParseDecimal(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseDecimal(%q) = %v, %v; want %v", tt.in, int64(got), err, int64(tt.want))
+		}
+	}
+
+	for _, in := range []string{"abc", "1/3", "1.2.3"} {
+		if _, err := ParseDecimal(in); err == nil {
+			t.Errorf("ParseDecimal(%q) succeeded", in)
+		}
+	}
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	d := func(s string) Decimal {
+		v, err := ParseDecimal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	// Floats would make these 0.30000000000000004 and 0.7000000000000001
+	if got := d("0.1") + d("0.2"); got.String() != "0.3" {
+		t.Errorf("0.1 + 0.2 = %v", got)
+	}
+	if got := d("0.07").MulInt(10); got.String() != "0.7" {
+		t.Errorf("0.07 * 10 = %v", got)
+	}
+
+	for _, tt := range []struct {
+		got  Decimal
+		want string
+	}{
+		{d("19.99").Mul(d("3")), "59.97"},
+		{d("1.5").Mul(d("-0.333333")), "-0.5"},
+		{d("100").Div(d("3")), "33.333333"},
+		{d("200").Div(d("3")), "66.666667"},
+		{d("-200").Div(d("3")), "-66.666667"},
+		{d("5").Div(0), "0"},
+		{d("-2.5").Abs(), "2.5"},
+		{d("2.345").Round(2), "2.35"},
+		{d("-2.345").Round(2), "-2.35"},
+		{d("2.344").Round(2), "2.34"},
+		{d("12.5").Round(0), "13"},
+	} {
+		if got := tt.got.String(); got != tt.want {
+			t.Errorf("got %v, want %v", got, tt.want)
+		}
+	}
+}
+
+func TestDecimalFormat(t *testing.T) {
+	for _, tt := range []struct {
+		d      Decimal
+		places int
+		want   string
+	}{
+		{NewDecimal(12.3), 2, "12.30"},
+		{NewDecimal(-0.005), 2, "-0.01"},
+		{NewDecimal(0.004), 2, "0.00"},
+		{NewDecimal(1234.5678), 0, "1235"},
+		{NewDecimal(1.234567), 8, "1.234567"},
+		{DecimalFromInt(-3), 1, "-3.0"},
+	} {
+		if got := tt.d.StringFixed(tt.places); got != tt.want {
+			t.Errorf("%v.StringFixed(%v) = %v, want %v", int64(tt.d), tt.places, got, tt.want)
+		}
+	}
+
+	if got := NewDecimal(12.30).String(); got != "12.3" {
+		t.Errorf("String() = %v, want 12.3", got)
+	}
+	if got := DecimalFromInt(100).String(); got != "100" {
+		t.Errorf("String() = %v, want 100", got)
+	}
+	if got := NewDecimal(2.5).Float64(); got != 2.5 {
+		t.Errorf("Float64() = %v, want 2.5", got)
+	}
+}
+
+func TestDecimalJSON(t *testing.T) {
+	var v struct {
+		A, B, C Decimal
+	}
+	if err := json.Unmarshal([]byte(`{"A": "1.10", "B": 2.25, "C": null}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.A != NewDecimal(1.1) || v.B != NewDecimal(2.25) || v.C != 0 {
+		t.Errorf("decoded %+v", v)
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); got != `{"A":"1.1","B":"2.25","C":"0"}` {
+		t.Errorf("encoded %v", got)
+	}
+
+	if err := json.Unmarshal([]byte(`{"A": "x"}`), &v); err == nil {
+		t.Error("decoded an invalid decimal")
+	}
+}
@@ -0,0 +1,125 @@
+package allyapi
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Dispatcher passes stream messages to handlers registered for their
+// symbols, so that each part of a program sees only the symbols it's
+// interested in:
+//
+//	d := allyapi.NewDispatcher()
+//	d.OnQuote("AAPL", func(q *allyapi.StreamQuote) { ... })
+//	ch, err := client.Stream(ctx, d.Symbols())
+//	...
+//	err = d.Run(ch)
+//
+// Handlers are called one at a time from the goroutine calling Dispatch or
+// Run: those for the message's symbol, then those for every symbol, each in
+// the order registered.
+type Dispatcher struct {
+	mu     sync.RWMutex
+	quotes map[string][]func(*StreamQuote)
+	trades map[string][]func(*StreamTrade)
+	status []func(StreamMessage)
+}
+
+// NewDispatcher returns a dispatcher with no handlers
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		quotes: make(map[string][]func(*StreamQuote)),
+		trades: make(map[string][]func(*StreamTrade)),
+	}
+}
+
+// OnQuote registers fn to be called with each quote for symbol, or for every
+// symbol if symbol is "*"
+func (d *Dispatcher) OnQuote(symbol string, fn func(*StreamQuote)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	symbol = strings.ToUpper(symbol)
+	d.quotes[symbol] = append(d.quotes[symbol], fn)
+}
+
+// OnTrade registers fn to be called with each trade for symbol, or for every
+// symbol if symbol is "*"
+func (d *Dispatcher) OnTrade(symbol string, fn func(*StreamTrade)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	symbol = strings.ToUpper(symbol)
+	d.trades[symbol] = append(d.trades[symbol], fn)
+}
+
+// OnStatus registers fn to be called with each status message
+func (d *Dispatcher) OnStatus(fn func(StreamMessage)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.status = append(d.status, fn)
+}
+
+// Symbols returns the symbols with handlers, not including "*"
+func (d *Dispatcher) Symbols() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	for sym := range d.quotes {
+		seen[sym] = true
+	}
+	for sym := range d.trades {
+		seen[sym] = true
+	}
+	delete(seen, "*")
+
+	symbols := make([]string, 0, len(seen))
+	for sym := range seen {
+		symbols = append(symbols, sym)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// Dispatch passes msg to the handlers for its symbol and event
+func (d *Dispatcher) Dispatch(msg StreamMessage) {
+	d.mu.RLock()
+	var quoteFns []func(*StreamQuote)
+	var tradeFns []func(*StreamTrade)
+	var statusFns []func(StreamMessage)
+	switch {
+	case msg.Quote != nil:
+		sym := strings.ToUpper(msg.Quote.Symbol)
+		quoteFns = append(append(quoteFns, d.quotes[sym]...), d.quotes["*"]...)
+	case msg.Trade != nil:
+		sym := strings.ToUpper(msg.Trade.Symbol)
+		tradeFns = append(append(tradeFns, d.trades[sym]...), d.trades["*"]...)
+	case msg.Event == StatusEvent:
+		statusFns = append(statusFns, d.status...)
+	}
+	d.mu.RUnlock()
+
+	// Handlers may register more handlers, so call them without the lock
+	for _, fn := range quoteFns {
+		fn(msg.Quote)
+	}
+	for _, fn := range tradeFns {
+		fn(msg.Trade)
+	}
+	for _, fn := range statusFns {
+		fn(msg)
+	}
+}
+
+// Run dispatches messages from ch until it is closed, and returns the error
+// that ended the stream, if any
+func (d *Dispatcher) Run(ch <-chan StreamMessage) error {
+	var err error
+	for msg := range ch {
+		d.Dispatch(msg)
+		if msg.Event == StatusEvent && msg.Status == StreamClosed {
+			err = msg.Err
+		}
+	}
+	return err
+}
@@ -0,0 +1,86 @@
+package allyapi
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Error classes that an APIError can be compared to with errors.Is
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrNotFound     = errors.New("not found")
+	ErrRateLimited  = errors.New("rate limit reached")
+	ErrServer       = errors.New("server error")
+)
+
+// APIError is an error reported by the API, either with an HTTP error status
+// or in the error field of a response
+type APIError struct {
+	// HTTP status of the response
+	StatusCode int
+
+	// Error message from the response, or the HTTP status text if the
+	// response didn't include one
+	Message string
+
+	// ID of the response, for reporting problems to Ally
+	RequestID string
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("API error: %v", e.Message)
+	if e.StatusCode != http.StatusOK {
+		msg += fmt.Sprintf(" (HTTP %d)", e.StatusCode)
+	}
+	if e.RequestID != "" {
+		msg += fmt.Sprintf(" [request %v]", e.RequestID)
+	}
+	return msg
+}
+
+// Is reports whether the error belongs to one of the error classes
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// Build an APIError from an HTTP error response, using the body's error
+// message and ID when it can be decoded
+func newHTTPError(resp *http.Response, isXML bool) *APIError {
+	e := &APIError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return e
+	}
+
+	var body ResponseBody
+	if isXML {
+		err = xml.Unmarshal(b, &body)
+	} else {
+		var m APIResponse
+		if err = json.Unmarshal(b, &m); err == nil && m.Response != nil {
+			body = *m.Response
+		}
+	}
+	if err == nil {
+		if body.Error != "" {
+			e.Message = body.Error
+		}
+		e.RequestID = body.ID
+	}
+	return e
+}
@@ -0,0 +1,476 @@
+package allyapi
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// https://www.ally.com/api/invest/documentation/fixml/
+const fixmlNamespace = "http://www.fixprotocol.org/FIXML-5-0-SP2"
+
+type fixml struct {
+	XMLName   xml.Name        `xml:"FIXML"`
+	Xmlns     string          `xml:"xmlns,attr"`
+	Order     *fixmlOrder     `xml:"Order,omitempty"`
+	OrderList *fixmlOrderList `xml:"NewOrdList,omitempty"`
+
+	MultiLegOrder *fixmlMultiLegOrder `xml:"NewOrdMleg,omitempty"`
+}
+
+type fixmlOrderList struct {
+	ListID          string           `xml:",attr"`
+	BidTyp          string           `xml:",attr"`
+	TotNoOrdrs      int              `xml:",attr"`
+	ContingencyType string           `xml:",attr,omitempty"`
+	Orders          []fixmlListOrder `xml:"Ord"`
+}
+
+type fixmlListOrder struct {
+	OrdID     string `xml:",attr"`
+	ListSeqNo int    `xml:",attr"`
+	fixmlOrder
+}
+
+type fixmlOrder struct {
+	TmInForce string          `xml:",attr,omitempty"`
+	Typ       string          `xml:",attr"`
+	Side      string          `xml:",attr"`
+	PosEfct   string          `xml:",attr,omitempty"`
+	AcctTyp   string          `xml:",attr,omitempty"`
+	Px        string          `xml:",attr,omitempty"`
+	StopPx    string          `xml:",attr,omitempty"`
+	ExecInst  string          `xml:",attr,omitempty"`
+	Acct      string          `xml:",attr"`
+	Instrmt   fixmlInstrument `xml:"Instrmt"`
+	PegInstr  *fixmlPeg       `xml:"PegInstr,omitempty"`
+	OrdQty    fixmlQuantity   `xml:"OrdQty"`
+}
+
+// Offset of a trailing stop from the last price. OfstTyp is 0 for an amount
+// or 1 for a percent.
+type fixmlPeg struct {
+	OfstTyp  string `xml:",attr"`
+	PegPxTyp string `xml:",attr"`
+	OfstVal  string `xml:",attr"`
+}
+
+type fixmlInstrument struct {
+	CFI    string `xml:",attr,omitempty"`
+	SecTyp string `xml:",attr"`
+	MatDt  string `xml:",attr,omitempty"`
+	StrkPx string `xml:",attr,omitempty"`
+	Sym    string `xml:",attr"`
+}
+
+type fixmlQuantity struct {
+	Qty string `xml:",attr"`
+}
+
+// FIXML codes for each order side
+var orderSides = map[string]string{
+	"buy":          "1",
+	"sell":         "2",
+	"sell_short":   "5",
+	"buy_to_cover": "1",
+}
+
+// FIXML codes for each order type
+var orderTypes = map[string]string{
+	"market":     "1",
+	"limit":      "2",
+	"stop":       "3",
+	"stop_limit": "4",
+
+	// Pegged to the last price
+	"trailing_stop": "P",
+}
+
+// FIXML codes for each time in force
+var timesInForce = map[string]string{
+	"day": "0",
+	"gtc": "1",
+	"moc": "7",
+}
+
+// Order is an equity or option order. Side is one of "buy", "sell",
+// "sell_short", or "buy_to_cover"; Type is one of "market", "limit", "stop",
+// "stop_limit", or "trailing_stop". An OCC Symbol makes an option order, for
+// which "buy" and "sell" open and close a long position, and "sell_short" and
+// "buy_to_cover" open and close a short one.
+//
+// A trailing stop follows the last price by either TrailAmount dollars or
+// TrailPercent percent.
+//
+// TimeInForce is "day", the default, "gtc" (good til canceled), or "moc"
+// (market on close). Market orders can't be good til canceled, and only
+// equity market orders can be market on close.
+type Order struct {
+	Account   string  `json:"account,omitempty" toml:"account,omitempty"`
+	Symbol    string  `json:"symbol,omitempty" toml:"symbol,omitempty"`
+	Side      string  `json:"side,omitempty" toml:"side,omitempty"`
+	Type      string  `json:"type,omitempty" toml:"type,omitempty"`
+	Quantity  int     `json:"quantity,omitempty" toml:"quantity,omitempty"`
+	Price     Decimal `json:"price,omitempty" toml:"price,omitempty"`
+	StopPrice Decimal `json:"stop_price,omitempty" toml:"stop_price,omitempty"`
+
+	TrailAmount  Decimal `json:"trail_amount,omitempty" toml:"trail_amount,omitempty"`
+	TrailPercent float64 `json:"trail_percent,omitempty" toml:"trail_percent,omitempty"`
+
+	TimeInForce string `json:"tif,omitempty" toml:"tif,omitempty"`
+}
+
+func formatPrice(p Decimal) string {
+	return p.String()
+}
+
+// Position effects of option orders by side
+var optionPositionEffects = map[string]string{
+	"buy":          "O",
+	"sell":         "C",
+	"sell_short":   "O",
+	"buy_to_cover": "C",
+}
+
+// Build the FIXML message for an order
+func (o *Order) fixml() (*fixml, error) {
+	fo, err := o.fixmlOrder()
+	if err != nil {
+		return nil, err
+	}
+	return &fixml{Xmlns: fixmlNamespace, Order: fo}, nil
+}
+
+func (o *Order) fixmlOrder() (*fixmlOrder, error) {
+	if o.Account == "" {
+		return nil, errors.New("order requires an account")
+	}
+	if o.Symbol == "" {
+		return nil, errors.New("order requires a symbol")
+	}
+	if o.Quantity < 1 {
+		return nil, fmt.Errorf("invalid quantity: %v", o.Quantity)
+	}
+
+	side, ok := orderSides[o.Side]
+	if !ok {
+		return nil, fmt.Errorf("unknown order side: %q", o.Side)
+	}
+	typ, ok := orderTypes[o.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown order type: %q", o.Type)
+	}
+	tif, err := o.timeInForce()
+	if err != nil {
+		return nil, err
+	}
+
+	fo := fixmlOrder{
+		TmInForce: tif,
+		Typ:       typ,
+		Side:      side,
+		Acct:      o.Account,
+		Instrmt:   fixmlInstrument{SecTyp: "CS", Sym: strings.ToUpper(o.Symbol)},
+		OrdQty:    fixmlQuantity{Qty: strconv.Itoa(o.Quantity)},
+	}
+
+	if opt, err := ParseOptionSymbol(o.Symbol); err == nil {
+		// Options are sold to open rather than sold short
+		if o.Side == "sell_short" {
+			fo.Side = orderSides["sell"]
+		}
+		fo.PosEfct = optionPositionEffects[o.Side]
+		fo.Instrmt = fixmlInstrument{
+			CFI:    "O" + string(opt.Type),
+			SecTyp: "OPT",
+			MatDt:  opt.Expiration.Format("2006-01-02") + "T00:00:00.000-05:00",
+			StrkPx: formatPrice(opt.Strike),
+			Sym:    opt.Underlying,
+		}
+	} else if o.Side == "buy_to_cover" {
+		// Buying to cover is a buy against the short account type
+		fo.AcctTyp = "5"
+	}
+
+	switch o.Type {
+	case "limit", "stop_limit":
+		if o.Price <= 0 {
+			return nil, fmt.Errorf("%v order requires a price", o.Type)
+		}
+		fo.Px = formatPrice(o.Price)
+	}
+	switch o.Type {
+	case "stop", "stop_limit":
+		if o.StopPrice <= 0 {
+			return nil, fmt.Errorf("%v order requires a stop price", o.Type)
+		}
+		fo.StopPx = formatPrice(o.StopPrice)
+	}
+	if o.Type == "trailing_stop" {
+		peg, err := o.trailingPeg()
+		if err != nil {
+			return nil, err
+		}
+		fo.ExecInst = "a"
+		fo.PegInstr = peg
+	} else if o.TrailAmount != 0 || o.TrailPercent != 0 {
+		return nil, fmt.Errorf("%v order cannot have a trailing amount or percent", o.Type)
+	}
+
+	return &fo, nil
+}
+
+// Check the time in force against the order type
+func (o *Order) timeInForce() (string, error) {
+	name := strings.ToLower(o.TimeInForce)
+	if name == "" {
+		name = "day"
+	}
+	tif, ok := timesInForce[name]
+	if !ok {
+		return "", fmt.Errorf("unknown time in force: %q", o.TimeInForce)
+	}
+	switch {
+	case name == "gtc" && o.Type == "market":
+		return "", errors.New("market orders cannot be good til canceled")
+	case name == "moc" && o.Type != "market":
+		return "", fmt.Errorf("%v orders cannot be market on close", o.Type)
+	case name == "moc" && IsOptionSymbol(o.Symbol):
+		return "", errors.New("option orders cannot be market on close")
+	}
+	return tif, nil
+}
+
+// Check the trailing amount or percent of a trailing stop
+func (o *Order) trailingPeg() (*fixmlPeg, error) {
+	switch {
+	case o.TrailAmount != 0 && o.TrailPercent != 0:
+		return nil, errors.New("trailing stop requires a trailing amount or percent, not both")
+	case o.TrailAmount > 0:
+		return &fixmlPeg{OfstTyp: "0", PegPxTyp: "1", OfstVal: formatPrice(o.TrailAmount)}, nil
+	case o.TrailPercent > 0 && o.TrailPercent < 100:
+		return &fixmlPeg{OfstTyp: "1", PegPxTyp: "1", OfstVal: strconv.FormatFloat(o.TrailPercent, 'f', -1, 64)}, nil
+	case o.TrailPercent != 0:
+		return nil, fmt.Errorf("invalid trailing percent: %v", o.TrailPercent)
+	case o.TrailAmount != 0:
+		return nil, fmt.Errorf("invalid trailing amount: %v", o.TrailAmount)
+	default:
+		return nil, errors.New("trailing stop requires a trailing amount or percent")
+	}
+}
+
+// Kinds of order groups
+const (
+	// One cancels other: filling either order cancels the other
+	OCO = "oco"
+	// One triggers other: filling the first order places the second
+	OTO = "oto"
+	// One triggers OCO: filling the first order places the other two as an
+	// OCO, such as an entry with a stop loss and a profit target
+	OTOCO = "otoco"
+)
+
+// FIXML contingency types and order counts of order groups
+var orderGroupTypes = map[string]struct {
+	contingency string
+	orders      int
+}{
+	OCO:   {"1", 2},
+	OTO:   {"2", 2},
+	OTOCO: {"101", 3},
+}
+
+// OrderGroup is a group of linked orders of kind OCO, OTO, or OTOCO, in the
+// order given in their descriptions. All orders must be for the same account.
+type OrderGroup struct {
+	Kind   string
+	Orders []Order
+}
+
+// Account returns the account of the group's orders
+func (g *OrderGroup) Account() string {
+	if len(g.Orders) == 0 {
+		return ""
+	}
+	return g.Orders[0].Account
+}
+
+// Build the FIXML message for an order group
+func (g *OrderGroup) fixml() (*fixml, error) {
+	typ, ok := orderGroupTypes[g.Kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown order group kind: %q", g.Kind)
+	}
+	if len(g.Orders) != typ.orders {
+		return nil, fmt.Errorf("%v order group requires %v orders, not %v", strings.ToUpper(g.Kind), typ.orders, len(g.Orders))
+	}
+
+	list := &fixmlOrderList{
+		BidTyp:          "3",
+		TotNoOrdrs:      len(g.Orders),
+		ContingencyType: typ.contingency,
+	}
+	for i := range g.Orders {
+		o := &g.Orders[i]
+		if o.Account != g.Account() {
+			return nil, errors.New("orders in a group must be for the same account")
+		}
+		fo, err := o.fixmlOrder()
+		if err != nil {
+			return nil, fmt.Errorf("order %v: %v", i+1, err)
+		}
+		list.Orders = append(list.Orders, fixmlListOrder{ListSeqNo: i + 1, fixmlOrder: *fo})
+	}
+	return &fixml{Xmlns: fixmlNamespace, OrderList: list}, nil
+}
+
+type fixmlMultiLegOrder struct {
+	TmInForce string             `xml:",attr,omitempty"`
+	Px        string             `xml:",attr,omitempty"`
+	OrdTyp    string             `xml:",attr"`
+	Acct      string             `xml:",attr"`
+	Orders    []fixmlMultiLegOrd `xml:"Ord"`
+}
+
+type fixmlMultiLegOrd struct {
+	OrdQty  string   `xml:",attr"`
+	PosEfct string   `xml:",attr,omitempty"`
+	Leg     fixmlLeg `xml:"Leg"`
+}
+
+type fixmlLeg struct {
+	Side   string `xml:",attr"`
+	Strk   string `xml:",attr,omitempty"`
+	Mat    string `xml:",attr,omitempty"`
+	MMY    string `xml:",attr,omitempty"`
+	SecTyp string `xml:",attr"`
+	CFI    string `xml:",attr,omitempty"`
+	Sym    string `xml:",attr"`
+}
+
+// Most legs the API accepts in a multi-leg order
+const maxLegs = 4
+
+// Leg is one leg of a multi-leg order. Symbol is usually an OCC option
+// symbol, but may be a stock, as in a buy-write. Side is as for an Order, and
+// Ratio is the number of contracts or shares of the leg in one spread,
+// defaulting to 1.
+type Leg struct {
+	Symbol string `toml:"symbol"`
+	Side   string `toml:"side"`
+	Ratio  int    `toml:"ratio,omitempty"`
+}
+
+// MultiLegOrder is an order that fills all of its legs together, such as a
+// vertical, calendar, or straddle. Quantity is the number of spreads, so each
+// leg's quantity is Quantity times its ratio.
+//
+// Type is "market" or "limit". The limit Price is the net price of one spread:
+// positive for a debit and negative for a credit. TimeInForce is "day", the
+// default, or "gtc".
+type MultiLegOrder struct {
+	Account     string
+	Legs        []Leg
+	Type        string
+	Quantity    int
+	Price       Decimal
+	TimeInForce string
+}
+
+// Build the FIXML message for a multi-leg order
+func (m *MultiLegOrder) fixml() (*fixml, error) {
+	if m.Account == "" {
+		return nil, errors.New("order requires an account")
+	}
+	if len(m.Legs) < 2 || len(m.Legs) > maxLegs {
+		return nil, fmt.Errorf("multi-leg order requires 2 to %v legs, not %v", maxLegs, len(m.Legs))
+	}
+	if m.Quantity < 1 {
+		return nil, fmt.Errorf("invalid quantity: %v", m.Quantity)
+	}
+
+	mo := &fixmlMultiLegOrder{Acct: m.Account}
+	switch m.Type {
+	case "market":
+		if m.Price != 0 {
+			return nil, errors.New("market order cannot have a price")
+		}
+	case "limit":
+		if m.Price == 0 {
+			return nil, errors.New("limit order requires a net price")
+		}
+		mo.Px = formatPrice(m.Price)
+	default:
+		return nil, fmt.Errorf("unknown order type: %q", m.Type)
+	}
+	mo.OrdTyp = orderTypes[m.Type]
+
+	tif := strings.ToLower(m.TimeInForce)
+	switch tif {
+	case "", "day":
+		mo.TmInForce = timesInForce["day"]
+	case "gtc":
+		if m.Type == "market" {
+			return nil, errors.New("market orders cannot be good til canceled")
+		}
+		mo.TmInForce = timesInForce["gtc"]
+	default:
+		return nil, fmt.Errorf("multi-leg orders cannot have time in force %q", m.TimeInForce)
+	}
+
+	for i, l := range m.Legs {
+		ord, err := l.fixml(m.Quantity)
+		if err != nil {
+			return nil, fmt.Errorf("leg %v: %v", i+1, err)
+		}
+		mo.Orders = append(mo.Orders, *ord)
+	}
+	return &fixml{Xmlns: fixmlNamespace, MultiLegOrder: mo}, nil
+}
+
+// Build a leg for quantity spreads
+func (l *Leg) fixml(quantity int) (*fixmlMultiLegOrd, error) {
+	if l.Symbol == "" {
+		return nil, errors.New("leg requires a symbol")
+	}
+	ratio := l.Ratio
+	if ratio == 0 {
+		ratio = 1
+	} else if ratio < 0 {
+		return nil, fmt.Errorf("invalid ratio: %v", l.Ratio)
+	}
+	side, ok := orderSides[l.Side]
+	if !ok {
+		return nil, fmt.Errorf("unknown order side: %q", l.Side)
+	}
+
+	ord := &fixmlMultiLegOrd{
+		OrdQty: strconv.Itoa(quantity * ratio),
+		Leg:    fixmlLeg{Side: side, SecTyp: "CS", Sym: strings.ToUpper(l.Symbol)},
+	}
+	opt, err := ParseOptionSymbol(l.Symbol)
+	if err != nil {
+		if l.Side == "buy_to_cover" {
+			return nil, errors.New("stock legs cannot buy to cover")
+		}
+		return ord, nil
+	}
+
+	// As for single orders, options are sold to open rather than sold short
+	if l.Side == "sell_short" {
+		ord.Leg.Side = orderSides["sell"]
+	}
+	ord.PosEfct = optionPositionEffects[l.Side]
+	ord.Leg = fixmlLeg{
+		Side:   ord.Leg.Side,
+		Strk:   formatPrice(opt.Strike),
+		Mat:    opt.Expiration.Format("2006-01-02") + "T00:00:00.000-05:00",
+		MMY:    opt.Expiration.Format("200601"),
+		SecTyp: "OPT",
+		CFI:    "O" + string(opt.Type),
+		Sym:    opt.Underlying,
+	}
+	return ord, nil
+}
@@ -0,0 +1,148 @@
+package allyapi
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+type fixmlBuilder interface {
+	fixml() (*fixml, error)
+}
+
+func marshalFIXML(t *testing.T, o fixmlBuilder) (string, error) {
+	t.Helper()
+	msg, err := o.fixml()
+	if err != nil {
+		return "", err
+	}
+	b, err := xml.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b), nil
+}
+
+func TestOrderFIXML(t *testing.T) {
+	const head = `<FIXML xmlns="http://www.fixprotocol.org/FIXML-5-0-SP2">`
+	for _, tt := range []struct {
+		name  string
+		order Order
+		want  string
+	}{
+		{
+			"market buy",
+			Order{Account: "1", Symbol: "aapl", Side: "buy", Type: "market", Quantity: 10},
+			head + `<Order TmInForce="0" Typ="1" Side="1" Acct="1"><Instrmt SecTyp="CS" Sym="AAPL"></Instrmt><OrdQty Qty="10"></OrdQty></Order></FIXML>`,
+		},
+		{
+			"gtc limit sell",
+			Order{Account: "1", Symbol: "AAPL", Side: "sell", Type: "limit", Quantity: 5, Price: NewDecimal(150.5), TimeInForce: "GTC"},
+			head + `<Order TmInForce="1" Typ="2" Side="2" Px="150.5" Acct="1"><Instrmt SecTyp="CS" Sym="AAPL"></Instrmt><OrdQty Qty="5"></OrdQty></Order></FIXML>`,
+		},
+		{
+			"stop limit",
+			Order{Account: "1", Symbol: "AAPL", Side: "sell", Type: "stop_limit", Quantity: 5, Price: NewDecimal(139.5), StopPrice: DecimalFromInt(140)},
+			head + `<Order TmInForce="0" Typ="4" Side="2" Px="139.5" StopPx="140" Acct="1"><Instrmt SecTyp="CS" Sym="AAPL"></Instrmt><OrdQty Qty="5"></OrdQty></Order></FIXML>`,
+		},
+		{
+			"buy to cover",
+			Order{Account: "1", Symbol: "AAPL", Side: "buy_to_cover", Type: "market", Quantity: 5},
+			head + `<Order TmInForce="0" Typ="1" Side="1" AcctTyp="5" Acct="1"><Instrmt SecTyp="CS" Sym="AAPL"></Instrmt><OrdQty Qty="5"></OrdQty></Order></FIXML>`,
+		},
+		{
+			"trailing stop percent",
+			Order{Account: "1", Symbol: "AAPL", Side: "sell", Type: "trailing_stop", Quantity: 5, TrailPercent: 2.5},
+			head + `<Order TmInForce="0" Typ="P" Side="2" ExecInst="a" Acct="1"><Instrmt SecTyp="CS" Sym="AAPL"></Instrmt><PegInstr OfstTyp="1" PegPxTyp="1" OfstVal="2.5"></PegInstr><OrdQty Qty="5"></OrdQty></Order></FIXML>`,
+		},
+		{
+			"option sold to open",
+			Order{Account: "1", Symbol: "AAPL250117C00200000", Side: "sell_short", Type: "limit", Quantity: 1, Price: NewDecimal(3.2)},
+			head + `<Order TmInForce="0" Typ="2" Side="2" PosEfct="O" Px="3.2" Acct="1"><Instrmt CFI="OC" SecTyp="OPT" MatDt="2025-01-17T00:00:00.000-05:00" StrkPx="200" Sym="AAPL"></Instrmt><OrdQty Qty="1"></OrdQty></Order></FIXML>`,
+		},
+	} {
+		got, err := marshalFIXML(t, &tt.order)
+		if err != nil {
+			t.Errorf("%v: %v", tt.name, err)
+		} else if got != tt.want {
+			t.Errorf("%v:\ngot  %v\nwant %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOrderFIXMLErrors(t *testing.T) {
+	for name, o := range map[string]Order{
+		"no account":        {Symbol: "AAPL", Side: "buy", Type: "market", Quantity: 1},
+		"no quantity":       {Account: "1", Symbol: "AAPL", Side: "buy", Type: "market"},
+		"unknown side":      {Account: "1", Symbol: "AAPL", Side: "hold", Type: "market", Quantity: 1},
+		"limit no price":    {Account: "1", Symbol: "AAPL", Side: "buy", Type: "limit", Quantity: 1},
+		"stop no stop":      {Account: "1", Symbol: "AAPL", Side: "sell", Type: "stop", Quantity: 1, Price: DecimalFromInt(1)},
+		"gtc market":        {Account: "1", Symbol: "AAPL", Side: "buy", Type: "market", Quantity: 1, TimeInForce: "gtc"},
+		"moc limit":         {Account: "1", Symbol: "AAPL", Side: "buy", Type: "limit", Quantity: 1, Price: DecimalFromInt(1), TimeInForce: "moc"},
+		"trail both":        {Account: "1", Symbol: "AAPL", Side: "sell", Type: "trailing_stop", Quantity: 1, TrailAmount: DecimalFromInt(1), TrailPercent: 1},
+		"trail on a limit":  {Account: "1", Symbol: "AAPL", Side: "sell", Type: "limit", Quantity: 1, Price: DecimalFromInt(1), TrailPercent: 1},
+		"trail of 100%":     {Account: "1", Symbol: "AAPL", Side: "sell", Type: "trailing_stop", Quantity: 1, TrailPercent: 100},
+		"moc option market": {Account: "1", Symbol: "AAPL250117C00200000", Side: "buy", Type: "market", Quantity: 1, TimeInForce: "moc"},
+	} {
+		if _, err := marshalFIXML(t, &o); err == nil {
+			t.Errorf("%v: built an invalid order", name)
+		}
+	}
+}
+
+func TestOrderGroupFIXML(t *testing.T) {
+	entry := Order{Account: "1", Symbol: "AAPL", Side: "buy", Type: "limit", Quantity: 10, Price: DecimalFromInt(100)}
+	stop := Order{Account: "1", Symbol: "AAPL", Side: "sell", Type: "stop", Quantity: 10, StopPrice: DecimalFromInt(95)}
+	target := Order{Account: "1", Symbol: "AAPL", Side: "sell", Type: "limit", Quantity: 10, Price: DecimalFromInt(110)}
+
+	got, err := marshalFIXML(t, &OrderGroup{Kind: OTOCO, Orders: []Order{entry, stop, target}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<FIXML xmlns="http://www.fixprotocol.org/FIXML-5-0-SP2"><NewOrdList ListID="" BidTyp="3" TotNoOrdrs="3" ContingencyType="101">` +
+		`<Ord OrdID="" ListSeqNo="1" TmInForce="0" Typ="2" Side="1" Px="100" Acct="1"><Instrmt SecTyp="CS" Sym="AAPL"></Instrmt><OrdQty Qty="10"></OrdQty></Ord>` +
+		`<Ord OrdID="" ListSeqNo="2" TmInForce="0" Typ="3" Side="2" StopPx="95" Acct="1"><Instrmt SecTyp="CS" Sym="AAPL"></Instrmt><OrdQty Qty="10"></OrdQty></Ord>` +
+		`<Ord OrdID="" ListSeqNo="3" TmInForce="0" Typ="2" Side="2" Px="110" Acct="1"><Instrmt SecTyp="CS" Sym="AAPL"></Instrmt><OrdQty Qty="10"></OrdQty></Ord>` +
+		`</NewOrdList></FIXML>`
+	if got != want {
+		t.Errorf("\ngot  %v\nwant %v", got, want)
+	}
+
+	if _, err := marshalFIXML(t, &OrderGroup{Kind: OCO, Orders: []Order{entry}}); err == nil {
+		t.Error("built an OCO group of one order")
+	}
+	other := stop
+	other.Account = "2"
+	if _, err := marshalFIXML(t, &OrderGroup{Kind: OTO, Orders: []Order{entry, other}}); err == nil {
+		t.Error("built a group across accounts")
+	}
+}
+
+func TestMultiLegOrderFIXML(t *testing.T) {
+	// A vertical call spread for a net debit of 1.25
+	m := MultiLegOrder{
+		Account:  "1",
+		Type:     "limit",
+		Quantity: 2,
+		Price:    NewDecimal(1.25),
+		Legs: []Leg{
+			{Symbol: "AAPL250117C00200000", Side: "buy"},
+			{Symbol: "AAPL250117C00210000", Side: "sell_short"},
+		},
+	}
+	got, err := marshalFIXML(t, &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<FIXML xmlns="http://www.fixprotocol.org/FIXML-5-0-SP2"><NewOrdMleg TmInForce="0" Px="1.25" OrdTyp="2" Acct="1">` +
+		`<Ord OrdQty="2" PosEfct="O"><Leg Side="1" Strk="200" Mat="2025-01-17T00:00:00.000-05:00" MMY="202501" SecTyp="OPT" CFI="OC" Sym="AAPL"></Leg></Ord>` +
+		`<Ord OrdQty="2" PosEfct="O"><Leg Side="2" Strk="210" Mat="2025-01-17T00:00:00.000-05:00" MMY="202501" SecTyp="OPT" CFI="OC" Sym="AAPL"></Leg></Ord>` +
+		`</NewOrdMleg></FIXML>`
+	if got != want {
+		t.Errorf("\ngot  %v\nwant %v", got, want)
+	}
+
+	m.Legs = m.Legs[:1]
+	if _, err := marshalFIXML(t, &m); err == nil {
+		t.Error("built a multi-leg order of one leg")
+	}
+}
@@ -0,0 +1,104 @@
+package allyapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// OptionQuoteFields are the quote fields used by NewOptionQuote
+var OptionQuoteFields = []string{
+	"symbol", "last", "bid", "ask", "vl", "openinterest", "days_to_expiration",
+	"delta", "gamma", "theta", "vega", "rho", "imp_volatility",
+}
+
+// Greeks are an option's sensitivities and implied volatility.
+// ImpliedVolatility is a fraction, e.g. 0.25 for 25%.
+type Greeks struct {
+	Delta             float64 `json:"delta"`
+	Gamma             float64 `json:"gamma"`
+	Theta             float64 `json:"theta"`
+	Vega              float64 `json:"vega"`
+	Rho               float64 `json:"rho"`
+	ImpliedVolatility float64 `json:"iv"`
+}
+
+// OptionQuote is a quote for an option contract
+type OptionQuote struct {
+	OptionSymbol
+	Symbol           string  `json:"symbol"`
+	Last             Decimal `json:"last"`
+	Bid              Decimal `json:"bid"`
+	Ask              Decimal `json:"ask"`
+	Volume           int64   `json:"volume"`
+	OpenInterest     int64   `json:"open_interest"`
+	DaysToExpiration int     `json:"days_to_expiration"`
+	Greeks           `json:"greeks"`
+}
+
+// NewOptionQuote reads an option quote from the fields of a quote returned by
+// GetQuotes. Missing or "na" fields are zero.
+func NewOptionQuote(quote map[string]string) (*OptionQuote, error) {
+	opt, err := ParseOptionSymbol(quote["symbol"])
+	if err != nil {
+		return nil, err
+	}
+
+	num := func(field string) float64 {
+		v := strings.TrimSuffix(quote[field], "%")
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	}
+	price := func(field string) Decimal {
+		d, _ := ParseDecimal(quote[field])
+		return d
+	}
+	return &OptionQuote{
+		OptionSymbol:     opt,
+		Symbol:           strings.ToUpper(quote["symbol"]),
+		Last:             price("last"),
+		Bid:              price("bid"),
+		Ask:              price("ask"),
+		Volume:           int64(num("vl")),
+		OpenInterest:     int64(num("openinterest")),
+		DaysToExpiration: int(num("days_to_expiration")),
+		Greeks: Greeks{
+			Delta:             num("delta"),
+			Gamma:             num("gamma"),
+			Theta:             num("theta"),
+			Vega:              num("vega"),
+			Rho:               num("rho"),
+			ImpliedVolatility: num("imp_volatility"),
+		},
+	}, nil
+}
+
+// OptionQuotes returns quotes, including greeks, for OCC option symbols
+func (ac *Client) OptionQuotes(symbols []string) ([]OptionQuote, error) {
+	for _, s := range symbols {
+		if !IsOptionSymbol(s) {
+			return nil, fmt.Errorf("not an option symbol: %q", s)
+		}
+	}
+
+	resp, err := ac.GetQuotes(symbols, OptionQuoteFields)
+	if err != nil {
+		return nil, err
+	}
+	return optionQuotes(resp)
+}
+
+func optionQuotes(resp *APIResponse) ([]OptionQuote, error) {
+	if resp.Response.Quotes == nil {
+		return nil, nil
+	}
+	quotes := make([]OptionQuote, 0, len(resp.Response.Quotes.Quote))
+	for _, q := range resp.Response.Quotes.Quote {
+		oq, err := NewOptionQuote(q)
+		if err != nil {
+			return nil, err
+		}
+		quotes = append(quotes, *oq)
+	}
+	return quotes, nil
+}
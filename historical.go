@@ -0,0 +1,196 @@
+package allyapi
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Bar is the open, high, low, and close prices and volume of a symbol over
+// an interval starting at Time
+type Bar struct {
+	Time   time.Time `json:"date" xml:"date"`
+	Open   Decimal   `json:"open" xml:"open"`
+	High   Decimal   `json:"high" xml:"high"`
+	Low    Decimal   `json:"low" xml:"low"`
+	Close  Decimal   `json:"close" xml:"close"`
+	Volume int64     `json:"volume" xml:"volume"`
+}
+
+// UnmarshalJSON accepts prices and volume as either numbers or strings, and
+// dates with or without a time
+func (b *Bar) UnmarshalJSON(data []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	return b.set(raw)
+}
+
+// UnmarshalXML accepts dates with or without a time
+func (b *Bar) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var raw struct {
+		Date   string `xml:"date"`
+		Open   string `xml:"open"`
+		High   string `xml:"high"`
+		Low    string `xml:"low"`
+		Close  string `xml:"close"`
+		Volume string `xml:"volume"`
+	}
+	if err := d.DecodeElement(&raw, &start); err != nil {
+		return err
+	}
+	return b.set(map[string]interface{}{
+		"date":   raw.Date,
+		"open":   raw.Open,
+		"high":   raw.High,
+		"low":    raw.Low,
+		"close":  raw.Close,
+		"volume": raw.Volume,
+	})
+}
+
+func (b *Bar) set(raw map[string]interface{}) error {
+	date := fmt.Sprint(raw["date"])
+	t, err := parseMarketTime(date)
+	if err != nil {
+		return fmt.Errorf("invalid bar date %q", date)
+	}
+	b.Time = t
+
+	for _, f := range []struct {
+		name string
+		v    *Decimal
+	}{{"open", &b.Open}, {"high", &b.High}, {"low", &b.Low}, {"close", &b.Close}} {
+		price, err := parseNumber(raw[f.name])
+		if err == nil {
+			*f.v, err = ParseDecimal(price)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid bar %v: %v", f.name, err)
+		}
+	}
+	volume, err := parseNumber(raw["volume"])
+	if err == nil && volume != "" {
+		var v float64
+		v, err = strconv.ParseFloat(volume, 64)
+		b.Volume = int64(v)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid bar volume: %v", err)
+	}
+	return nil
+}
+
+// Get the text of a JSON number that may be quoted; missing values are empty
+func parseNumber(v interface{}) (string, error) {
+	switch n := v.(type) {
+	case nil:
+		return "", nil
+	case float64:
+		return strconv.FormatFloat(n, 'f', -1, 64), nil
+	case string:
+		return n, nil
+	default:
+		return "", fmt.Errorf("unexpected value %v", v)
+	}
+}
+
+// Bars holds one or more bars
+type Bars []Bar
+
+// UnmarshalJSON accepts either an array of bars or a single bar
+func (bs *Bars) UnmarshalJSON(data []byte) error {
+	return unmarshalArray(data, (*[]Bar)(bs))
+}
+
+// HistoricalQuotes returns a symbol's bars from from to to, inclusive.
+// interval is one of "daily", "weekly", or "monthly"; an empty interval means
+// "daily". Zero times leave the range open at that end.
+func (ac *Client) HistoricalQuotes(symbol, interval string, from, to time.Time) (Bars, error) {
+	if interval == "" {
+		interval = "daily"
+	}
+	query := url.Values{"symbols": {symbol}, "interval": {interval}}
+	if !from.IsZero() {
+		query.Set("startdate", from.In(MarketTime).Format("2006-01-02"))
+	}
+	if !to.IsZero() {
+		query.Set("enddate", to.In(MarketTime).Format("2006-01-02"))
+	}
+
+	resp, err := ac.get(ac.endpoint("/market/historical/search") + "?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	if resp.Response.Timeseries == nil {
+		return nil, nil
+	}
+	return resp.Response.Timeseries.Series.Data, nil
+}
+
+// TimeSales returns a symbol's intraday bars from from to to, inclusive, from
+// its time and sales. interval is one of "1min", "5min", or "tick" for a bar
+// of each trade; an empty interval means "1min". A zero from means today.
+func (ac *Client) TimeSales(symbol, interval string, from, to time.Time) (Bars, error) {
+	if interval == "" {
+		interval = "1min"
+	}
+	if from.IsZero() {
+		from = time.Now()
+	}
+	query := url.Values{
+		"symbols":   {symbol},
+		"interval":  {interval},
+		"startdate": {from.In(MarketTime).Format("2006-01-02")},
+	}
+	if !to.IsZero() {
+		query.Set("enddate", to.In(MarketTime).Format("2006-01-02"))
+	}
+
+	resp, err := ac.get(ac.endpoint("/market/timesales") + "?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	if resp.Response.Quotes == nil {
+		return nil, nil
+	}
+	bars := make(Bars, 0, len(resp.Response.Quotes.Quote))
+	for _, q := range resp.Response.Quotes.Quote {
+		b, err := timeSalesBar(q)
+		if err != nil {
+			return nil, err
+		}
+		bars = append(bars, b)
+	}
+	return bars, nil
+}
+
+// Bar of a time and sales quote, whose volume is the bar's in incr_vl. Ticks
+// have only a last price, which is all of the bar's prices.
+func timeSalesBar(q map[string]string) (Bar, error) {
+	raw := map[string]interface{}{
+		"date":   q["datetime"],
+		"open":   q["opn"],
+		"high":   q["hi"],
+		"low":    q["lo"],
+		"close":  q["last"],
+		"volume": q["incr_vl"],
+	}
+	if q["datetime"] == "" {
+		raw["date"] = q["date"]
+	}
+	for _, f := range []string{"open", "high", "low"} {
+		if raw[f] == "" {
+			raw[f] = q["last"]
+		}
+	}
+	if q["incr_vl"] == "" {
+		raw["volume"] = q["vl"]
+	}
+	var b Bar
+	return b, b.set(raw)
+}
@@ -0,0 +1,183 @@
+package allyapi
+
+import (
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Transaction is an entry in an account's history
+type Transaction struct {
+	Activity    string             `json:",omitempty" xml:"activity,omitempty"`
+	Amount      Decimal            `json:",omitempty" xml:"amount,omitempty"`
+	Date        string             `json:",omitempty" xml:"date,omitempty"`
+	Desc        string             `json:",omitempty" xml:"desc,omitempty"`
+	Symbol      string             `json:",omitempty" xml:"symbol,omitempty"`
+	Transaction TransactionDetails `json:",omitempty" xml:"transaction,omitempty"`
+}
+
+// TransactionDetails holds the trade details of a transaction
+type TransactionDetails struct {
+	AccountType string  `json:",omitempty" xml:"accounttype,omitempty"`
+	Commission  Decimal `json:",omitempty" xml:"commission,omitempty"`
+	Description string  `json:",omitempty" xml:"description,omitempty"`
+	Fee         Decimal `json:",omitempty" xml:"fee,omitempty"`
+	Price       Decimal `json:",omitempty" xml:"price,omitempty"`
+	Quantity    string  `json:",omitempty" xml:"quantity,omitempty"`
+	SecFee      Decimal `json:",omitempty" xml:"secfee,omitempty"`
+	Security    struct {
+		Cusip  string `json:",omitempty" xml:"cusip,omitempty"`
+		ID     string `json:",omitempty" xml:"id,omitempty"`
+		SecTyp string `json:",omitempty" xml:"sectyp,omitempty"`
+		Sym    string `json:",omitempty" xml:"sym,omitempty"`
+	} `json:",omitempty" xml:"security,omitempty"`
+	SettlementDate  string `json:",omitempty" xml:"settlementdate,omitempty"`
+	Side            string `json:",omitempty" xml:"side,omitempty"`
+	Source          string `json:",omitempty" xml:"source,omitempty"`
+	TradeDate       string `json:",omitempty" xml:"tradedate,omitempty"`
+	TransactionDate string `json:",omitempty" xml:"transactiondate,omitempty"`
+}
+
+// Time parses the transaction's date in market time
+func (t *Transaction) Time() (time.Time, error) {
+	return parseMarketTime(t.Date)
+}
+
+// Transactions holds one or more transactions
+type Transactions []Transaction
+
+// UnmarshalJSON accepts either an array of transactions or a single
+// transaction
+func (ts *Transactions) UnmarshalJSON(data []byte) error {
+	return unmarshalArray(data, (*[]Transaction)(ts))
+}
+
+// History returns an account's transactions. dateRange is one of "all",
+// "today", "current_week", "current_month", or "last_month", and txType is one
+// of "all", "bookkeeping", or "trade"; empty values mean "all".
+func (ac *Client) History(account, dateRange, txType string) (Transactions, error) {
+	return ac.HistoryPages(account, dateRange, txType, 0).All()
+}
+
+// HistoryRange returns the narrowest of History's date ranges that covers
+// from to to, inclusive, as of now; zero times are unbounded. Weeks are taken
+// to start on Monday.
+func HistoryRange(from, to, now time.Time) string {
+	now = now.In(MarketTime)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, MarketTime)
+	week := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	month := today.AddDate(0, 0, 1-today.Day())
+
+	switch {
+	case from.IsZero():
+		return "all"
+	case !from.Before(today):
+		return "today"
+	case !from.Before(week):
+		return "current_week"
+	case !from.Before(month):
+		return "current_month"
+	case !from.Before(month.AddDate(0, -1, 0)) && !to.IsZero() && to.Before(month):
+		return "last_month"
+	}
+	return "all"
+}
+
+// HistoryIterator steps through an account's transactions, requesting pages
+// of them as needed
+type HistoryIterator struct {
+	client   *Client
+	endpoint string
+	query    url.Values
+	pageSize int
+
+	page int
+	last Transactions
+	txs  Transactions
+	tx   Transaction
+	done bool
+	err  error
+}
+
+// HistoryPages returns an iterator over an account's transactions, with
+// dateRange and txType as for History. With pageSize above zero, each request
+// asks for at most that many transactions, and requests follow until a page
+// comes back short.
+func (ac *Client) HistoryPages(account, dateRange, txType string, pageSize int) *HistoryIterator {
+	if dateRange == "" {
+		dateRange = "all"
+	}
+	if txType == "" {
+		txType = "all"
+	}
+	return &HistoryIterator{
+		client:   ac,
+		endpoint: ac.endpoint("/accounts/" + url.PathEscape(account) + "/history"),
+		query:    url.Values{"range": {dateRange}, "transactions": {txType}},
+		pageSize: pageSize,
+	}
+}
+
+// Next advances to the next transaction, returning false when there are no
+// more or a request fails
+func (it *HistoryIterator) Next() bool {
+	for len(it.txs) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		it.fetch()
+	}
+	it.tx, it.txs = it.txs[0], it.txs[1:]
+	return true
+}
+
+// Transaction returns the current transaction
+func (it *HistoryIterator) Transaction() Transaction {
+	return it.tx
+}
+
+// Err returns the error, if any, that ended the iteration
+func (it *HistoryIterator) Err() error {
+	return it.err
+}
+
+// All collects the remaining transactions
+func (it *HistoryIterator) All() (Transactions, error) {
+	var txs Transactions
+	for it.Next() {
+		txs = append(txs, it.Transaction())
+	}
+	return txs, it.Err()
+}
+
+func (it *HistoryIterator) fetch() {
+	it.page++
+	query := url.Values{}
+	for k, v := range it.query {
+		query[k] = v
+	}
+	if it.pageSize > 0 {
+		query.Set("page", strconv.Itoa(it.page))
+		query.Set("count", strconv.Itoa(it.pageSize))
+	}
+
+	resp, err := it.client.get(it.endpoint + "?" + query.Encode())
+	if err != nil {
+		it.err = err
+		return
+	}
+	var txs Transactions
+	if resp.Response.Transactions != nil {
+		txs = resp.Response.Transactions.Transaction
+	}
+
+	// Stop on a short page, or a repeated one from a server that ignores
+	// the page
+	it.done = it.pageSize <= 0 || len(txs) < it.pageSize
+	if it.page > 1 && slices.Equal(txs, it.last) {
+		it.done = true
+		return
+	}
+	it.last, it.txs = txs, txs
+}
@@ -0,0 +1,64 @@
+package allyapi
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Holding is a position in an account
+type Holding struct {
+	AccountType string  `json:",omitempty" xml:"accounttype,omitempty"`
+	CostBasis   Decimal `json:",omitempty" xml:"costbasis,omitempty"`
+	GainLoss    Decimal `json:",omitempty" xml:"gainloss,omitempty"`
+	Instrument  struct {
+		Cusip  string `json:",omitempty" xml:"cusip,omitempty"`
+		Desc   string `json:",omitempty" xml:"desc,omitempty"`
+		SecTyp string `json:",omitempty" xml:"sectyp,omitempty"`
+		Sym    string `json:",omitempty" xml:"sym,omitempty"`
+	} `json:",omitempty" xml:"instrument,omitempty"`
+	MarketValue   Decimal `json:",omitempty" xml:"marketvalue,omitempty"`
+	Price         Decimal `json:",omitempty" xml:"price,omitempty"`
+	PurchasePrice Decimal `json:",omitempty" xml:"purchaseprice,omitempty"`
+	Qty           string  `json:",omitempty" xml:"qty,omitempty"`
+}
+
+// Multiplier returns what the holding's quantity is multiplied by to get a
+// number of shares; see Multiplier
+func (h *Holding) Multiplier() int64 {
+	return Multiplier(h.Instrument.Sym)
+}
+
+// Holdings holds one or more holdings
+type Holdings []Holding
+
+// UnmarshalJSON accepts either an array of holdings or a single holding
+func (hs *Holdings) UnmarshalJSON(data []byte) error {
+	return unmarshalArray(data, (*[]Holding)(hs))
+}
+
+// Holdings returns the positions in an account
+func (ac *Client) Holdings(account string) (Holdings, error) {
+	resp, err := ac.get(ac.endpoint("/accounts/" + url.PathEscape(account) + "/holdings"))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Response.AccountHoldings == nil {
+		return nil, nil
+	}
+	return resp.Response.AccountHoldings.Holding, nil
+}
+
+// Positions returns the quantity of each symbol held, negative if short
+func (hs Holdings) Positions() (map[string]int, error) {
+	positions := make(map[string]int)
+	for _, h := range hs {
+		qty, err := strconv.ParseFloat(h.Qty, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity of %v: %q", h.Instrument.Sym, h.Qty)
+		}
+		positions[strings.ToUpper(h.Instrument.Sym)] += int(qty)
+	}
+	return positions, nil
+}
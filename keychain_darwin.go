@@ -0,0 +1,52 @@
+//go:build darwin
+// +build darwin
+
+package allyapi
+
+import (
+	"fmt"
+
+	"github.com/keybase/go-keychain"
+)
+
+// Try to get credentials from keychain
+func getCredsFromKeychain(service, account string) (string, error) {
+	query := keychain.NewItem()
+	query.SetSecClass(keychain.SecClassGenericPassword)
+	query.SetService(service)
+	query.SetAccount(account)
+	query.SetMatchLimit(keychain.MatchLimitOne)
+	query.SetReturnData(true)
+	results, err := keychain.QueryItem(query)
+	if err != nil {
+		return "", err
+	} else if len(results) != 1 {
+		return "", fmt.Errorf("got %v results", len(results))
+	}
+	password := string(results[0].Data)
+	return password, nil
+}
+
+// Add or update a credential in the keychain
+func setCredsInKeychain(service, account, secret string) error {
+	item := keychain.NewItem()
+	item.SetSecClass(keychain.SecClassGenericPassword)
+	item.SetService(service)
+	item.SetAccount(account)
+	item.SetData([]byte(secret))
+	item.SetSynchronizable(keychain.SynchronizableNo)
+	item.SetAccessible(keychain.AccessibleWhenUnlocked)
+
+	err := keychain.AddItem(item)
+	if err != keychain.ErrorDuplicateItem {
+		return err
+	}
+
+	query := keychain.NewItem()
+	query.SetSecClass(keychain.SecClassGenericPassword)
+	query.SetService(service)
+	query.SetAccount(account)
+	update := keychain.NewItem()
+	update.SetData([]byte(secret))
+	return keychain.UpdateItem(query, update)
+}
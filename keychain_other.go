@@ -0,0 +1,18 @@
+//go:build !darwin
+// +build !darwin
+
+package allyapi
+
+import (
+	"github.com/zalando/go-keyring"
+)
+
+// Try to get credentials from the Secret Service (Linux and BSD) or the
+// Windows Credential Manager
+func getCredsFromKeychain(service, account string) (string, error) {
+	return keyring.Get(service, account)
+}
+
+func setCredsInKeychain(service, account, secret string) error {
+	return keyring.Set(service, account, secret)
+}
@@ -0,0 +1,30 @@
+package allyapi
+
+import "errors"
+
+// Ladder returns count limit orders like o, priced evenly from from to to
+// inclusive and rounded to the cent, e.g. buys at 95, 93.75, 92.50, 91.25,
+// and 90 for five from 95 to 90. Each is for o's quantity.
+func Ladder(o *Order, from, to Decimal, count int) ([]Order, error) {
+	switch {
+	case count < 1:
+		return nil, errors.New("a ladder needs at least one order")
+	case from <= 0 || to <= 0:
+		return nil, errors.New("ladder prices must be positive")
+	case count > 1 && from == to:
+		return nil, errors.New("a ladder of more than one order needs a price range")
+	}
+
+	orders := make([]Order, count)
+	for i := range orders {
+		price := from
+		if count > 1 {
+			price = from + (to-from)*Decimal(i)/Decimal(count-1)
+		}
+		orders[i] = *o
+		orders[i].Type = "limit"
+		orders[i].Price = price.Round(2)
+		orders[i].StopPrice = 0
+	}
+	return orders, nil
+}
@@ -0,0 +1,45 @@
+package allyapi
+
+import "testing"
+
+func TestLadder(t *testing.T) {
+	o := &Order{Account: "1", Symbol: "AAPL", Side: "buy", Type: "stop", Quantity: 10, StopPrice: DecimalFromInt(99)}
+	orders, err := Ladder(o, DecimalFromInt(95), DecimalFromInt(90), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"95", "93.75", "92.5", "91.25", "90"}
+	if len(orders) != len(want) {
+		t.Fatalf("got %d orders, want %d", len(orders), len(want))
+	}
+	for i, o := range orders {
+		if o.Price.String() != want[i] || o.Type != "limit" || o.StopPrice != 0 || o.Quantity != 10 || o.Symbol != "AAPL" {
+			t.Errorf("order %d = %+v, want a limit of 10 at %v", i, o, want[i])
+		}
+	}
+
+	// Prices are rounded to the cent
+	orders, err = Ladder(o, DecimalFromInt(10), DecimalFromInt(11), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := orders[1].Price.String(); got != "10.33" {
+		t.Errorf("second price = %v, want 10.33", got)
+	}
+
+	if orders, err := Ladder(o, DecimalFromInt(10), DecimalFromInt(10), 1); err != nil || len(orders) != 1 || orders[0].Price != DecimalFromInt(10) {
+		t.Errorf("ladder of one = %+v, %v", orders, err)
+	}
+	for _, tt := range []struct {
+		from, to int64
+		count    int
+	}{
+		{10, 9, 0},
+		{0, 9, 2},
+		{10, 10, 2},
+	} {
+		if _, err := Ladder(o, DecimalFromInt(tt.from), DecimalFromInt(tt.to), tt.count); err == nil {
+			t.Errorf("built a ladder of %v from %v to %v", tt.count, tt.from, tt.to)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package allyapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// Market time shouldn't depend on the system having a time zone database
+	_ "time/tzdata"
+)
+
+// MarketTime is the time zone of the US markets, in which the API's dates
+// are given and times are returned
+var MarketTime = mustLoadLocation("America/New_York")
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// Parse a date or time from the API. Those without a UTC offset are in
+// market time.
+func parseMarketTime(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, MarketTime); err == nil {
+			return t.In(MarketTime), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+// Parse a Unix timestamp in seconds, which may have a fractional part
+func timestampToDate(str string) (time.Time, error) {
+	secs, frac, _ := strings.Cut(str, ".")
+	sec, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", str)
+	}
+
+	// The fraction is of a second, so pad or truncate it to nanoseconds
+	var nsec int64
+	if frac != "" {
+		if len(frac) > 9 {
+			frac = frac[:9]
+		}
+		frac += strings.Repeat("0", 9-len(frac))
+		if nsec, err = strconv.ParseInt(frac, 10, 64); err != nil || nsec < 0 {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q", str)
+		}
+	}
+	return time.Unix(sec, nsec).In(MarketTime), nil
+}
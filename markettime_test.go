@@ -0,0 +1,30 @@
+package allyapi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimestampToDate(t *testing.T) {
+	for in, want := range map[string]time.Time{
+		"1700000000":            time.Unix(1700000000, 0),
+		"1700000000.5":          time.Unix(1700000000, 500000000),
+		"1700000000.123456789":  time.Unix(1700000000, 123456789),
+		"1700000000.1234567891": time.Unix(1700000000, 123456789),
+	} {
+		got, err := timestampToDate(in)
+		if err != nil {
+			t.Errorf("%q: %v", in, err)
+			continue
+		}
+		if !got.Equal(want) || got.Location() != MarketTime {
+			t.Errorf("%q = %v, want %v", in, got, want.In(MarketTime))
+		}
+	}
+
+	for _, in := range []string{"", ".5", "soon", "1700000000.x", "1700000000.-5"} {
+		if got, err := timestampToDate(in); err == nil {
+			t.Errorf("%q = %v, want an error", in, got)
+		}
+	}
+}
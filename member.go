@@ -0,0 +1,42 @@
+package allyapi
+
+// Member is the profile of the user the credentials belong to
+type Member struct {
+	Account       MemberAccounts `json:",omitempty" xml:"account,omitempty"`
+	Disabled      string         `json:",omitempty" xml:"disabled,omitempty"`
+	ResetPassword string         `json:",omitempty" xml:"resetpassword,omitempty"`
+	ResetPin      string         `json:",omitempty" xml:"resetpin,omitempty"`
+}
+
+// MemberAccount is an account the member can access and what it may trade
+type MemberAccount struct {
+	Account       string `json:",omitempty" xml:"account,omitempty"`
+	Nickname      string `json:",omitempty" xml:"nickname,omitempty"`
+	FundTrading   string `json:",omitempty" xml:"fundtrading,omitempty"`
+	IRA           string `json:",omitempty" xml:"ira,omitempty"`
+	MarginTrading string `json:",omitempty" xml:"margintrading,omitempty"`
+	OptionLevel   string `json:",omitempty" xml:"optionlevel,omitempty"`
+	Shared        string `json:",omitempty" xml:"shared,omitempty"`
+	StockTrading  string `json:",omitempty" xml:"stocktrading,omitempty"`
+}
+
+// MemberAccounts holds one or more member accounts
+type MemberAccounts []MemberAccount
+
+// UnmarshalJSON accepts either an array of accounts or a single account
+func (ma *MemberAccounts) UnmarshalJSON(data []byte) error {
+	return unmarshalArray(data, (*[]MemberAccount)(ma))
+}
+
+// Member returns the profile of the user the credentials belong to, which
+// makes it a cheap way to check them
+func (ac *Client) Member() (*Member, error) {
+	resp, err := ac.get(ac.endpoint("/member/profile"))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Response.UserData == nil {
+		return &Member{}, nil
+	}
+	return resp.Response.UserData, nil
+}
@@ -0,0 +1,78 @@
+package allyapi
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// Metrics counts client activity for monitoring long-running processes. A nil
+// *Metrics counts nothing.
+type Metrics struct {
+	apiCalls   int64
+	quotes     int64
+	reconnects int64
+	errors     int64
+}
+
+func (m *Metrics) addAPICall() {
+	if m != nil {
+		atomic.AddInt64(&m.apiCalls, 1)
+	}
+}
+
+func (m *Metrics) addError() {
+	if m != nil {
+		atomic.AddInt64(&m.errors, 1)
+	}
+}
+
+// AddReconnect counts a reconnection of a stream
+func (m *Metrics) AddReconnect() {
+	if m != nil {
+		atomic.AddInt64(&m.reconnects, 1)
+	}
+}
+
+// Count streamed quotes and trades before passing them on
+func (m *Metrics) countQuotes(handle func(*APIResponse) error) func(*APIResponse) error {
+	if m == nil {
+		return handle
+	}
+	return func(r *APIResponse) error {
+		if r.Quote != nil || r.Trade != nil {
+			atomic.AddInt64(&m.quotes, 1)
+		}
+		return handle(r)
+	}
+}
+
+// MetricsHandler serves the client's metrics and rate limit in the
+// Prometheus text format
+func (ac *Client) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m := ac.Metrics
+		if m == nil {
+			m = &Metrics{}
+		}
+		used, remaining, limit, expires := ac.RateLimit()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		metric := func(name, typ, help string, value interface{}) {
+			fmt.Fprintf(w, "# HELP %v %v\n# TYPE %v %v\n%v %v\n", name, help, name, typ, name, value)
+		}
+		metric("allyapi_api_calls_total", "counter", "API requests made.", atomic.LoadInt64(&m.apiCalls))
+		metric("allyapi_quotes_received_total", "counter", "Streamed quotes and trades received.", atomic.LoadInt64(&m.quotes))
+		metric("allyapi_stream_reconnects_total", "counter", "Stream reconnections.", atomic.LoadInt64(&m.reconnects))
+		metric("allyapi_errors_total", "counter", "Failed API requests.", atomic.LoadInt64(&m.errors))
+		metric("allyapi_rate_limit_used", "gauge", "API calls used in the current rate limit period.", used)
+		metric("allyapi_rate_limit_remaining", "gauge", "API calls remaining in the current rate limit period.", remaining)
+		metric("allyapi_rate_limit_limit", "gauge", "API calls allowed per rate limit period.", limit)
+
+		var reset int64
+		if !expires.IsZero() {
+			reset = expires.Unix()
+		}
+		metric("allyapi_rate_limit_reset_timestamp_seconds", "gauge", "When the current rate limit period ends.", reset)
+	})
+}
@@ -0,0 +1,154 @@
+package allyapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+)
+
+// Option configures a Client
+type Option func(*Client)
+
+// Environment holds the base URLs of the REST and streaming APIs
+type Environment struct {
+	BaseURL   string
+	StreamURL string
+}
+
+var (
+	// Live is the production API
+	Live = Environment{
+		BaseURL:   "https://api.invest.ally.com/v1",
+		StreamURL: "https://stream.invest.ally.com/v1",
+	}
+
+	// Dev is the developer API, which is the default
+	Dev = Environment{
+		BaseURL:   "https://devapi.invest.ally.com/v1",
+		StreamURL: "https://devapi-stream.invest.ally.com/v1",
+	}
+)
+
+// EnvironmentByName returns the environment called "live" or "dev"
+func EnvironmentByName(name string) (Environment, error) {
+	switch name {
+	case "live":
+		return Live, nil
+	case "dev":
+		return Dev, nil
+	}
+	return Environment{}, fmt.Errorf("unknown environment: %q", name)
+}
+
+// WithEnvironment sets the API environment; the default is Dev
+func WithEnvironment(env Environment) Option {
+	return func(ac *Client) {
+		ac.env = env
+	}
+}
+
+// WithFormat sets the format of the API's responses, "json" or "xml"; the
+// default is "json"
+func WithFormat(format string) Option {
+	return func(ac *Client) {
+		ac.format = format
+	}
+}
+
+// WithCredentials sets the client's credentials, so that NewClient doesn't
+// load them
+func WithCredentials(creds *Credentials) Option {
+	return func(ac *Client) {
+		ac.creds = creds
+	}
+}
+
+// WithCredentialSource makes NewClient load credentials from source, using
+// the config file at path; see LoadCredentials
+func WithCredentialSource(source, path string) Option {
+	return func(ac *Client) {
+		ac.credsSource = source
+		ac.credsPath = path
+	}
+}
+
+// WithProfile makes NewClient load the credentials of the named profile in
+// the config file; see ConfigFile.ForProfile
+func WithProfile(name string) Option {
+	return func(ac *Client) {
+		ac.profile = name
+	}
+}
+
+// Logger receives the client's log messages as key-value pairs; *slog.Logger
+// satisfies it
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// WithContext sets the context of the client's requests, including
+// streaming ones, so that cancelling it aborts them; the default is
+// context.Background()
+func WithContext(ctx context.Context) Option {
+	return func(ac *Client) {
+		ac.ctx = ctx
+	}
+}
+
+// WithLogger sets the client's logger; the default is slog.Default()
+func WithLogger(l Logger) Option {
+	return func(ac *Client) {
+		ac.logger = l
+	}
+}
+
+// WithTransport sets the transport that sends requests once they have been
+// signed; the default is http.DefaultTransport
+func WithTransport(rt http.RoundTripper) Option {
+	return func(ac *Client) {
+		ac.transport = rt
+	}
+}
+
+// WithProxy sends requests, including streaming ones, through the proxy at
+// proxyURL instead of the one given by $HTTP_PROXY and $HTTPS_PROXY. With
+// WithTransport, in either order, the transport must be an *http.Transport,
+// a copy of which is given the proxy.
+func WithProxy(proxyURL *url.URL) Option {
+	return func(ac *Client) {
+		ac.proxy = proxyURL
+	}
+}
+
+// Version of this module in the running binary, or "devel" if unknown
+func moduleVersion() string {
+	const path = "github.com/n8henrie/allyapi"
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "devel"
+	}
+	mod := &info.Main
+	for _, dep := range info.Deps {
+		if dep.Path == path {
+			mod = dep
+		}
+	}
+	if mod.Path != path || mod.Version == "" || mod.Version == "(devel)" {
+		return "devel"
+	}
+	return mod.Version
+}
+
+// WithUserAgent appends an identifier for the calling application, e.g.
+// "myapp/1.0", to the User-Agent header, which defaults to allyapi/VERSION
+func WithUserAgent(app string) Option {
+	return func(ac *Client) {
+		ac.userAgent += " " + app
+	}
+}
@@ -0,0 +1,123 @@
+package allyapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OptionType is whether an option is a call or a put
+type OptionType byte
+
+const (
+	Call OptionType = 'C'
+	Put  OptionType = 'P'
+)
+
+func (t OptionType) String() string {
+	switch t {
+	case Call:
+		return "call"
+	case Put:
+		return "put"
+	default:
+		return fmt.Sprintf("OptionType(%q)", byte(t))
+	}
+}
+
+// MarshalText encodes the type as "call" or "put"
+func (t OptionType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText accepts the same values as ParseOptionType
+func (t *OptionType) UnmarshalText(text []byte) error {
+	v, err := ParseOptionType(string(text))
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
+
+// ParseOptionType parses "call", "put", "c", or "p", in any case
+func ParseOptionType(s string) (OptionType, error) {
+	switch strings.ToLower(s) {
+	case "c", "call":
+		return Call, nil
+	case "p", "put":
+		return Put, nil
+	default:
+		return 0, fmt.Errorf("invalid option type %q", s)
+	}
+}
+
+// OptionSymbol identifies an option contract
+type OptionSymbol struct {
+	Underlying string     `json:"underlying"`
+	Expiration time.Time  `json:"expiration"`
+	Type       OptionType `json:"type"`
+	Strike     Decimal    `json:"strike"`
+}
+
+// String formats the option as an OCC symbol, e.g. AAPL250117C00200000 for
+// the AAPL January 17, 2025 $200 call
+func (o OptionSymbol) String() string {
+	return fmt.Sprintf("%v%v%c%08d",
+		strings.ToUpper(o.Underlying),
+		o.Expiration.Format("060102"),
+		o.Type,
+		int64(o.Strike.Round(3))/1000,
+	)
+}
+
+// ParseOptionSymbol parses an OCC option symbol. The underlying may be
+// padded with spaces to six characters, as in the OCC's own format.
+func ParseOptionSymbol(s string) (OptionSymbol, error) {
+	// Underlying, then YYMMDD, C or P, and the strike in thousandths
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if len(s) < 16 {
+		return OptionSymbol{}, fmt.Errorf("invalid option symbol %q", s)
+	}
+	root := strings.TrimSpace(s[:len(s)-15])
+	tail := s[len(s)-15:]
+
+	exp, err := time.ParseInLocation("060102", tail[:6], MarketTime)
+	if err != nil {
+		return OptionSymbol{}, fmt.Errorf("invalid option symbol %q: bad expiration", s)
+	}
+	typ, err := ParseOptionType(tail[6:7])
+	if err != nil {
+		return OptionSymbol{}, fmt.Errorf("invalid option symbol %q: bad type", s)
+	}
+	strike, err := strconv.ParseUint(tail[7:], 10, 64)
+	if err != nil || root == "" {
+		return OptionSymbol{}, fmt.Errorf("invalid option symbol %q", s)
+	}
+	return OptionSymbol{
+		Underlying: root,
+		Expiration: exp,
+		Type:       typ,
+		Strike:     Decimal(strike * 1000),
+	}, nil
+}
+
+// ContractSize is the number of shares of the underlying in one option
+// contract, which option prices are quoted per share of
+const ContractSize = 100
+
+// Multiplier returns what a quantity of symbol is multiplied by to get a
+// number of shares: ContractSize for OCC option symbols, and 1 for others
+func Multiplier(symbol string) int64 {
+	if IsOptionSymbol(symbol) {
+		return ContractSize
+	}
+	return 1
+}
+
+// IsOptionSymbol reports whether s is an OCC option symbol
+func IsOptionSymbol(s string) bool {
+	_, err := ParseOptionSymbol(s)
+	return err == nil
+}
@@ -0,0 +1,62 @@
+package allyapi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseOptionSymbol(t *testing.T) {
+	for _, tt := range []struct {
+		in, underlying, expiration string
+		typ                        OptionType
+		strike                     string
+	}{
+		{"AAPL250117C00200000", "AAPL", "2025-01-17", Call, "200"},
+		{"spy240621p00512500", "SPY", "2024-06-21", Put, "512.5"},
+		{"BRKB  250321C00420000", "BRKB", "2025-03-21", Call, "420"},
+		{"F250117P00000500", "F", "2025-01-17", Put, "0.5"},
+	} {
+		o, err := ParseOptionSymbol(tt.in)
+		if err != nil {
+			t.Errorf("%v: %v", tt.in, err)
+			continue
+		}
+		if o.Underlying != tt.underlying || o.Expiration.Format("2006-01-02") != tt.expiration || o.Type != tt.typ || o.Strike.String() != tt.strike {
+			t.Errorf("%v: got %v %v %v %v", tt.in, o.Underlying, o.Expiration.Format("2006-01-02"), o.Type, o.Strike)
+		}
+		if o.Expiration.Location() != MarketTime {
+			t.Errorf("%v: expiration in %v, want market time", tt.in, o.Expiration.Location())
+		}
+	}
+
+	for _, in := range []string{"AAPL", "250117C00200000", "AAPL251317C00200000", "AAPL250117X00200000", "AAPL250117C0020000A"} {
+		if IsOptionSymbol(in) {
+			t.Errorf("%v parsed as an option symbol", in)
+		}
+	}
+}
+
+func TestOptionSymbolString(t *testing.T) {
+	o := OptionSymbol{
+		Underlying: "spy",
+		Expiration: time.Date(2024, 6, 21, 0, 0, 0, 0, MarketTime),
+		Type:       Put,
+		Strike:     NewDecimal(512.5),
+	}
+	if got := o.String(); got != "SPY240621P00512500" {
+		t.Errorf("got %v, want SPY240621P00512500", got)
+	}
+	back, err := ParseOptionSymbol(o.String())
+	if err != nil || !back.Expiration.Equal(o.Expiration) || back.Strike != o.Strike {
+		t.Errorf("round trip gave %+v, %v", back, err)
+	}
+}
+
+func TestMultiplier(t *testing.T) {
+	if m := Multiplier("AAPL250117C00200000"); m != ContractSize {
+		t.Errorf("option multiplier = %v, want %v", m, ContractSize)
+	}
+	if m := Multiplier("AAPL"); m != 1 {
+		t.Errorf("stock multiplier = %v, want 1", m)
+	}
+}
@@ -0,0 +1,142 @@
+package allyapi
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// ErrDryRun is returned instead of sending an order request when the client
+// is in dry run mode
+var ErrDryRun = errors.New("dry run: request not sent")
+
+// OrderResponse holds the fields in the response to placing or previewing an
+// order
+type OrderResponse struct {
+	ClientOrderID     string        `json:",omitempty" xml:"clientorderid,omitempty"`
+	OrderStatus       string        `json:",omitempty" xml:"orderstatus,omitempty"`
+	EstCommission     Decimal       `json:",omitempty" xml:"estcommission,omitempty"`
+	Principal         Decimal       `json:",omitempty" xml:"principal,omitempty"`
+	SecFee            Decimal       `json:",omitempty" xml:"secfee,omitempty"`
+	MarginRequirement Decimal       `json:",omitempty" xml:"marginrequirement,omitempty"`
+	NetAmt            Decimal       `json:",omitempty" xml:"netamt,omitempty"`
+	Warning           *OrderWarning `json:",omitempty" xml:"warning,omitempty"`
+}
+
+// OrderWarning is a warning about a placed or previewed order
+type OrderWarning struct {
+	WarningCode string `json:",omitempty" xml:"warningcode,omitempty"`
+	WarningText string `json:",omitempty" xml:"warningtext,omitempty"`
+}
+
+// Order endpoints only accept FIXML, so always request an XML response
+func (ac *Client) postFIXML(endpoint string, msg *fixml) (*APIResponse, error) {
+	return ac.postOrder(endpoint, msg, ac.DuplicateWindow)
+}
+
+// Send an order message, refusing it if it duplicates one placed within
+// window
+func (ac *Client) postOrder(endpoint string, msg *fixml, window time.Duration) (*APIResponse, error) {
+	b, err := xml.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ac.ctx, "POST", ac.env.BaseURL+endpoint, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", ac.userAgent)
+	req.Header.Set("Content-Type", "text/xml")
+
+	if ac.DryRun {
+		w := ac.DryRunOutput
+		if w == nil {
+			w = os.Stdout
+		}
+		fmt.Fprintf(w, "%v %v\nContent-Type: %v\n\n%s\n", req.Method, req.URL, req.Header.Get("Content-Type"), b)
+		return nil, ErrDryRun
+	}
+
+	if err := ac.checkDuplicate(endpoint, b, window); err != nil {
+		return nil, err
+	}
+	audit, err := ac.auditOrder(endpoint, b)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ac.callRequest(req)
+	audit(resp, err)
+	return resp, err
+}
+
+// PreviewOrder returns the estimated cost and commission of an order without
+// placing it
+func (ac *Client) PreviewOrder(o *Order) (*APIResponse, error) {
+	msg, err := o.fixml()
+	if err != nil {
+		return nil, err
+	}
+	return ac.postFIXML("/accounts/"+o.Account+"/orders/preview.xml", msg)
+}
+
+// PlaceOrder places an order
+func (ac *Client) PlaceOrder(o *Order) (*APIResponse, error) {
+	msg, err := o.fixml()
+	if err != nil {
+		return nil, err
+	}
+	return ac.postFIXML("/accounts/"+o.Account+"/orders.xml", msg)
+}
+
+// PlaceDuplicateOrder places an order like PlaceOrder, even if an identical
+// one was placed within DuplicateWindow
+func (ac *Client) PlaceDuplicateOrder(o *Order) (*APIResponse, error) {
+	msg, err := o.fixml()
+	if err != nil {
+		return nil, err
+	}
+	return ac.postOrder("/accounts/"+o.Account+"/orders.xml", msg, 0)
+}
+
+// PreviewOrderGroup returns the estimated cost and commission of an order
+// group without placing it
+func (ac *Client) PreviewOrderGroup(g *OrderGroup) (*APIResponse, error) {
+	msg, err := g.fixml()
+	if err != nil {
+		return nil, err
+	}
+	return ac.postFIXML("/accounts/"+g.Account()+"/orders/preview.xml", msg)
+}
+
+// PlaceOrderGroup places an order group
+func (ac *Client) PlaceOrderGroup(g *OrderGroup) (*APIResponse, error) {
+	msg, err := g.fixml()
+	if err != nil {
+		return nil, err
+	}
+	return ac.postFIXML("/accounts/"+g.Account()+"/orders.xml", msg)
+}
+
+// PreviewMultiLegOrder returns the estimated cost and commission of a
+// multi-leg order without placing it
+func (ac *Client) PreviewMultiLegOrder(m *MultiLegOrder) (*APIResponse, error) {
+	msg, err := m.fixml()
+	if err != nil {
+		return nil, err
+	}
+	return ac.postFIXML("/accounts/"+m.Account+"/orders/preview.xml", msg)
+}
+
+// PlaceMultiLegOrder places a multi-leg order
+func (ac *Client) PlaceMultiLegOrder(m *MultiLegOrder) (*APIResponse, error) {
+	msg, err := m.fixml()
+	if err != nil {
+		return nil, err
+	}
+	return ac.postFIXML("/accounts/"+m.Account+"/orders.xml", msg)
+}
@@ -0,0 +1,296 @@
+package allyapi
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Order statuses
+const (
+	StatusNew             = "new"
+	StatusPartiallyFilled = "partially_filled"
+	StatusFilled          = "filled"
+	StatusDoneForDay      = "done_for_day"
+	StatusCanceled        = "canceled"
+	StatusReplaced        = "replaced"
+	StatusPendingCancel   = "pending_cancel"
+	StatusRejected        = "rejected"
+	StatusPendingNew      = "pending_new"
+	StatusExpired         = "expired"
+	StatusPendingReplace  = "pending_replace"
+)
+
+// Order statuses by FIXML code
+var orderStatuses = map[string]string{
+	"0": StatusNew,
+	"1": StatusPartiallyFilled,
+	"2": StatusFilled,
+	"3": StatusDoneForDay,
+	"4": StatusCanceled,
+	"5": StatusReplaced,
+	"6": StatusPendingCancel,
+	"8": StatusRejected,
+	"A": StatusPendingNew,
+	"C": StatusExpired,
+	"E": StatusPendingReplace,
+}
+
+// Order sides and types by FIXML code. Buys to cover are told apart by
+// their account type.
+var (
+	fixmlSides = map[string]string{"1": "buy", "2": "sell", "5": "sell_short"}
+	fixmlTypes = map[string]string{"1": "market", "2": "limit", "3": "stop", "4": "stop_limit", "P": "trailing_stop"}
+)
+
+// OrderStatus is the state of an order in an account's order list. Status is
+// one of the Status constants, or the FIXML code of a status it doesn't know.
+//
+// Filled is the quantity filled so far and AvgPrice its average price, over
+// all of the order's executions.
+type OrderStatus struct {
+	ID        string    `json:"id"`
+	Account   string    `json:"account"`
+	Symbol    string    `json:"symbol"`
+	Side      string    `json:"side"`
+	Type      string    `json:"type"`
+	Status    string    `json:"status"`
+	Quantity  int       `json:"quantity"`
+	Remaining int       `json:"remaining"`
+	Filled    int       `json:"filled"`
+	AvgPrice  Decimal   `json:"avg_price,omitempty"`
+	Price     Decimal   `json:"price,omitempty"`
+	StopPrice Decimal   `json:"stop_price,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// Done reports whether the order can no longer fill: it has filled, or was
+// canceled, replaced, rejected, or expired
+func (s *OrderStatus) Done() bool {
+	switch s.Status {
+	case StatusFilled, StatusDoneForDay, StatusCanceled, StatusReplaced, StatusRejected, StatusExpired:
+		return true
+	}
+	return false
+}
+
+// An order's execution report, as given in the order list
+type fixmlExecReport struct {
+	OrdID     string          `xml:",attr"`
+	Stat      string          `xml:",attr"`
+	Acct      string          `xml:",attr"`
+	AcctTyp   string          `xml:",attr"`
+	Side      string          `xml:",attr"`
+	Typ       string          `xml:",attr"`
+	Px        string          `xml:",attr"`
+	StopPx    string          `xml:",attr"`
+	LeavesQty string          `xml:",attr"`
+	CumQty    string          `xml:",attr"`
+	AvgPx     string          `xml:",attr"`
+	LastQty   string          `xml:",attr"`
+	LastPx    string          `xml:",attr"`
+	TxnTm     string          `xml:",attr"`
+	Instrmt   fixmlInstrument `xml:"Instrmt"`
+	OrdQty    fixmlQuantity   `xml:"OrdQty"`
+}
+
+// Orders in the order list, each a FIXML message
+type orderMessages []struct {
+	FIXMLMessage string `json:"fixmlmessage"`
+}
+
+// UnmarshalJSON accepts either an array of orders or a single order
+func (om *orderMessages) UnmarshalJSON(data []byte) error {
+	type messages orderMessages
+	return unmarshalArray(data, (*messages)(om))
+}
+
+// Quantities and prices of what an execution report says was filled
+type fill struct {
+	cumQty, lastQty int
+	avgPx, lastPx   Decimal
+}
+
+// Convert an execution report to an order status, and what it says was
+// filled
+func (r *fixmlExecReport) status() (OrderStatus, fill, error) {
+	var f fill
+	s := OrderStatus{
+		ID:      r.OrdID,
+		Account: r.Acct,
+		Symbol:  r.Instrmt.Sym,
+		Side:    fixmlSides[r.Side],
+		Type:    fixmlTypes[r.Typ],
+		Status:  orderStatuses[r.Stat],
+	}
+	if s.Status == "" {
+		s.Status = r.Stat
+	}
+	if s.Side == "buy" && r.AcctTyp == "5" {
+		s.Side = "buy_to_cover"
+	}
+
+	// Options are identified by their underlying, expiration, and strike
+	if r.Instrmt.SecTyp == "OPT" {
+		typ, err := ParseOptionType(r.Instrmt.CFI[min(1, len(r.Instrmt.CFI)):])
+		if err != nil {
+			return s, f, fmt.Errorf("order %v: %v", r.OrdID, err)
+		}
+		expiration, err := parseMarketTime(r.Instrmt.MatDt)
+		if err != nil {
+			return s, f, fmt.Errorf("order %v: %v", r.OrdID, err)
+		}
+		strike, err := ParseDecimal(r.Instrmt.StrkPx)
+		if err != nil {
+			return s, f, fmt.Errorf("order %v: %v", r.OrdID, err)
+		}
+		s.Symbol = OptionSymbol{Underlying: r.Instrmt.Sym, Expiration: expiration, Type: typ, Strike: strike}.String()
+	}
+
+	var err error
+	for _, q := range []struct {
+		s string
+		n *int
+	}{{r.OrdQty.Qty, &s.Quantity}, {r.LeavesQty, &s.Remaining}, {r.CumQty, &f.cumQty}, {r.LastQty, &f.lastQty}} {
+		if q.s == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(q.s, 64)
+		if err != nil {
+			return s, f, fmt.Errorf("order %v: invalid quantity %q", r.OrdID, q.s)
+		}
+		*q.n = int(v)
+	}
+	if s.Price, err = ParseDecimal(r.Px); err != nil {
+		return s, f, fmt.Errorf("order %v: %v", r.OrdID, err)
+	}
+	if s.StopPrice, err = ParseDecimal(r.StopPx); err != nil {
+		return s, f, fmt.Errorf("order %v: %v", r.OrdID, err)
+	}
+	if f.avgPx, err = ParseDecimal(r.AvgPx); err != nil {
+		return s, f, fmt.Errorf("order %v: %v", r.OrdID, err)
+	}
+	if f.lastPx, err = ParseDecimal(r.LastPx); err != nil {
+		return s, f, fmt.Errorf("order %v: %v", r.OrdID, err)
+	}
+	if r.TxnTm != "" {
+		if s.Time, err = parseMarketTime(r.TxnTm); err != nil {
+			return s, f, fmt.Errorf("order %v: %v", r.OrdID, err)
+		}
+	}
+	return s, f, nil
+}
+
+// Orders returns the status of the orders in an account, from the last day
+// or so. The list is always requested as JSON, since its orders are FIXML
+// messages in either format.
+func (ac *Client) Orders(account string) ([]OrderStatus, error) {
+	b, err := ac.Raw("GET", "/accounts/"+url.PathEscape(account)+"/orders.json", nil)
+	if err != nil {
+		return nil, err
+	}
+	var m struct {
+		Response struct {
+			ID          string `json:"@id"`
+			Error       string
+			OrderStatus struct {
+				Order orderMessages
+			}
+		}
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	if e := m.Response.Error; e != "" && e != "Success" {
+		ac.Metrics.addError()
+		return nil, &APIError{StatusCode: http.StatusOK, Message: e, RequestID: m.Response.ID}
+	}
+
+	// An order is listed once for each of its executions, oldest first, so
+	// merge them into its latest status
+	var orders []OrderStatus
+	index := make(map[string]int)
+	fills := make(map[string]*fillTotal)
+	for _, o := range m.Response.OrderStatus.Order {
+		var msg struct {
+			ExecRpt fixmlExecReport
+		}
+		if err := xml.Unmarshal([]byte(o.FIXMLMessage), &msg); err != nil {
+			return nil, fmt.Errorf("error decoding order: %v", err)
+		}
+		s, f, err := msg.ExecRpt.status()
+		if err != nil {
+			return nil, err
+		}
+		if fills[s.ID] == nil {
+			fills[s.ID] = &fillTotal{}
+		}
+		fills[s.ID].add(f)
+		if i, ok := index[s.ID]; ok {
+			orders[i] = s
+		} else {
+			index[s.ID] = len(orders)
+			orders = append(orders, s)
+		}
+	}
+	for i := range orders {
+		o := &orders[i]
+		o.Filled, o.AvgPrice = fills[o.ID].total(o)
+	}
+	return orders, nil
+}
+
+// Running total of an order's fills
+type fillTotal struct {
+	// Latest cumulative quantity and average price reported
+	cumQty int
+	avgPx  Decimal
+
+	// Sum of the executions reported
+	qty   int
+	value Decimal
+}
+
+func (t *fillTotal) add(f fill) {
+	if f.cumQty >= t.cumQty && f.cumQty > 0 {
+		t.cumQty, t.avgPx = f.cumQty, f.avgPx
+	}
+	if f.lastQty > 0 {
+		t.qty += f.lastQty
+		t.value += f.lastPx.MulInt(int64(f.lastQty))
+	}
+}
+
+// The quantity filled and its average price. Cumulative figures are used
+// when the API gives them, then the sum of the executions, and then the
+// quantity no longer remaining, whose price isn't known.
+func (t *fillTotal) total(o *OrderStatus) (int, Decimal) {
+	switch {
+	case t.cumQty > 0:
+		return t.cumQty, t.avgPx
+	case t.qty > 0:
+		return t.qty, t.value.Div(DecimalFromInt(int64(t.qty)))
+	case o.Status == StatusPartiallyFilled || o.Status == StatusFilled:
+		return o.Quantity - o.Remaining, 0
+	}
+	return 0, 0
+}
+
+// Order returns the status of an order in an account, or an error wrapping
+// ErrNotFound if it isn't in the account's order list
+func (ac *Client) Order(account, id string) (*OrderStatus, error) {
+	orders, err := ac.Orders(account)
+	if err != nil {
+		return nil, err
+	}
+	for i := range orders {
+		if orders[i].ID == id {
+			return &orders[i], nil
+		}
+	}
+	return nil, fmt.Errorf("order %v: %w", id, ErrNotFound)
+}
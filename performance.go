@@ -0,0 +1,212 @@
+package allyapi
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PortfolioValue is an account's value at the close of a day, and the money
+// deposited into it that day, negative if withdrawn
+type PortfolioValue struct {
+	Date  time.Time `json:"date"`
+	Value Decimal   `json:"value"`
+	Flow  Decimal   `json:"flow,omitempty"`
+}
+
+// TimeWeightedReturn returns the return of daily values, oldest first, as a
+// fraction, removing the effect of deposits and withdrawals: each day's
+// return is its value less that day's flow, which is taken to arrive at the
+// close, over the day before's. Days after one valued at nothing are skipped.
+func TimeWeightedReturn(values []PortfolioValue) float64 {
+	growth := 1.0
+	for i := 1; i < len(values); i++ {
+		prev := values[i-1].Value.Float64()
+		if prev <= 0 {
+			continue
+		}
+		growth *= (values[i].Value - values[i].Flow).Float64() / prev
+	}
+	return growth - 1
+}
+
+// Performance is an account's time-weighted return over a period, compared
+// to a benchmark's
+type Performance struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+
+	StartValue Decimal `json:"start_value"`
+	EndValue   Decimal `json:"end_value"`
+	NetFlows   Decimal `json:"net_flows"`
+	Return     float64 `json:"return"`
+
+	Benchmark       string  `json:"benchmark"`
+	BenchmarkReturn float64 `json:"benchmark_return"`
+
+	// The account's value on each trading day, and the benchmark's close
+	Values          []PortfolioValue `json:"values"`
+	BenchmarkCloses []Decimal        `json:"benchmark_closes"`
+}
+
+// Symbol traded in a transaction
+func (t *Transaction) symbol() string {
+	if t.Symbol != "" {
+		return strings.ToUpper(t.Symbol)
+	}
+	return strings.ToUpper(t.Transaction.Security.Sym)
+}
+
+// Performance returns the time-weighted return of an account from from to
+// to, inclusive, and that of benchmark's price on the same trading days.
+//
+// The account's value on each day is worked back from its holdings and cash
+// now by undoing the transactions since: trades change the quantities held,
+// and every transaction's amount the cash. Bookkeeping transactions, such as
+// deposits, withdrawals, and transfers, are taken to be money moved in or
+// out rather than returns. Holdings are valued at their daily closes.
+func (ac *Client) Performance(account string, from, to time.Time, benchmark string) (*Performance, error) {
+	now := time.Now()
+	if to.IsZero() || to.After(now) {
+		to = now
+	}
+	if !from.Before(to) {
+		return nil, errors.New("the period must start before it ends")
+	}
+	benchmark = strings.ToUpper(benchmark)
+
+	holdings, err := ac.Holdings(account)
+	if err != nil {
+		return nil, fmt.Errorf("error getting holdings: %w", err)
+	}
+	positions, err := holdings.Positions()
+	if err != nil {
+		return nil, err
+	}
+	b, err := ac.Balances(account)
+	if err != nil {
+		return nil, fmt.Errorf("error getting balances: %w", err)
+	}
+	txs, err := ac.History(account, HistoryRange(from, now, now), "all")
+	if err != nil {
+		return nil, fmt.Errorf("error getting history: %w", err)
+	}
+
+	// Newest first, for undoing
+	type dated struct {
+		t    *Transaction
+		when time.Time
+	}
+	var since []dated
+	for i := range txs {
+		when, err := txs[i].Time()
+		if err != nil {
+			return nil, fmt.Errorf("invalid transaction date %q", txs[i].Date)
+		}
+		if !when.Before(from) {
+			since = append(since, dated{&txs[i], when})
+		}
+	}
+	sort.SliceStable(since, func(i, j int) bool { return since[i].when.After(since[j].when) })
+
+	// Closes of the benchmark, whose trading days are the portfolio's, and of
+	// everything held during the period
+	bench, err := ac.HistoricalQuotes(benchmark, "daily", from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error getting quotes of %v: %w", benchmark, err)
+	}
+	if len(bench) < 2 {
+		return nil, fmt.Errorf("not enough quotes of %v for the period", benchmark)
+	}
+	sort.Slice(bench, func(i, j int) bool { return bench[i].Time.Before(bench[j].Time) })
+
+	held := make(map[string]bool)
+	for sym, qty := range positions {
+		if qty != 0 {
+			held[sym] = true
+		}
+	}
+	for _, d := range since {
+		if strings.EqualFold(d.t.Activity, "trade") && d.t.symbol() != "" {
+			held[d.t.symbol()] = true
+		}
+	}
+	closes := make(map[string]map[string]Decimal)
+	for sym := range held {
+		bars, err := ac.HistoricalQuotes(sym, "daily", from.AddDate(0, 0, -7), to)
+		if err != nil {
+			return nil, fmt.Errorf("error getting quotes of %v: %w", sym, err)
+		}
+		closes[sym] = make(map[string]Decimal)
+		for _, bar := range bars {
+			closes[sym][bar.Time.In(MarketTime).Format("2006-01-02")] = bar.Close
+		}
+	}
+
+	// Walk back from now through the benchmark's days, undoing the
+	// transactions after each day's close before valuing it
+	cash := b.Money.Total
+	undo := func(t *Transaction) {
+		cash -= t.Amount
+		if strings.EqualFold(t.Activity, "trade") {
+			qty, _ := strconv.ParseFloat(t.Transaction.Quantity, 64)
+			positions[t.symbol()] -= int(qty)
+		}
+	}
+	closeOf := func(i int) time.Time {
+		day := bench[i].Time.In(MarketTime)
+		return time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, MarketTime)
+	}
+	p := &Performance{Benchmark: benchmark, Values: make([]PortfolioValue, len(bench)), BenchmarkCloses: make([]Decimal, len(bench))}
+	next := 0
+	for i := len(bench) - 1; i >= 0; i-- {
+		dayEnd := closeOf(i)
+		for ; next < len(since) && !since[next].when.Before(dayEnd); next++ {
+			undo(since[next].t)
+		}
+
+		v := PortfolioValue{Date: dayEnd.AddDate(0, 0, -1), Value: cash}
+		for sym, qty := range positions {
+			if qty != 0 {
+				v.Value += lastClose(closes[sym], v.Date).MulInt(int64(qty))
+			}
+		}
+
+		// Money moved since the day before's close, including over weekends
+		if i > 0 {
+			prevEnd := closeOf(i - 1)
+			for j := next; j < len(since) && !since[j].when.Before(prevEnd); j++ {
+				if strings.EqualFold(since[j].t.Activity, "bookkeeping") {
+					v.Flow += since[j].t.Amount
+				}
+			}
+		}
+		p.Values[i] = v
+		p.BenchmarkCloses[i] = bench[i].Close
+	}
+
+	first, last := p.Values[0], p.Values[len(p.Values)-1]
+	p.From, p.To = first.Date, last.Date
+	p.StartValue, p.EndValue = first.Value, last.Value
+	for _, v := range p.Values[1:] {
+		p.NetFlows += v.Flow
+	}
+	p.Return = TimeWeightedReturn(p.Values)
+	if start := p.BenchmarkCloses[0]; start > 0 {
+		p.BenchmarkReturn = p.BenchmarkCloses[len(bench)-1].Float64()/start.Float64() - 1
+	}
+	return p, nil
+}
+
+// Close on day, or the last before it if the symbol didn't trade that day
+func lastClose(closes map[string]Decimal, day time.Time) Decimal {
+	for i := 0; i < 7; i++ {
+		if c, ok := closes[day.AddDate(0, 0, -i).Format("2006-01-02")]; ok {
+			return c
+		}
+	}
+	return 0
+}
@@ -0,0 +1,148 @@
+package allyapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+type rateLimitState struct {
+	Used      int
+	Remaining int
+	Limit     int
+	Expires   time.Time
+}
+
+// DefaultStateDir returns the allyapi directory in $XDG_STATE_HOME, or
+// ~/.local/state if it is not set
+func DefaultStateDir() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "allyapi")
+}
+
+// LoadRateLimitState reads the rate limit counters saved in StateFile by a
+// previous process. Counters from an expired period are ignored.
+func (ac *Client) LoadRateLimitState() error {
+	if ac.StateFile == "" {
+		return nil
+	}
+
+	b, err := os.ReadFile(ac.StateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var state rateLimitState
+	if err := json.Unmarshal(b, &state); err != nil {
+		return fmt.Errorf("%v: %v", ac.StateFile, err)
+	}
+
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	if time.Now().Before(state.Expires) {
+		ac.rateLimitUsed = state.Used
+		ac.rateLimitRemaining = state.Remaining
+		ac.rateLimitLimit = state.Limit
+		ac.rateLimitExpires = state.Expires
+	}
+	return nil
+}
+
+// Called with ac.mu held
+func (ac *Client) saveRateLimitState() error {
+	if ac.StateFile == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(rateLimitState{
+		Used:      ac.rateLimitUsed,
+		Remaining: ac.rateLimitRemaining,
+		Limit:     ac.rateLimitLimit,
+		Expires:   ac.rateLimitExpires,
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(ac.StateFile), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(ac.StateFile, b, 0600)
+}
+
+// Refuse to make a request when the rate limit is known to be used up
+func (ac *Client) checkRateLimit() error {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	if ac.rateLimitRemaining <= 0 && time.Now().Before(ac.rateLimitExpires) {
+		return fmt.Errorf("%w; no API calls remaining until %v", ErrRateLimited, ac.rateLimitExpires)
+	}
+	return nil
+}
+
+// RateLimit returns the rate limit counters from the most recent response, or
+// from StateFile if no request has been made yet: the number of calls used and
+// remaining in the current period, the total allowed, and when the period
+// ends. All are zero if nothing is known.
+func (ac *Client) RateLimit() (used, remaining, limit int, expires time.Time) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	return ac.rateLimitUsed, ac.rateLimitRemaining, ac.rateLimitLimit, ac.rateLimitExpires
+}
+
+// Update the rate limit counters from the response headers:
+// X-Ratelimit-Used: Number of requests sent against the current limit
+// X-Ratelimit-Expire: When the current limit will expire (Unix timestamp)
+// X-Ratelimit-Limit: Total number of requests allowed in the call limit
+// X-Ratelimit-Remaining: Number of requests allowed against the current limit
+func (ac *Client) updateRateLimit(resp *http.Response) {
+	remaining, err := strconv.Atoi(resp.Header.Get("X-Ratelimit-Remaining"))
+	if err != nil {
+		ac.logger.Debug("unable to determine API calls remaining", "error", err)
+		return
+	}
+	used, _ := strconv.Atoi(resp.Header.Get("X-Ratelimit-Used"))
+	limit, _ := strconv.Atoi(resp.Header.Get("X-Ratelimit-Limit"))
+	expires, err := timestampToDate(resp.Header.Get("X-Ratelimit-Expire"))
+	if err != nil {
+		ac.logger.Warn("unable to determine rate limit expiration", "error", err)
+	}
+
+	ac.mu.Lock()
+	ac.rateLimitUsed = used
+	ac.rateLimitRemaining = remaining
+	ac.rateLimitLimit = limit
+	ac.rateLimitExpires = expires
+	ac.mu.Unlock()
+
+	if remaining < 10 {
+		ac.logger.Warn("few API calls remaining", "remaining", remaining, "expires", expires)
+	}
+
+	// Save the state in the background so that requests aren't held up by
+	// disk writes; Wait blocks until it is done
+	ac.wg.Add(1)
+	go func() {
+		defer ac.wg.Done()
+		ac.mu.Lock()
+		defer ac.mu.Unlock()
+
+		if err := ac.saveRateLimitState(); err != nil {
+			ac.logger.Warn("unable to save rate limit state", "error", err)
+		}
+	}()
+}
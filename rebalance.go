@@ -0,0 +1,153 @@
+package allyapi
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Allocation is the target percentage of a portfolio's value in each symbol,
+// configured in the config file, e.g.
+//
+//	[allocation]
+//	VTI = 60
+//	BND = 30
+//
+// What isn't allocated, 10% here, is kept in cash. Only stocks and funds
+// can be allocated; option contracts are left out of the portfolio.
+type Allocation map[string]float64
+
+// Validate reports whether the percentages are valid and add up to no more
+// than 100, and whether no symbol is an option
+func (a Allocation) Validate() error {
+	if len(a) == 0 {
+		return errors.New("no target allocation")
+	}
+	var total float64
+	for sym, pct := range a {
+		// Trades are in shares, which options aren't held in
+		if IsOptionSymbol(sym) {
+			return fmt.Errorf("can't allocate to option %v", sym)
+		}
+		if pct < 0 || math.IsNaN(pct) {
+			return fmt.Errorf("invalid allocation of %v: %v%%", sym, pct)
+		}
+		total += pct
+	}
+	if total > 100 {
+		return fmt.Errorf("allocation adds up to %v%%, over 100%%", total)
+	}
+	return nil
+}
+
+// RebalanceTrade is a trade that brings a symbol to its target allocation.
+// Values are at Price, and percentages of the portfolio's value.
+type RebalanceTrade struct {
+	Symbol   string  `json:"symbol"`
+	Side     string  `json:"side"`
+	Quantity int     `json:"quantity"`
+	Price    Decimal `json:"price"`
+
+	Held       int     `json:"held"`
+	Value      Decimal `json:"value"`
+	Percent    float64 `json:"percent"`
+	Target     Decimal `json:"target"`
+	TargetPct  float64 `json:"target_pct"`
+	TradeValue Decimal `json:"trade_value"`
+}
+
+// PlanRebalance returns the trades that bring positions, in shares by
+// symbol, to the allocation, given the symbols' prices and the cash
+// available. The portfolio is the allocated symbols and the cash; positions
+// in other symbols are left out of it. Symbols within threshold percentage
+// points of their targets aren't traded. Trades are in whole shares, with
+// sells first so that they fund the buys, and then by symbol.
+func PlanRebalance(alloc Allocation, positions map[string]int, prices map[string]Decimal, cash Decimal, threshold float64) ([]RebalanceTrade, error) {
+	if err := alloc.Validate(); err != nil {
+		return nil, err
+	}
+
+	total := cash
+	for sym := range alloc {
+		sym = strings.ToUpper(sym)
+		if prices[sym] <= 0 {
+			return nil, fmt.Errorf("no price for %v", sym)
+		}
+		total += prices[sym].MulInt(int64(positions[sym]))
+	}
+	if total <= 0 {
+		return nil, errors.New("nothing to rebalance: no cash or holdings in the allocation")
+	}
+
+	var trades []RebalanceTrade
+	for sym, pct := range alloc {
+		sym = strings.ToUpper(sym)
+		t := RebalanceTrade{Symbol: sym, Price: prices[sym], Held: positions[sym], TargetPct: pct}
+		t.Value = t.Price.MulInt(int64(t.Held))
+		t.Percent = t.Value.Float64() / total.Float64() * 100
+		t.Target = total.Mul(NewDecimal(pct / 100))
+		if math.Abs(t.Percent-t.TargetPct) < threshold {
+			continue
+		}
+
+		// Whole shares the target pays for, so that the buys never cost
+		// more than the cash and sells raise
+		want := int(t.Target / t.Price)
+		switch {
+		case want > t.Held:
+			t.Side, t.Quantity = "buy", want-t.Held
+		case want < t.Held:
+			t.Side, t.Quantity = "sell", t.Held-want
+		default:
+			continue
+		}
+		t.TradeValue = t.Price.MulInt(int64(t.Quantity))
+		trades = append(trades, t)
+	}
+	sort.Slice(trades, func(i, j int) bool {
+		if trades[i].Side != trades[j].Side {
+			return trades[i].Side == "sell"
+		}
+		return trades[i].Symbol < trades[j].Symbol
+	})
+	return trades, nil
+}
+
+// Rebalance plans the trades that bring an account's holdings to the
+// allocation, with PlanRebalance, at the last prices and with the account's
+// available cash
+func (ac *Client) Rebalance(account string, alloc Allocation, threshold float64) ([]RebalanceTrade, error) {
+	holdings, err := ac.Holdings(account)
+	if err != nil {
+		return nil, fmt.Errorf("error getting holdings: %w", err)
+	}
+	positions, err := holdings.Positions()
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := ac.Balances(account)
+	if err != nil {
+		return nil, fmt.Errorf("error getting balances: %w", err)
+	}
+
+	symbols := make([]string, 0, len(alloc))
+	for sym := range alloc {
+		symbols = append(symbols, strings.ToUpper(sym))
+	}
+	resp, err := ac.GetQuotes(symbols, []string{"symbol", "last"})
+	if err != nil {
+		return nil, fmt.Errorf("error getting quotes: %w", err)
+	}
+	prices := make(map[string]Decimal)
+	if resp.Response.Quotes != nil {
+		for _, q := range resp.Response.Quotes.Quote {
+			if prices[strings.ToUpper(q["symbol"])], err = ParseDecimal(q["last"]); err != nil {
+				return nil, fmt.Errorf("invalid price of %v: %v", q["symbol"], err)
+			}
+		}
+	}
+	return PlanRebalance(alloc, positions, prices, b.Money.CashAvailable, threshold)
+}
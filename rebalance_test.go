@@ -0,0 +1,60 @@
+package allyapi
+
+import "testing"
+
+func TestPlanRebalance(t *testing.T) {
+	// $10,000 in all: VTI is 70% against a target of 60, and BND 20% against
+	// 30, with $1,000 in cash
+	alloc := Allocation{"vti": 60, "BND": 30}
+	// Contracts of a VTI option, held apart from the shares, are left out
+	positions := map[string]int{"VTI": 35, "BND": 25, "AAPL": 100, "VTI250117C00200000": 3}
+	prices := map[string]Decimal{"VTI": DecimalFromInt(200), "BND": DecimalFromInt(80), "AAPL": DecimalFromInt(150), "VTI250117C00200000": DecimalFromInt(5)}
+
+	trades, err := PlanRebalance(alloc, positions, prices, DecimalFromInt(1000), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		symbol, side string
+		qty          int
+		value        string
+	}{
+		{"VTI", "sell", 5, "1000"},
+		{"BND", "buy", 12, "960"},
+	}
+	if len(trades) != len(want) {
+		t.Fatalf("got %d trades, want %d: %+v", len(trades), len(want), trades)
+	}
+	for i, w := range want {
+		tr := trades[i]
+		if tr.Symbol != w.symbol || tr.Side != w.side || tr.Quantity != w.qty || tr.TradeValue.String() != w.value {
+			t.Errorf("trade %d = %v %v %v for %v, want %+v", i, tr.Side, tr.Quantity, tr.Symbol, tr.TradeValue, w)
+		}
+	}
+	if trades[0].Percent != 70 || trades[0].Target.String() != "6000" {
+		t.Errorf("VTI is %v%% with a target of %v, want 70%% and 6000", trades[0].Percent, trades[0].Target)
+	}
+
+	// Within 15 points of the targets, nothing is traded
+	if trades, err := PlanRebalance(alloc, positions, prices, DecimalFromInt(1000), 15); err != nil || len(trades) != 0 {
+		t.Errorf("got %+v, %v within the threshold", trades, err)
+	}
+
+	if _, err := PlanRebalance(Allocation{"VTI": 60, "XYZ": 30}, positions, prices, DecimalFromInt(1000), 1); err == nil {
+		t.Error("planned without a price for XYZ")
+	}
+	if _, err := PlanRebalance(Allocation{"VTI": 60}, nil, prices, 0, 1); err == nil {
+		t.Error("planned for an empty portfolio")
+	}
+}
+
+func TestAllocationValidate(t *testing.T) {
+	for _, a := range []Allocation{nil, {"VTI": -1}, {"VTI": 60, "BND": 50}, {"VTI": 60, "vti250117c00200000": 1}} {
+		if err := a.Validate(); err == nil {
+			t.Errorf("%v is valid", a)
+		}
+	}
+	if err := (Allocation{"VTI": 60, "BND": 40}).Validate(); err != nil {
+		t.Error(err)
+	}
+}
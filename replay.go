@@ -0,0 +1,128 @@
+package allyapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// RecordedMessage is a stream message as written by a StreamRecorder, with
+// when it arrived
+type RecordedMessage struct {
+	Time    time.Time    `json:"time"`
+	Message *APIResponse `json:"message"`
+}
+
+// StreamRecorder writes stream messages as lines of JSON, each with the time
+// it was recorded, to be read back by Replay or ReplayStream. It is safe to
+// use from more than one goroutine.
+type StreamRecorder struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewStreamRecorder returns a recorder writing to w
+func NewStreamRecorder(w io.Writer) *StreamRecorder {
+	return &StreamRecorder{w: w}
+}
+
+// Record writes a message, as received now
+func (r *StreamRecorder) Record(m *APIResponse) error {
+	b, err := json.Marshal(RecordedMessage{Time: time.Now(), Message: m})
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err = r.w.Write(append(b, '\n'))
+	return err
+}
+
+// Replay reads messages recorded by a StreamRecorder and passes each to
+// handle, as StreamQuotes does, until the recording ends, ctx is canceled,
+// or handle returns an error. Messages are spaced as they were recorded,
+// divided by speed, so that 1 replays in real time and 10 ten times as fast;
+// a speed of 0 replays them without waiting.
+func Replay(ctx context.Context, r io.Reader, speed float64, handle func(*APIResponse) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+
+	var first, start time.Time
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec RecordedMessage
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return fmt.Errorf("invalid recording at line %v: %v", line, err)
+		}
+		if rec.Message == nil {
+			continue
+		}
+
+		if first.IsZero() {
+			first, start = rec.Time, time.Now()
+		} else if speed > 0 {
+			due := start.Add(time.Duration(float64(rec.Time.Sub(first)) / speed))
+			if err := sleepUntil(ctx, due); err != nil {
+				return nil
+			}
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err := handle(rec.Message); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Wait until t, or return ctx's error if it is canceled first
+func sleepUntil(ctx context.Context, t time.Time) error {
+	d := time.Until(t)
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// ReplayStream is Replay as a channel of messages, like Stream. The channel
+// is closed after the final StreamClosed message, whose Err is set if the
+// recording couldn't be read.
+func ReplayStream(ctx context.Context, r io.Reader, speed float64) <-chan StreamMessage {
+	ch := make(chan StreamMessage)
+	send := func(msg StreamMessage) error {
+		select {
+		case ch <- msg:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	go func() {
+		defer close(ch)
+		err := Replay(ctx, r, speed, func(m *APIResponse) error {
+			if msg, ok := streamMessage(m); ok {
+				return send(msg)
+			}
+			return nil
+		})
+		if ctx.Err() != nil {
+			err = nil
+		}
+		send(StreamMessage{Event: StatusEvent, Status: StreamClosed, Err: err})
+	}()
+	return ch
+}
@@ -0,0 +1,23 @@
+package allyapi
+
+import "errors"
+
+// PositionSize returns how many shares to trade at entry so that being
+// stopped out at stop loses no more than riskPercent of capital, and the
+// position costs no more than capital. The stop is below the entry for a long
+// position and above it for a short one.
+func PositionSize(capital Decimal, riskPercent float64, entry, stop Decimal) (int, error) {
+	switch {
+	case capital <= 0:
+		return 0, errors.New("no capital to risk")
+	case riskPercent <= 0 || riskPercent > 100:
+		return 0, errors.New("risk must be a percentage between 0 and 100")
+	case entry <= 0 || stop <= 0:
+		return 0, errors.New("entry and stop prices must be positive")
+	case entry == stop:
+		return 0, errors.New("entry and stop prices must differ")
+	}
+	risk := capital.Mul(NewDecimal(riskPercent / 100))
+	shares := int(risk / (entry - stop).Abs())
+	return min(shares, int(capital/entry)), nil
+}
@@ -0,0 +1,44 @@
+package allyapi
+
+import "testing"
+
+func TestPositionSize(t *testing.T) {
+	for _, tt := range []struct {
+		capital     int64
+		risk        float64
+		entry, stop string
+		want        int
+	}{
+		// Risking $200 at $2 a share
+		{10000, 2, "50", "48", 100},
+		// Short, stopped out above the entry
+		{10000, 2, "48", "50", 100},
+		// Risk allows 1000 shares, but capital only pays for 200
+		{10000, 2, "50", "49.80", 200},
+		// Rounded down to whole shares
+		{10000, 1, "33", "30", 33},
+	} {
+		entry, _ := ParseDecimal(tt.entry)
+		stop, _ := ParseDecimal(tt.stop)
+		got, err := PositionSize(DecimalFromInt(tt.capital), tt.risk, entry, stop)
+		if err != nil || got != tt.want {
+			t.Errorf("PositionSize(%v, %v, %v, %v) = %v, %v; want %v", tt.capital, tt.risk, tt.entry, tt.stop, got, err, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		capital     int64
+		risk        float64
+		entry, stop int64
+	}{
+		{0, 1, 50, 48},
+		{10000, 0, 50, 48},
+		{10000, 101, 50, 48},
+		{10000, 1, 0, 48},
+		{10000, 1, 50, 50},
+	} {
+		if _, err := PositionSize(DecimalFromInt(tt.capital), tt.risk, DecimalFromInt(tt.entry), DecimalFromInt(tt.stop)); err == nil {
+			t.Errorf("PositionSize(%v, %v, %v, %v) succeeded", tt.capital, tt.risk, tt.entry, tt.stop)
+		}
+	}
+}
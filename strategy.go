@@ -0,0 +1,377 @@
+package allyapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Strategy is a trading strategy run by a StrategyRunner, which calls its
+// methods one at a time as quotes, trades, completed bars, and fills arrive.
+// An error from any of them stops the runner. Embed BaseStrategy to implement
+// only some of them.
+type Strategy interface {
+	OnQuote(r *StrategyRunner, q *StreamQuote) error
+	OnTrade(r *StrategyRunner, t *StreamTrade) error
+	OnBar(r *StrategyRunner, b *SymbolBar) error
+	OnFill(r *StrategyRunner, o *OrderStatus) error
+}
+
+// BaseStrategy implements Strategy by doing nothing
+type BaseStrategy struct{}
+
+func (BaseStrategy) OnQuote(*StrategyRunner, *StreamQuote) error { return nil }
+func (BaseStrategy) OnTrade(*StrategyRunner, *StreamTrade) error { return nil }
+func (BaseStrategy) OnBar(*StrategyRunner, *SymbolBar) error     { return nil }
+func (BaseStrategy) OnFill(*StrategyRunner, *OrderStatus) error  { return nil }
+
+// DefaultFillPoll is how often a StrategyRunner checks for fills by default
+const DefaultFillPoll = 10 * time.Second
+
+// StrategyRunner runs a Strategy on streamed quotes and trades, placing its
+// orders in Account and keeping track of the account's positions as they
+// fill.
+//
+// For example, to buy 10 shares of each symbol that trades above $100:
+//
+//	type breakout struct{ allyapi.BaseStrategy }
+//
+//	func (breakout) OnTrade(r *allyapi.StrategyRunner, t *allyapi.StreamTrade) error {
+//		if t.Last > allyapi.DecimalFromInt(100) && r.Position(t.Symbol) == 0 {
+//			_, err := r.PlaceOrder(&allyapi.Order{Symbol: t.Symbol, Side: "buy", Type: "market", Quantity: 10})
+//			return err
+//		}
+//		return nil
+//	}
+//
+//	r := allyapi.NewStrategyRunner(client, account, breakout{})
+//	err := r.Run(ctx, []string{"AAPL", "MSFT"})
+type StrategyRunner struct {
+	Client   *Client
+	Account  string
+	Strategy Strategy
+
+	// Interval of the bars passed to OnBar; 0 builds none
+	BarInterval time.Duration
+
+	// How often the account's orders are checked for fills
+	FillPoll time.Duration
+
+	mu        sync.Mutex
+	positions map[string]int
+	orders    map[string]OrderStatus
+	bars      *BarBuilder
+
+	// While replaying, orders aren't placed but rest here until replayed
+	// trades fill them
+	replaying bool
+	simulated []OrderStatus
+}
+
+// NewStrategyRunner returns a runner of strategy in account
+func NewStrategyRunner(client *Client, account string, strategy Strategy) *StrategyRunner {
+	return &StrategyRunner{Client: client, Account: account, Strategy: strategy, FillPoll: DefaultFillPoll}
+}
+
+// Position returns the quantity of symbol held in the account, negative if
+// it is short
+func (r *StrategyRunner) Position(symbol string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.positions[strings.ToUpper(symbol)]
+}
+
+// Positions returns the quantities held in the account by symbol
+func (r *StrategyRunner) Positions() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	positions := make(map[string]int, len(r.positions))
+	for sym, qty := range r.positions {
+		positions[sym] = qty
+	}
+	return positions
+}
+
+// PlaceOrder places an order in the runner's account. During a replay the
+// order is only simulated: it fills at the price of the first later trade of
+// its symbol that a market, limit, or stop order would fill at.
+func (r *StrategyRunner) PlaceOrder(o *Order) (*APIResponse, error) {
+	order := *o
+	order.Account = r.Account
+	r.mu.Lock()
+	replaying := r.replaying
+	r.mu.Unlock()
+	if replaying {
+		return r.simulateOrder(&order)
+	}
+	return r.Client.PlaceOrder(&order)
+}
+
+// Run streams symbols and runs the strategy until ctx is canceled, the
+// stream ends, or the strategy returns an error
+func (r *StrategyRunner) Run(ctx context.Context, symbols []string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if err := r.start(); err != nil {
+		return err
+	}
+	ch, err := r.Client.Stream(ctx, symbols)
+	if err != nil {
+		return fmt.Errorf("error streaming quotes: %v", err)
+	}
+	return r.run(ctx, ch, true)
+}
+
+// Replay runs the strategy on messages recorded by a StreamRecorder, as
+// ReplayStream replays them at speed. Bars are completed by later trades and
+// at the end of the recording rather than on time. Orders are simulated, as
+// described by PlaceOrder, rather than placed, and the account's orders
+// aren't checked for fills.
+func (r *StrategyRunner) Replay(ctx context.Context, recording io.Reader, speed float64) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if err := r.start(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.replaying = true
+	r.simulated = nil
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.replaying = false
+		r.mu.Unlock()
+	}()
+	return r.run(ctx, ReplayStream(ctx, recording, speed), false)
+}
+
+// Load the account's positions, and its orders so that only later fills are
+// passed to the strategy
+func (r *StrategyRunner) start() error {
+	holdings, err := r.Client.Holdings(r.Account)
+	if err != nil {
+		return fmt.Errorf("error getting holdings: %v", err)
+	}
+	orders, err := r.Client.Orders(r.Account)
+	if err != nil {
+		return fmt.Errorf("error getting orders: %v", err)
+	}
+
+	positions, err := holdings.Positions()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.positions = positions
+	r.orders = make(map[string]OrderStatus)
+	for _, o := range orders {
+		r.orders[o.ID] = o
+	}
+	if r.BarInterval > 0 {
+		r.bars = NewBarBuilder(r.BarInterval)
+	}
+	return nil
+}
+
+func (r *StrategyRunner) run(ctx context.Context, ch <-chan StreamMessage, live bool) error {
+	poll := r.FillPoll
+	if poll <= 0 {
+		poll = DefaultFillPoll
+	}
+	// Replayed orders fill as trades are replayed instead
+	var fills <-chan time.Time
+	if live {
+		ticker := time.NewTicker(poll)
+		defer ticker.Stop()
+		fills = ticker.C
+	}
+
+	// Bars complete on time only when live, as replayed trades' times
+	// aren't now
+	var tick <-chan time.Time
+	if r.bars != nil && live {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case m, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if err := r.handle(&m, live); err != nil {
+				return err
+			}
+		case now := <-tick:
+			if err := r.onBars(r.bars.Flush(now)); err != nil {
+				return err
+			}
+		case <-fills:
+			if err := r.checkFills(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Pass a stream message to the strategy
+func (r *StrategyRunner) handle(m *StreamMessage, live bool) error {
+	switch m.Event {
+	case QuoteEvent:
+		if err := r.Strategy.OnQuote(r, m.Quote); err != nil {
+			return err
+		}
+	case TradeEvent:
+		// Orders placed on seeing a trade can only fill at later ones
+		if !live {
+			if err := r.fillSimulated(m.Trade); err != nil {
+				return err
+			}
+		}
+		if err := r.Strategy.OnTrade(r, m.Trade); err != nil {
+			return err
+		}
+		if r.bars != nil {
+			if b := r.bars.AddTrade(m.Trade, time.Now()); b != nil {
+				return r.onBars([]SymbolBar{*b})
+			}
+		}
+	case StatusEvent:
+		if m.Status != StreamClosed {
+			return nil
+		}
+		if r.bars != nil && !live {
+			if err := r.onBars(r.bars.FlushAll()); err != nil {
+				return err
+			}
+		}
+		if m.Err != nil {
+			return fmt.Errorf("error streaming quotes: %v", m.Err)
+		}
+	}
+	return nil
+}
+
+func (r *StrategyRunner) onBars(bars []SymbolBar) error {
+	for i := range bars {
+		if err := r.Strategy.OnBar(r, &bars[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Pass orders that filled further since they were last checked to the
+// strategy, after updating the positions. Failing to get the orders is logged
+// and tried again at the next poll.
+func (r *StrategyRunner) checkFills() error {
+	orders, err := r.Client.Orders(r.Account)
+	if err != nil {
+		r.Client.logger.Error("error getting orders", "error", err)
+		return nil
+	}
+	for i := range orders {
+		if err := r.update(&orders[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Record an order's state, passing it to the strategy if it filled further
+func (r *StrategyRunner) update(o *OrderStatus) error {
+	r.mu.Lock()
+	filled := o.Filled - r.orders[o.ID].Filled
+	r.orders[o.ID] = *o
+	if filled > 0 {
+		switch o.Side {
+		case "buy", "buy_to_cover":
+			r.positions[strings.ToUpper(o.Symbol)] += filled
+		case "sell", "sell_short":
+			r.positions[strings.ToUpper(o.Symbol)] -= filled
+		}
+	}
+	r.mu.Unlock()
+	if filled > 0 {
+		return r.Strategy.OnFill(r, o)
+	}
+	return nil
+}
+
+// Accept an order during a replay, to be filled by a later trade
+func (r *StrategyRunner) simulateOrder(o *Order) (*APIResponse, error) {
+	if _, err := o.fixmlOrder(); err != nil {
+		return nil, err
+	}
+	switch o.Type {
+	case "", "market", "limit", "stop":
+	default:
+		return nil, fmt.Errorf("%v orders can't be simulated in a replay", o.Type)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	status := OrderStatus{
+		ID:        fmt.Sprintf("replay-%d", len(r.orders)+1),
+		Account:   o.Account,
+		Symbol:    strings.ToUpper(o.Symbol),
+		Side:      o.Side,
+		Type:      o.Type,
+		Status:    StatusNew,
+		Quantity:  o.Quantity,
+		Remaining: o.Quantity,
+		Price:     o.Price,
+		StopPrice: o.StopPrice,
+		Time:      time.Now(),
+	}
+	if status.Type == "" {
+		status.Type = "market"
+	}
+	r.orders[status.ID] = status
+	r.simulated = append(r.simulated, status)
+	return &APIResponse{Response: &ResponseBody{OrderResponse: OrderResponse{ClientOrderID: status.ID, OrderStatus: "0"}}}, nil
+}
+
+// Fill the simulated orders that a replayed trade would have filled
+func (r *StrategyRunner) fillSimulated(t *StreamTrade) error {
+	symbol := strings.ToUpper(t.Symbol)
+	r.mu.Lock()
+	var filled []OrderStatus
+	resting := r.simulated[:0]
+	for _, o := range r.simulated {
+		if o.Symbol == symbol && fills(&o, t.Last) {
+			o.Status, o.Filled, o.Remaining, o.AvgPrice, o.Time = StatusFilled, o.Quantity, 0, t.Last, t.Time()
+			filled = append(filled, o)
+		} else {
+			resting = append(resting, o)
+		}
+	}
+	r.simulated = resting
+	r.mu.Unlock()
+
+	for i := range filled {
+		if err := r.update(&filled[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Report whether a trade at price fills a simulated order
+func fills(o *OrderStatus, price Decimal) bool {
+	buy := o.Side == "buy" || o.Side == "buy_to_cover"
+	switch o.Type {
+	case "limit":
+		return buy && price <= o.Price || !buy && price >= o.Price
+	case "stop":
+		return buy && price >= o.StopPrice || !buy && price <= o.StopPrice
+	}
+	return price > 0
+}
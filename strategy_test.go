@@ -0,0 +1,100 @@
+package allyapi_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/n8henrie/allyapi"
+	"github.com/n8henrie/allyapi/allytest"
+)
+
+// Buys 10 shares on the first trade and sells them with a limit of 42
+type buyThenSell struct {
+	allyapi.BaseStrategy
+	bought bool
+	fills  []allyapi.OrderStatus
+}
+
+func (s *buyThenSell) OnTrade(r *allyapi.StrategyRunner, t *allyapi.StreamTrade) error {
+	if s.bought {
+		return nil
+	}
+	s.bought = true
+	_, err := r.PlaceOrder(&allyapi.Order{Symbol: t.Symbol, Side: "buy", Type: "market", Quantity: 10})
+	return err
+}
+
+func (s *buyThenSell) OnFill(r *allyapi.StrategyRunner, o *allyapi.OrderStatus) error {
+	s.fills = append(s.fills, *o)
+	if o.Side == "buy" {
+		_, err := r.PlaceOrder(&allyapi.Order{Symbol: o.Symbol, Side: "sell", Type: "limit", Quantity: 10, Price: allyapi.DecimalFromInt(42)})
+		return err
+	}
+	return nil
+}
+
+func TestStrategyReplaySimulatesOrders(t *testing.T) {
+	srv := allytest.NewServer()
+	defer srv.Close()
+	client := srv.Client()
+
+	var recording bytes.Buffer
+	rec := allyapi.NewStreamRecorder(&recording)
+	for _, last := range []string{"40", "40.5", "41", "42.25"} {
+		d, err := allyapi.ParseDecimal(last)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec.Record(&allyapi.APIResponse{Trade: &allyapi.StreamTrade{Symbol: "AAPL", Last: d}})
+	}
+
+	s := &buyThenSell{}
+	r := allyapi.NewStrategyRunner(client, allytest.AccountID, s)
+	if err := r.Replay(context.Background(), &recording, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// The buy fills at the next trade and the sell once the price reaches
+	// its limit
+	if len(s.fills) != 2 {
+		t.Fatalf("got %d fills, want 2", len(s.fills))
+	}
+	buy, sell := s.fills[0], s.fills[1]
+	if buy.Side != "buy" || buy.Filled != 10 || buy.AvgPrice.String() != "40.5" {
+		t.Errorf("buy = %+v, want 10 filled at 40.5", buy)
+	}
+	if sell.Side != "sell" || sell.Filled != 10 || sell.AvgPrice.String() != "42.25" {
+		t.Errorf("sell = %+v, want 10 filled at 42.25", sell)
+	}
+	if got := r.Position("AAPL"); got != 100 {
+		t.Errorf("position = %d, want 100", got)
+	}
+
+	for _, req := range srv.Requests() {
+		if req.Method != http.MethodGet {
+			t.Errorf("replay made a %v request to %v", req.Method, req.URL.Path)
+		}
+	}
+}
+
+func TestStrategyReplayRejectsUnsimulatedOrders(t *testing.T) {
+	srv := allytest.NewServer()
+	defer srv.Close()
+
+	var recording bytes.Buffer
+	allyapi.NewStreamRecorder(&recording).Record(&allyapi.APIResponse{Trade: &allyapi.StreamTrade{Symbol: "AAPL", Last: allyapi.DecimalFromInt(40)}})
+
+	r := allyapi.NewStrategyRunner(srv.Client(), allytest.AccountID, trailingStop{})
+	if err := r.Replay(context.Background(), &recording, 0); err == nil {
+		t.Error("expected an error placing a trailing stop in a replay")
+	}
+}
+
+type trailingStop struct{ allyapi.BaseStrategy }
+
+func (trailingStop) OnTrade(r *allyapi.StrategyRunner, t *allyapi.StreamTrade) error {
+	_, err := r.PlaceOrder(&allyapi.Order{Symbol: t.Symbol, Side: "sell", Type: "trailing_stop", Quantity: 10, TrailAmount: allyapi.DecimalFromInt(1)})
+	return err
+}
@@ -0,0 +1,385 @@
+package allyapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StreamEvent is the kind of a StreamMessage
+type StreamEvent int
+
+const (
+	QuoteEvent StreamEvent = iota + 1
+	TradeEvent
+	StatusEvent
+)
+
+func (e StreamEvent) String() string {
+	switch e {
+	case QuoteEvent:
+		return "quote"
+	case TradeEvent:
+		return "trade"
+	case StatusEvent:
+		return "status"
+	default:
+		return "unknown"
+	}
+}
+
+// Statuses of messages sent by the client rather than the API
+const (
+	// The final message on a stream
+	StreamClosed = "closed"
+
+	// Sent before closing a stream with ErrStreamStale
+	StreamStale = "stale"
+)
+
+// ErrStreamStale ends a stream that has gone longer than the client's
+// StreamTimeout without a message
+var ErrStreamStale = errors.New("stream is stale: no messages received within timeout")
+
+// Cancels a stream if it goes the client's StreamTimeout without being reset
+type watchdog struct {
+	timer   *time.Timer
+	timeout time.Duration
+	stale   int32
+}
+
+// Start a watchdog calling cancel, or one that does nothing if the client has
+// no StreamTimeout
+func (ac *Client) newWatchdog(cancel context.CancelFunc) *watchdog {
+	w := &watchdog{timeout: ac.StreamTimeout}
+	if w.timeout > 0 {
+		w.timer = time.AfterFunc(ac.StreamTimeout, func() {
+			atomic.StoreInt32(&w.stale, 1)
+			ac.logger.Warn("stream is stale", "timeout", ac.StreamTimeout)
+			cancel()
+		})
+	}
+	return w
+}
+
+func (w *watchdog) reset() {
+	if w.timer != nil && !w.fired() {
+		w.timer.Reset(w.timeout)
+	}
+}
+
+func (w *watchdog) stop() {
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+}
+
+func (w *watchdog) fired() bool {
+	return atomic.LoadInt32(&w.stale) == 1
+}
+
+// StreamMessage is a quote, trade, or change in status of a stream. Quote is
+// set for a QuoteEvent and Trade for a TradeEvent. A StatusEvent has the
+// Status sent by the API, such as "connected", or StreamClosed when the
+// stream ends, with Err set if it failed.
+type StreamMessage struct {
+	Event  StreamEvent
+	Quote  *StreamQuote
+	Trade  *StreamTrade
+	Status string
+	Err    error
+}
+
+// Convert a decoded streaming response to a message, if it is a quote, trade,
+// or status
+func streamMessage(m *APIResponse) (StreamMessage, bool) {
+	switch {
+	case m.Quote != nil:
+		return StreamMessage{Event: QuoteEvent, Quote: m.Quote}, true
+	case m.Trade != nil:
+		return StreamMessage{Event: TradeEvent, Trade: m.Trade}, true
+	case m.Status != "":
+		return StreamMessage{Event: StatusEvent, Status: m.Status}, true
+	}
+	return StreamMessage{}, false
+}
+
+// Stream streams quotes and trades for symbols until ctx is canceled or the
+// connection closes. The returned error is for failing to connect; the
+// channel is closed after the final StreamClosed message. If the client has a
+// StreamTimeout and the stream goes stale, a StreamStale message comes before
+// the final message, whose Err is ErrStreamStale.
+func (ac *Client) Stream(ctx context.Context, symbols []string) (<-chan StreamMessage, error) {
+	data := map[string][]string{"symbols": {strings.Join(symbols, ",")}}
+	req, err := ac.newRequest(ac.env.StreamURL+"/market/quotes.json", "POST", data)
+	if err != nil {
+		return nil, err
+	}
+	connCtx, cancel := context.WithCancel(ctx)
+	resp, err := ac.open(req.WithContext(connCtx))
+	if err != nil {
+		cancel()
+		ac.Metrics.addError()
+		return nil, err
+	}
+	wd := ac.newWatchdog(cancel)
+
+	ch := make(chan StreamMessage)
+	send := func(msg StreamMessage) error {
+		select {
+		case ch <- msg:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	handle := ac.Metrics.countQuotes(func(m *APIResponse) error {
+		if msg, ok := streamMessage(m); ok {
+			return send(msg)
+		}
+		return nil
+	})
+
+	go func() {
+		defer close(ch)
+		defer cancel()
+		defer resp.Body.Close()
+		defer wd.stop()
+
+		var err error
+		decoder := json.NewDecoder(resp.Body)
+		for decoder.More() {
+			var m APIResponse
+			if err = decoder.Decode(&m); err != nil {
+				break
+			}
+			wd.reset()
+			if err = handle(&m); err != nil {
+				break
+			}
+		}
+
+		// Canceling the context is a normal end to the stream
+		switch {
+		case wd.fired():
+			err = ErrStreamStale
+			ac.Metrics.addError()
+			send(StreamMessage{Event: StatusEvent, Status: StreamStale})
+		case ctx.Err() != nil:
+			err = nil
+		case err != nil:
+			ac.Metrics.addError()
+		}
+		send(StreamMessage{Event: StatusEvent, Status: StreamClosed, Err: err})
+	}()
+	return ch, nil
+}
+
+// StreamSession is a stream whose symbols can change while it runs. Changing
+// the symbols reconnects with the new set, while messages continue on the
+// same channel. Stale streams are also reconnected, after a StreamStale
+// message: at once the first time, then waiting twice as long after each
+// reconnection that fails or goes stale without a quote or trade, up to
+// maxReconnectDelay.
+type StreamSession struct {
+	client  *Client
+	ctx     context.Context
+	cancel  context.CancelFunc
+	out     chan StreamMessage
+	restart chan struct{}
+	done    chan struct{}
+
+	mu      sync.Mutex
+	symbols map[string]bool
+}
+
+// NewStreamSession starts streaming symbols, which may be empty, until ctx is
+// canceled, Close is called, or the connection closes
+func (ac *Client) NewStreamSession(ctx context.Context, symbols ...string) *StreamSession {
+	s := &StreamSession{
+		client:  ac,
+		out:     make(chan StreamMessage),
+		restart: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		symbols: make(map[string]bool),
+	}
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	for _, sym := range symbols {
+		s.symbols[strings.ToUpper(sym)] = true
+	}
+	go s.run()
+	return s
+}
+
+// Messages returns the channel of stream messages, which is closed after the
+// session ends
+func (s *StreamSession) Messages() <-chan StreamMessage {
+	return s.out
+}
+
+// Symbols returns the symbols being streamed
+func (s *StreamSession) Symbols() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	symbols := make([]string, 0, len(s.symbols))
+	for sym := range s.symbols {
+		symbols = append(symbols, sym)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// Subscribe adds symbols to the stream
+func (s *StreamSession) Subscribe(symbols ...string) {
+	s.update(symbols, true)
+}
+
+// Unsubscribe removes symbols from the stream
+func (s *StreamSession) Unsubscribe(symbols ...string) {
+	s.update(symbols, false)
+}
+
+func (s *StreamSession) update(symbols []string, add bool) {
+	s.mu.Lock()
+	changed := false
+	for _, sym := range symbols {
+		sym = strings.ToUpper(sym)
+		if s.symbols[sym] != add {
+			changed = true
+			if add {
+				s.symbols[sym] = true
+			} else {
+				delete(s.symbols, sym)
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	if changed {
+		select {
+		case s.restart <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Close ends the session
+func (s *StreamSession) Close() error {
+	s.cancel()
+	<-s.done
+	return nil
+}
+
+func (s *StreamSession) send(msg StreamMessage) {
+	select {
+	case s.out <- msg:
+	case <-s.ctx.Done():
+	}
+}
+
+// Bounds of the wait between reconnections of a stale stream
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
+)
+
+// Wait d unless the session ends first, reporting whether it didn't
+func (s *StreamSession) wait(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+func (s *StreamSession) run() {
+	defer close(s.done)
+	defer close(s.out)
+	defer s.cancel()
+
+	// Set once a stale stream is being reconnected, until a quote or trade
+	// arrives
+	reconnecting := false
+	var delay time.Duration
+	backOff := func() bool {
+		if delay > 0 && !s.wait(delay) {
+			return false
+		}
+		delay = min(max(2*delay, minReconnectDelay), maxReconnectDelay)
+		return true
+	}
+
+	for {
+		// Wait for symbols to stream
+		symbols := s.Symbols()
+		if len(symbols) == 0 {
+			select {
+			case <-s.restart:
+				continue
+			case <-s.ctx.Done():
+				return
+			}
+		}
+
+		ctx, cancel := context.WithCancel(s.ctx)
+		ch, err := s.client.Stream(ctx, symbols)
+		if err != nil {
+			cancel()
+			// Keep trying to reconnect unless the session is no longer
+			// authorized
+			if reconnecting && !errors.Is(err, ErrUnauthorized) {
+				s.client.logger.Warn("error reconnecting stream", "error", err, "retry", delay)
+				if backOff() {
+					continue
+				}
+				return
+			}
+			s.send(StreamMessage{Event: StatusEvent, Status: StreamClosed, Err: err})
+			return
+		}
+
+	connection:
+		for {
+			select {
+			case <-s.restart:
+				// Reconnect with the new symbols, dropping the old
+				// connection's remaining messages
+				cancel()
+				for range ch {
+				}
+				break connection
+			case msg, ok := <-ch:
+				if !ok {
+					cancel()
+					return
+				}
+				if msg.Event == StatusEvent && msg.Status == StreamClosed {
+					cancel()
+					// Reconnect to stale streams
+					if errors.Is(msg.Err, ErrStreamStale) {
+						s.client.Metrics.AddReconnect()
+						reconnecting = true
+						if !backOff() {
+							return
+						}
+						break connection
+					}
+					s.send(msg)
+					return
+				}
+				if msg.Event != StatusEvent {
+					reconnecting, delay = false, 0
+				}
+				s.send(msg)
+			}
+		}
+	}
+}
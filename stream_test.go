@@ -0,0 +1,56 @@
+package allyapi_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/n8henrie/allyapi"
+	"github.com/n8henrie/allyapi/allytest"
+)
+
+func TestStreamSessionRetriesStaleReconnects(t *testing.T) {
+	srv := allytest.NewServer()
+	defer srv.Close()
+	srv.StreamInterval = time.Hour
+
+	// The first reconnection fails, as if the API were briefly down
+	var streams int32
+	handler := srv.Config.Handler
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/stream/") && atomic.AddInt32(&streams, 1) == 2 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+
+	client := srv.Client()
+	client.StreamTimeout = 100 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	s := client.NewStreamSession(ctx, "AAPL")
+	defer s.Close()
+
+	trades := 0
+	for m := range s.Messages() {
+		switch {
+		case m.Event == allyapi.TradeEvent:
+			trades++
+		case m.Status == allyapi.StreamClosed:
+			t.Fatalf("session closed: %v", m.Err)
+		}
+		if trades == 2 {
+			break
+		}
+	}
+	if trades != 2 {
+		t.Fatalf("got %d trades before the session ended, want 2", trades)
+	}
+	if n := atomic.LoadInt32(&streams); n != 3 {
+		t.Errorf("connected %d times, want 3", n)
+	}
+}
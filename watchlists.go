@@ -0,0 +1,94 @@
+package allyapi
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Watchlist is a named list of symbols saved on the server
+type Watchlist struct {
+	ID            string         `json:",omitempty" xml:"id,omitempty"`
+	WatchlistItem WatchlistItems `json:",omitempty" xml:"watchlistitem,omitempty"`
+}
+
+// WatchlistItem is a symbol in a watchlist
+type WatchlistItem struct {
+	CostBasis  string `json:",omitempty" xml:"costbasis,omitempty"`
+	Qty        string `json:",omitempty" xml:"qty,omitempty"`
+	Instrument struct {
+		Sym string `json:",omitempty" xml:"sym,omitempty"`
+	} `json:",omitempty" xml:"instrument,omitempty"`
+}
+
+// Watchlists holds one or more watchlists
+type Watchlists []Watchlist
+
+// UnmarshalJSON accepts either an array of watchlists or a single watchlist
+func (wl *Watchlists) UnmarshalJSON(data []byte) error {
+	return unmarshalArray(data, (*[]Watchlist)(wl))
+}
+
+// WatchlistItems holds one or more watchlist items
+type WatchlistItems []WatchlistItem
+
+// UnmarshalJSON accepts either an array of items or a single item
+func (wi *WatchlistItems) UnmarshalJSON(data []byte) error {
+	return unmarshalArray(data, (*[]WatchlistItem)(wi))
+}
+
+// Watchlists returns the IDs of all saved watchlists
+func (ac *Client) Watchlists() ([]string, error) {
+	resp, err := ac.get(ac.endpoint("/watchlists"))
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	if resp.Response.Watchlists != nil {
+		for _, w := range resp.Response.Watchlists.Watchlist {
+			ids = append(ids, w.ID)
+		}
+	}
+	return ids, nil
+}
+
+// Watchlist returns the symbols in a saved watchlist
+func (ac *Client) Watchlist(id string) ([]string, error) {
+	resp, err := ac.get(ac.endpoint("/watchlists/" + url.PathEscape(id)))
+	if err != nil {
+		return nil, err
+	}
+
+	var symbols []string
+	if resp.Response.Watchlists != nil {
+		for _, w := range resp.Response.Watchlists.Watchlist {
+			for _, item := range w.WatchlistItem {
+				symbols = append(symbols, item.Instrument.Sym)
+			}
+		}
+	}
+	return symbols, nil
+}
+
+// CreateWatchlist saves a new watchlist with the given symbols
+func (ac *Client) CreateWatchlist(id string, symbols []string) error {
+	data := map[string][]string{"id": {id}}
+	if len(symbols) > 0 {
+		data["symbols"] = []string{strings.Join(symbols, ",")}
+	}
+	_, err := ac.post(ac.endpoint("/watchlists"), data)
+	return err
+}
+
+// AddWatchlistSymbols adds symbols to a saved watchlist
+func (ac *Client) AddWatchlistSymbols(id string, symbols []string) error {
+	data := map[string][]string{"symbols": {strings.Join(symbols, ",")}}
+	_, err := ac.post(ac.endpoint("/watchlists/"+url.PathEscape(id)+"/symbols"), data)
+	return err
+}
+
+// RemoveWatchlistSymbol removes a symbol from a saved watchlist
+func (ac *Client) RemoveWatchlistSymbol(id, symbol string) error {
+	_, err := ac.call(ac.endpoint("/watchlists/"+url.PathEscape(id)+"/symbols/"+url.PathEscape(symbol)), "DELETE", nil)
+	return err
+}